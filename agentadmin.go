@@ -0,0 +1,199 @@
+/*
+© Copyright IBM Corporation 2022, 2022
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+/*
+* This file contains the "agents create" and "agents delete" commands, which
+* create an agent from a definition file and delete an agent through the
+* agent resource of the MQ Web Server:
+*
+*   POST   /ibmmq/rest/v2/admin/mft/agent
+*   DELETE /ibmmq/rest/v2/admin/mft/agent/{name}
+*
+* The definition file is JSON, or YAML when its name ends with .yaml or .yml,
+* and is sent as it is once its name, type and queue manager are checked, so
+* that the attributes of a protocol bridge agent reach the MQ Web Server
+* without this program knowing them:
+*
+*   { "name": "SFTPBRIDGE", "type": "bridge", "qmgrName": "QM1",
+*     "bridge": { "serverType": "SFTP", "serverHost": "sftp.example.com" } }
+*
+* Not every level of MQ exposes the creation and deletion of agents. When the
+* MQ Web Server rejects the method, the agent must be created with
+* fteCreateAgent or fteCreateBridgeAgent, or deleted with fteDeleteAgent.
+ */
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Types of agents that can be created.
+var agentTypes = map[string]bool{"standard": true, "bridge": true, "cdBridge": true}
+
+// Names of agents: letters, digits, '.' and '_', 28 characters at most.
+var agentNamePattern = regexp.MustCompile(`^[A-Za-z0-9._]{1,28}$`)
+
+// Longest name of a queue manager.
+const maxQueueManagerName = 48
+
+/* Read an agent definition file, JSON or YAML.
+* fileName - Name of the file
+ */
+func readAgentDefinition(fileName string) (map[string]interface{}, error) {
+	content, err := os.ReadFile(fileName)
+	if err != nil {
+		return nil, err
+	}
+	definition := map[string]interface{}{}
+	extension := filepath.Ext(fileName)
+	if strings.EqualFold(extension, ".yaml") || strings.EqualFold(extension, ".yml") {
+		err = yaml.Unmarshal(content, &definition)
+	} else {
+		err = json.Unmarshal(content, &definition)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", fileName, err)
+	}
+	return definition, nil
+}
+
+/* Check the attributes of an agent definition known to this program.
+* definition - Agent definition
+ */
+func validateAgentDefinition(definition map[string]interface{}) error {
+	var problems requestProblems
+	name, _ := definition["name"].(string)
+	if !agentNamePattern.MatchString(name) {
+		problems.add(jsonPointer("name"), name, "must be 1 to 28 letters, digits, '.' or '_'")
+	}
+	if agentType, _ := definition["type"].(string); !agentTypes[agentType] {
+		problems.add(jsonPointer("type"), agentType, "must be standard, bridge or cdBridge")
+	}
+	if qmgr, _ := definition["qmgrName"].(string); len(qmgr) == 0 || len(qmgr) > maxQueueManagerName {
+		problems.add(jsonPointer("qmgrName"), qmgr, "must be the name of a queue manager of 1 to %d characters", maxQueueManagerName)
+	}
+	return problems.err()
+}
+
+/* Return the error of a request on the agent resource, telling when the MQ
+* Web Server does not support the method. A collection that is not found
+* does not support it either.
+* response - Response of the request
+* body     - Body of the response
+ */
+func agentAdminError(response *http.Response, body string) error {
+	unsupported := response.StatusCode == http.StatusMethodNotAllowed || response.StatusCode == http.StatusNotImplemented
+	if unsupported || (response.StatusCode == http.StatusNotFound && response.Request != nil && response.Request.Method == "POST") {
+		return fmt.Errorf("%s", message("agentAdminUnsupported", response.Status))
+	}
+	return mqWebError(response, body)
+}
+
+// Command "agents create" - create an agent from a definition file.
+func agentsCreateCommand(args []string) int {
+	flags := newFlagSet("agents create")
+	routeName := flags.String("route", "", "Route whose MQ Web Server creates the agent")
+	file := flags.String("file", "", "JSON or YAML file with the definition of the agent")
+	dryRun := flags.Bool("dry-run", false, "Print the agent request without creating the agent")
+	if !parseCommandLine(flags, args) || flags.NArg() != 0 || len(*file) == 0 {
+		printMessage("agentsUsage")
+		return 2
+	}
+	definition, err := readAgentDefinition(*file)
+	if err == nil {
+		err = validateAgentDefinition(definition)
+	}
+	if err != nil {
+		printError(err)
+		return 2
+	}
+	request, err := json.Marshal(definition)
+	if err != nil {
+		printError(err)
+		return 2
+	}
+	if *dryRun {
+		fmt.Println(indentRequest(string(request)))
+		return 0
+	}
+	route, err := findRoute(*routeName)
+	if err != nil {
+		printError(err)
+		return 2
+	}
+	agentsUrl := agentCollectionUrlOf(route.transferUrl())
+	response, body, err := callMQWeb("POST", agentsUrl, string(request))
+	if err == nil && response.StatusCode != http.StatusCreated && response.StatusCode != http.StatusAccepted {
+		err = agentAdminError(response, body)
+	}
+	if err != nil {
+		printMessage("agentCreateFailed", definition["name"], err)
+		return 1
+	}
+	printMessage("agentCreated", definition["name"], definition["type"], definition["qmgrName"])
+	return 0
+}
+
+// Command "agents delete" - delete an agent.
+func agentsDeleteCommand(args []string) int {
+	flags := newFlagSet("agents delete")
+	routeName := flags.String("route", "", "Route whose MQ Web Server deletes the agent")
+	force := flags.Bool("force", false, "Delete the agent without asking for confirmation")
+	if !parseCommandLine(flags, args) || flags.NArg() != 1 {
+		printMessage("agentsUsage")
+		return 2
+	}
+	name := flags.Arg(0)
+	route, err := findRoute(*routeName)
+	if err != nil {
+		printError(err)
+		return 2
+	}
+	if !*force && !askConfirmation(message("agentDeleteConfirm", name)) {
+		printMessage("agentNotDeleted", name)
+		return 1
+	}
+	agentUrl := agentCollectionUrlOf(route.transferUrl()) + "/" + url.PathEscape(name)
+	response, body, err := callMQWeb("DELETE", agentUrl, "")
+	if err == nil && response.StatusCode == http.StatusNotFound {
+		printMessage("agentNotFound", name)
+		return 1
+	}
+	if err == nil && response.StatusCode != http.StatusNoContent && response.StatusCode != http.StatusOK &&
+		response.StatusCode != http.StatusAccepted {
+		err = agentAdminError(response, body)
+	}
+	if err != nil {
+		printMessage("agentDeleteFailed", name, err)
+		return 1
+	}
+	// The cache would still give the deleted agent
+	agents := readAgentCache()
+	if _, found := agents[name]; found {
+		delete(agents, name)
+		if err := writeAgentCache(agents); err != nil {
+			printMessage("agentCacheWriteFailed", err)
+		}
+	}
+	printMessage("agentDeleted", name)
+	return 0
+}
@@ -0,0 +1,84 @@
+/*
+© Copyright IBM Corporation 2022, 2022
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+/*
+* This file contains the health checks of the agents of a transfer, made
+* with the checks of agents.go before a transfer is submitted. An agent
+* publishes its status at a regular interval, and the MQ Web Server reports
+* the time since the last publication as its statusAge, h:mm:ss. An agent
+* whose status is old has stopped or lost its queue manager even when its
+* last published state is ready, and an agent with many queued transfers
+* will not start a new one soon. The thresholds are set in the configuration:
+*
+*   "agentHealth": { "maxStatusAge": "10m", "maxQueuedTransfers": 50, "abort": true }
+*
+* The age of a cached status includes the time since it was queried. An
+* unhealthy agent is reported as a warning, or fails the submission with
+* "abort". A threshold of 0 is not checked.
+ */
+package main
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Thresholds of the health of the agents of a transfer.
+type AgentHealthPolicy struct {
+	MaxStatusAge       Duration `json:"maxStatusAge"`
+	MaxQueuedTransfers int64    `json:"maxQueuedTransfers"`
+	Abort              bool     `json:"abort"`
+}
+
+/* Parse a status age, h:mm:ss with an optional number of days before the
+* hours, d:hh:mm:ss. Returns false if it is not in that form.
+* text - Status age reported by the MQ Web Server
+ */
+func parseStatusAge(text string) (time.Duration, bool) {
+	fields := strings.Split(strings.TrimSpace(text), ":")
+	if len(fields) < 3 || len(fields) > 4 {
+		return 0, false
+	}
+	units := []time.Duration{time.Second, time.Minute, time.Hour, 24 * time.Hour}
+	age := time.Duration(0)
+	for index := range fields {
+		value, err := strconv.Atoi(fields[len(fields)-1-index])
+		if err != nil || value < 0 {
+			return 0, false
+		}
+		age += time.Duration(value) * units[index]
+	}
+	return age, true
+}
+
+/* Return the problems of the health of an agent, empty if it is healthy.
+* agent  - Status of the agent
+* policy - Health thresholds
+* now    - Current time
+ */
+func (agent AgentInfo) healthProblems(policy AgentHealthPolicy, now time.Time) []string {
+	problems := []string{}
+	if maxAge := time.Duration(policy.MaxStatusAge); maxAge > 0 {
+		if age, parsed := parseStatusAge(agent.StatusAge); parsed {
+			age += now.Sub(agent.FetchedAt)
+			if age > maxAge {
+				problems = append(problems, message("agentStatusOld", age.Round(time.Second).String(), maxAge.String()))
+			}
+		}
+	}
+	if policy.MaxQueuedTransfers > 0 && agent.QueuedTransfers > policy.MaxQueuedTransfers {
+		problems = append(problems, message("agentQueueDeep", agent.QueuedTransfers, policy.MaxQueuedTransfers))
+	}
+	return problems
+}
@@ -0,0 +1,109 @@
+/*
+© Copyright IBM Corporation 2022, 2022
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+/*
+* This file contains the interactive selection of the agents of a transfer,
+* with submit -interactive. The agents are discovered from the MQ Web Server,
+* refreshing the agent cache, and listed in a numbered menu with their queue
+* manager and state:
+*
+*   Choose the source agent:
+*     1) DEST                     DESTQM               ready
+*     2) OLD                      SRCQM                stopped
+*     3) SRC                      SRCQM                ready
+*   Agent number or name [3]:
+*
+* The user answers with the number or the name of an agent, or with an empty
+* line to keep the agent of the route, shown in brackets. The queue manager
+* of the chosen agent becomes the queue manager of the transfer, so that it
+* always matches the agent.
+ */
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+/* Let the user choose the source and destination agents of a route.
+* route - Route of the transfer, whose agents are proposed by default
+ */
+func selectRouteAgents(route Route) (Route, error) {
+	agents, err := discoverAgents()
+	if err != nil {
+		return route, err
+	}
+	if len(agents) == 0 {
+		return route, fmt.Errorf("%s", message("agentMenuEmpty"))
+	}
+	reader := bufio.NewReader(os.Stdin)
+	source, err := selectAgent(reader, agents, message("agentMenuSource"), route.SourceAgent)
+	if err != nil {
+		return route, err
+	}
+	destination, err := selectAgent(reader, agents, message("agentMenuDestination"), route.DestinationAgent)
+	if err != nil {
+		return route, err
+	}
+	route.SourceAgent, route.SourceQM = source.Name, source.QueueManager
+	route.DestinationAgent, route.DestinationQM = destination.Name, destination.QueueManager
+	return route, nil
+}
+
+/* Print a menu of agents and read the choice of the user.
+* reader  - Input of the user
+* agents  - Agents to choose from
+* label   - Role of the agent, source or destination
+* current - Agent chosen with an empty answer, if any
+ */
+func selectAgent(reader *bufio.Reader, agents []AgentInfo, label string, current string) (AgentInfo, error) {
+	fmt.Println(message("agentMenuTitle", label))
+	byName := map[string]AgentInfo{}
+	defaultChoice := ""
+	for index, agent := range agents {
+		fmt.Printf("  %d) %-24s %-20s %s\n", index+1, agent.Name, agent.QueueManager, agent.State)
+		byName[strings.ToUpper(agent.Name)] = agent
+		if strings.EqualFold(agent.Name, current) {
+			defaultChoice = strconv.Itoa(index + 1)
+		}
+	}
+	for {
+		if len(defaultChoice) > 0 {
+			fmt.Print(message("agentMenuPromptDefault", defaultChoice))
+		} else {
+			fmt.Print(message("agentMenuPrompt"))
+		}
+		answer, err := reader.ReadString('\n')
+		answer = strings.TrimSpace(answer)
+		if len(answer) == 0 && err == io.EOF {
+			return AgentInfo{}, fmt.Errorf("%s", message("agentMenuNoChoice", label))
+		}
+		if len(answer) == 0 {
+			answer = defaultChoice
+		}
+		if number, convErr := strconv.Atoi(answer); convErr == nil && number >= 1 && number <= len(agents) {
+			return agents[number-1], nil
+		}
+		if agent, found := byName[strings.ToUpper(answer)]; found {
+			return agent, nil
+		}
+		printMessage("agentMenuInvalid", answer, len(agents))
+		if err != nil {
+			return AgentInfo{}, fmt.Errorf("%s", message("agentMenuNoChoice", label))
+		}
+	}
+}
@@ -0,0 +1,434 @@
+/*
+© Copyright IBM Corporation 2022, 2022
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+/*
+* This file contains the cache of the status of MFT agents, queried from the
+* agent resource of the MQ Web Server, next to the transfer resource. Checks
+* made before a transfer is submitted look agents up in the cache, which is
+* kept in "agents.json" under the state directory, so that the MQ Web Server
+* is not queried for every submission. An agent is queried again once its
+* entry is older than "agentCacheTtl" in the configuration, 5m by default, 0
+* to disable the cache.
+*
+* After agents are restarted, migrated or removed, the "refresh" command
+* discards the cache and discovers all the agents again.
+*
+* With "checkAgents" in the configuration, or -check-agents, the source and
+* destination agents of a transfer are looked up before it is submitted, and
+* the submission fails when either does not exist or is not ready or active.
+* A transfer between agents that are not running would otherwise stay in the
+* new state until they are started. Their health is also checked, see
+* agenthealth.go.
+*
+* The "agents list" command lists the agents with their type (standard,
+* bridge or cdBridge), state, queue manager and version, filtered by name
+* pattern, state and type and sorted by any of the columns, so that the
+* agents of large topologies can be found. "agents show NAME" prints every
+* attribute of an agent. Both query the MQ Web Server rather than the cache.
+* Agents are created and deleted by the commands of agentadmin.go.
+ */
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/tidwall/gjson"
+)
+
+// Default time an agent status is cached.
+const defaultAgentCacheTtl = 5 * time.Minute
+
+// Status of an agent as reported by the MQ Web Server.
+type AgentInfo struct {
+	Name            string    `json:"name"`
+	Type            string    `json:"type"`
+	State           string    `json:"state"`
+	QueueManager    string    `json:"qmgrName"`
+	Version         string    `json:"version,omitempty"`
+	StatusAge       string    `json:"statusAge"`
+	QueuedTransfers int64     `json:"queuedTransfers,omitempty"`
+	FetchedAt       time.Time `json:"fetchedAt"`
+}
+
+// Returns the file of the agent cache.
+func agentCacheFile() string {
+	return filepath.Join(config.StateDirectory, "agents.json")
+}
+
+// Return the URL of the agent resource of the MQ Web Server, next to the
+// transfer resource.
+func agentCollectionUrl() string {
+	return agentCollectionUrlOf(config.TransferUrl)
+}
+
+// Return the URL of the agent resource next to a transfer resource.
+func agentCollectionUrlOf(transferUrl string) string {
+	return strings.TrimSuffix(strings.TrimSuffix(transferUrl, "/"), "/transfer") + "/agent"
+}
+
+// Read the agent cache. A missing or unreadable cache is empty.
+func readAgentCache() map[string]AgentInfo {
+	agents := map[string]AgentInfo{}
+	if content, err := os.ReadFile(agentCacheFile()); err == nil {
+		json.Unmarshal(content, &agents)
+	}
+	return agents
+}
+
+// Write the agent cache.
+func writeAgentCache(agents map[string]AgentInfo) error {
+	if err := os.MkdirAll(config.StateDirectory, 0700); err != nil {
+		return err
+	}
+	content, err := json.MarshalIndent(agents, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(agentCacheFile(), content, 0600)
+}
+
+/* Return the status of an agent, from the cache if it is recent enough.
+* name - Name of the agent
+ */
+func lookupAgent(name string) (AgentInfo, error) {
+	ttl := time.Duration(config.AgentCacheTtl)
+	agents := readAgentCache()
+	if agent, found := agents[name]; found && ttl > 0 && time.Since(agent.FetchedAt) < ttl {
+		return agent, nil
+	}
+	response, body, err := callMQWeb("GET", agentCollectionUrl()+"/"+url.PathEscape(name)+"?attributes=*", "")
+	if err != nil {
+		return AgentInfo{}, err
+	}
+	if response.StatusCode == http.StatusNotFound {
+		return AgentInfo{}, fmt.Errorf("agent %s not found", name)
+	}
+	if response.StatusCode != http.StatusOK {
+		return AgentInfo{}, fmt.Errorf("agent %s: %s", name, response.Status)
+	}
+	found := parseAgents(body, time.Now())
+	if len(found) == 0 {
+		return AgentInfo{}, fmt.Errorf("agent %s not found", name)
+	}
+	if ttl > 0 {
+		agents[name] = found[0]
+		if err := writeAgentCache(agents); err != nil {
+			printMessage("agentCacheWriteFailed", err)
+		}
+	}
+	return found[0], nil
+}
+
+// States of an agent that can run transfers.
+var agentReadyStates = map[string]bool{"ready": true, "active": true}
+
+/* Check that the source and destination agents of a route exist and can run
+* transfers.
+* route - Route of the transfer
+ */
+func checkRouteAgents(route Route) error {
+	for _, name := range []string{route.SourceAgent, route.DestinationAgent} {
+		agent, err := lookupAgent(name)
+		if err != nil {
+			return err
+		}
+		if !agentReadyStates[strings.ToLower(agent.State)] {
+			return fmt.Errorf("%s", message("agentNotReady", name, agent.State))
+		}
+		if problems := agent.healthProblems(config.AgentHealth, time.Now()); len(problems) > 0 {
+			if config.AgentHealth.Abort {
+				return fmt.Errorf("%s", message("agentUnhealthy", name, strings.Join(problems, ", ")))
+			}
+			printMessage("agentHealthWarning", name, strings.Join(problems, ", "))
+		}
+	}
+	return nil
+}
+
+// Query the status of all the agents and replace the cache with it.
+func discoverAgents() ([]AgentInfo, error) {
+	response, body, err := callMQWeb("GET", agentCollectionUrl()+"?attributes=*", "")
+	if err != nil {
+		return nil, err
+	}
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("agent query: %s", response.Status)
+	}
+	found := parseAgents(body, time.Now())
+	agents := map[string]AgentInfo{}
+	for _, agent := range found {
+		agents[agent.Name] = agent
+	}
+	if err := writeAgentCache(agents); err != nil {
+		printMessage("agentCacheWriteFailed", err)
+	}
+	return found, nil
+}
+
+/* Parse the agents of a response of the agent resource, sorted by name.
+* body - Response body
+* now  - Time of the query
+ */
+func parseAgents(body string, now time.Time) []AgentInfo {
+	agents := []AgentInfo{}
+	for _, agent := range gjson.Get(body, "agent").Array() {
+		agents = append(agents, AgentInfo{
+			Name:            agent.Get("name").String(),
+			Type:            agent.Get("type").String(),
+			State:           agent.Get("state").String(),
+			QueueManager:    agent.Get("qmgrName").String(),
+			Version:         agent.Get("version").String(),
+			StatusAge:       agent.Get("statusAge").String(),
+			QueuedTransfers: agent.Get("queuedTransfers").Int(),
+			FetchedAt:       now,
+		})
+	}
+	sort.Slice(agents, func(i, k int) bool { return agents[i].Name < agents[k].Name })
+	return agents
+}
+
+// Command "refresh" - discard the agent cache and discover the agents again.
+func refreshCommand(args []string) int {
+	flags := newFlagSet("refresh")
+	if !parseCommandLine(flags, args) || flags.NArg() != 0 {
+		printMessage("refreshUsage")
+		return 2
+	}
+	if err := os.Remove(agentCacheFile()); err != nil && !os.IsNotExist(err) {
+		printMessage("agentCacheWriteFailed", err)
+		return 1
+	}
+	agents, err := discoverAgents()
+	if err != nil {
+		printMessage("agentDiscoveryFailed", err)
+		return 1
+	}
+	printMessage("agentsDiscovered", len(agents))
+	for _, agent := range agents {
+		fmt.Printf("  %-24s %-10s %-12s %s\n", agent.Name, agent.Type, agent.State, agent.QueueManager)
+	}
+	return 0
+}
+
+// Subcommands of the "agents" command.
+var agentsCommands = map[string]func(args []string) int{
+	"list":   agentsListCommand,
+	"show":   agentsShowCommand,
+	"create": agentsCreateCommand,
+	"delete": agentsDeleteCommand,
+}
+
+// Command "agents" - list and show agents.
+func agentsCommand(args []string) int {
+	if len(args) == 0 {
+		printMessage("agentsUsage")
+		return 2
+	}
+	run, found := agentsCommands[args[0]]
+	if !found {
+		printMessage("agentsUsage")
+		return 2
+	}
+	return run(args[1:])
+}
+
+// Filter of the agents listed.
+type AgentFilter struct {
+	Name   string
+	States []string
+	Types  []string
+}
+
+/* Check if an agent passes the filter. The name is a wildcard pattern, and
+* the states and types are compared regardless of case.
+* agent - Agent to check
+ */
+func (filter AgentFilter) matches(agent AgentInfo) bool {
+	if matched, _ := path.Match(strings.ToUpper(filter.Name), strings.ToUpper(agent.Name)); len(filter.Name) > 0 && !matched {
+		return false
+	}
+	return matchesAny(agent.State, filter.States) && matchesAny(agent.Type, filter.Types)
+}
+
+// Check if a value is one of a list of values regardless of case, or the
+// list is empty.
+func matchesAny(value string, values []string) bool {
+	for _, candidate := range values {
+		if strings.EqualFold(value, candidate) {
+			return true
+		}
+	}
+	return len(values) == 0
+}
+
+// Columns by which agents can be sorted, with their comparison.
+var agentSortColumns = map[string]func(agent, other AgentInfo) int{
+	"name":    func(agent, other AgentInfo) int { return strings.Compare(agent.Name, other.Name) },
+	"type":    func(agent, other AgentInfo) int { return strings.Compare(agent.Type, other.Type) },
+	"state":   func(agent, other AgentInfo) int { return strings.Compare(agent.State, other.State) },
+	"qmgr":    func(agent, other AgentInfo) int { return strings.Compare(agent.QueueManager, other.QueueManager) },
+	"version": func(agent, other AgentInfo) int { return compareVersions(agent.Version, other.Version) },
+}
+
+/* Sort agents by columns, the first column deciding first. Agents equal in
+* every column stay sorted by name.
+* agents  - Agents to sort, sorted by name
+* columns - Columns of agentSortColumns
+* reverse - Whether to sort in descending order
+ */
+func sortAgents(agents []AgentInfo, columns []string, reverse bool) {
+	sort.SliceStable(agents, func(i, k int) bool {
+		for _, column := range columns {
+			if order := agentSortColumns[column](agents[i], agents[k]); order != 0 {
+				return (order < 0) != reverse
+			}
+		}
+		return false
+	})
+}
+
+// Command "agents list" - list the agents of the MQ Web Server.
+func agentsListCommand(args []string) int {
+	flags := newFlagSet("agents list")
+	routeName := flags.String("route", "", "Route whose MQ Web Server is queried")
+	output := flags.String("output", "table", "Output format, table or json")
+	filter := AgentFilter{}
+	flags.StringVar(&filter.Name, "name", "", "Wildcard pattern of the names of the agents listed")
+	states := flags.String("state", "", "Comma separated states of the agents listed, such as ready,active")
+	types := flags.String("type", "", "Comma separated types of the agents listed, standard, bridge or cdBridge")
+	sortColumns := flags.String("sort", "name", "Comma separated columns sorting the agents, name, type, state, qmgr or version")
+	reverse := flags.Bool("reverse", false, "Sort the agents in descending order")
+	if !parseCommandLine(flags, args) || flags.NArg() != 0 || (*output != "table" && *output != "json") {
+		printMessage("agentsUsage")
+		return 2
+	}
+	if _, err := path.Match(filter.Name, ""); err != nil {
+		printMessage("agentsUsage")
+		return 2
+	}
+	columns := strings.Split(strings.ToLower(*sortColumns), ",")
+	for _, column := range columns {
+		if _, found := agentSortColumns[column]; !found {
+			printMessage("agentsUsage")
+			return 2
+		}
+	}
+	if len(*states) > 0 {
+		filter.States = strings.Split(*states, ",")
+	}
+	if len(*types) > 0 {
+		filter.Types = strings.Split(*types, ",")
+	}
+	route, err := findRoute(*routeName)
+	if err != nil {
+		printError(err)
+		return 2
+	}
+	agentsUrl := agentCollectionUrlOf(route.transferUrl()) + "?attributes=*"
+	response, body, err := callMQWeb("GET", agentsUrl, "")
+	if err != nil {
+		logMessage("agentQueryFailed", []any{logUrl, agentsUrl}, agentsUrl, err)
+		return 1
+	}
+	if response.StatusCode != http.StatusOK {
+		logMessage("agentQueryFailed", []any{logUrl, agentsUrl}, agentsUrl, mqWebError(response, body))
+		return 1
+	}
+	agents := []AgentInfo{}
+	for _, agent := range parseAgents(body, time.Now()) {
+		if filter.matches(agent) {
+			agents = append(agents, agent)
+		}
+	}
+	sortAgents(agents, columns, *reverse)
+
+	if *output == "json" {
+		raw := map[string]string{}
+		for _, agent := range gjson.Get(body, "agent").Array() {
+			raw[agent.Get("name").String()] = agent.Raw
+		}
+		selected := []string{}
+		for _, agent := range agents {
+			selected = append(selected, raw[agent.Name])
+		}
+		fmt.Println(indentRequest("[" + strings.Join(selected, ",") + "]"))
+		return 0
+	}
+	printMessage("agentsListed", len(agents))
+	fmt.Printf("%-28s %-12s %-12s %-20s %s\n", "NAME", "TYPE", "STATE", "QUEUE MANAGER", "VERSION")
+	for _, agent := range agents {
+		fmt.Printf("%-28s %-12s %-12s %-20s %s\n", agent.Name, agent.Type, agent.State, agent.QueueManager, agent.Version)
+	}
+	return 0
+}
+
+// Command "agents show" - print every attribute of an agent.
+func agentsShowCommand(args []string) int {
+	flags := newFlagSet("agents show")
+	routeName := flags.String("route", "", "Route whose MQ Web Server is queried")
+	if !parseCommandLine(flags, args) || flags.NArg() != 1 {
+		printMessage("agentsUsage")
+		return 2
+	}
+	route, err := findRoute(*routeName)
+	if err != nil {
+		printError(err)
+		return 2
+	}
+	agentUrl := agentCollectionUrlOf(route.transferUrl()) + "/" + url.PathEscape(flags.Arg(0))
+	response, body, err := callMQWeb("GET", agentUrl+"?attributes=*", "")
+	if err != nil {
+		logMessage("agentQueryFailed", []any{logUrl, agentUrl}, agentUrl, err)
+		return 1
+	}
+	agent := gjson.Get(body, "agent.0")
+	if response.StatusCode == http.StatusNotFound || (response.StatusCode == http.StatusOK && !agent.Exists()) {
+		printMessage("agentNotFound", flags.Arg(0))
+		return 1
+	}
+	if response.StatusCode != http.StatusOK {
+		logMessage("agentQueryFailed", []any{logUrl, agentUrl}, agentUrl, mqWebError(response, body))
+		return 1
+	}
+	printAttributes("", agent)
+	return 0
+}
+
+/* Print the attributes of a JSON object as "path: value" lines, the path of
+* nested attributes joined with dots.
+* prefix - Path of the object
+* object - JSON object
+ */
+func printAttributes(prefix string, object gjson.Result) {
+	object.ForEach(func(key, value gjson.Result) bool {
+		path := key.String()
+		if len(prefix) > 0 {
+			path = prefix + "." + path
+		}
+		if value.IsObject() {
+			printAttributes(path, value)
+		} else {
+			fmt.Printf("%-32s %s\n", path+":", value.String())
+		}
+		return true
+	})
+}
@@ -0,0 +1,235 @@
+/*
+© Copyright IBM Corporation 2022, 2022
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+/*
+* This file contains the audit log, a JSON Lines file to which a record is
+* appended for every transfer request submitted by "submit", "batch", "fixup"
+* and "release", once its outcome is known:
+*
+*   "auditLog": "/var/log/mft/audit.jsonl"
+*
+* A record gives who submitted the request (the user of the operating system
+* and of the MQ Web Server, and the host), when, the command and route, the
+* SHA-256 of the request, the transfer ID and the outcome with the final state
+* of the transfer. Records are chained to make the log tamper-evident: each
+* holds the hash of the previous record and its own hash, the SHA-256 of the
+* record without its hash. Changing, removing or inserting a record breaks
+* the chain from there, which "audit verify" reports. Removing the last
+* records leaves a valid chain, so keep a copy of the last hash elsewhere to
+* detect it. The chain assumes that a single process appends at a time; give
+* concurrent runs their own file.
+ */
+package main
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/user"
+	"strings"
+	"time"
+
+	"github.com/tidwall/gjson"
+)
+
+// Record of the audit log.
+type AuditRecord struct {
+	Time          time.Time `json:"time"`
+	RunId         string    `json:"runId"`
+	User          string    `json:"user"`
+	MQUser        string    `json:"mqUser,omitempty"`
+	Host          string    `json:"host"`
+	Command       string    `json:"command"`
+	Route         string    `json:"route,omitempty"`
+	Url           string    `json:"url"`
+	RequestSha256 string    `json:"requestSha256"`
+	TransferId    string    `json:"transferId,omitempty"`
+	Outcome       string    `json:"outcome"`
+	State         string    `json:"state,omitempty"`
+	PreviousHash  string    `json:"previousHash"`
+	Hash          string    `json:"hash"`
+}
+
+// Return the hash of a record, computed without its hash.
+func (record AuditRecord) computeHash() string {
+	record.Hash = ""
+	content, _ := json.Marshal(record)
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
+
+// Return the name of the user of the operating system.
+func osUserName() string {
+	if current, err := user.Current(); err == nil {
+		return current.Username
+	}
+	if name := os.Getenv("USER"); len(name) > 0 {
+		return name
+	}
+	return os.Getenv("USERNAME")
+}
+
+/* Append a record for a submitted transfer request to the audit log, if
+* one is configured. Errors are printed, the transfer having been submitted.
+* command        - Command submitting the request
+* route          - Name of the route of the transfer
+* url            - URL of the transfer resource the request was posted to
+* request        - Transfer request in JSON format
+* outcome        - Outcome of the submission: submitted, notEnded, scheduled
+*                  or failed
+* transferStatus - Last response of the transfer status query, if any
+ */
+func auditSubmission(command string, route string, url string, request string, outcome string, transferStatus string) {
+	if len(config.AuditLog) == 0 {
+		return
+	}
+	sum := sha256.Sum256([]byte(request))
+	transfer := gjson.Get(transferStatus, "transfer.0")
+	host, _ := os.Hostname()
+	record := AuditRecord{
+		Time:          time.Now().UTC(),
+		RunId:         runId,
+		User:          osUserName(),
+		MQUser:        config.UserId,
+		Host:          host,
+		Command:       command,
+		Route:         route,
+		Url:           url,
+		RequestSha256: hex.EncodeToString(sum[:]),
+		TransferId:    transfer.Get("id").String(),
+		Outcome:       outcome,
+		State:         transfer.Get("status.state").String(),
+	}
+	if err := appendAuditRecord(config.AuditLog, record); err != nil {
+		printMessage("auditWriteFailed", config.AuditLog, err)
+	}
+}
+
+/* Chain a record to the last record of the audit log and append it.
+* fileName - Audit log
+* record   - Record to append, without its hashes
+ */
+func appendAuditRecord(fileName string, record AuditRecord) error {
+	records, err := readAuditLog(fileName)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if len(records) > 0 {
+		record.PreviousHash = records[len(records)-1].Hash
+	}
+	record.Hash = record.computeHash()
+	content, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	file, err := os.OpenFile(fileName, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	if _, err := file.Write(append(content, '\n')); err != nil {
+		return err
+	}
+	return file.Close()
+}
+
+/* Read the records of an audit log.
+* fileName - Audit log
+ */
+func readAuditLog(fileName string) ([]AuditRecord, error) {
+	file, err := os.Open(fileName)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	records := []AuditRecord{}
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(strings.TrimSpace(scanner.Text())) == 0 {
+			continue
+		}
+		var record AuditRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			return records, fmt.Errorf("%s", message("auditRecordInvalid", fileName, line, err))
+		}
+		records = append(records, record)
+	}
+	return records, scanner.Err()
+}
+
+/* Check the chain of the records of an audit log. Returns the number of the
+* first record breaking the chain, from 1, or 0 if the chain is intact.
+* records - Records of the audit log
+ */
+func verifyAuditChain(records []AuditRecord) int {
+	previous := ""
+	for index, record := range records {
+		if record.PreviousHash != previous || record.Hash != record.computeHash() {
+			return index + 1
+		}
+		previous = record.Hash
+	}
+	return 0
+}
+
+// Subcommands of the audit command.
+var auditCommands = map[string]func(args []string) int{
+	"verify": auditVerifyCommand,
+}
+
+// Command "audit" - check the audit log.
+func auditCommand(args []string) int {
+	if len(args) == 0 {
+		printMessage("auditUsage")
+		return 2
+	}
+	command, found := auditCommands[args[0]]
+	if !found {
+		printMessage("auditUsage")
+		return 2
+	}
+	return command(args[1:])
+}
+
+// Command "audit verify" - check that the records of the audit log are
+// chained.
+func auditVerifyCommand(args []string) int {
+	flags := newFlagSet("audit verify")
+	fileName := flags.String("file", "", "Audit log to check, by default the auditLog of the configuration")
+	if !parseCommandLine(flags, args) || flags.NArg() != 0 {
+		printMessage("auditUsage")
+		return 2
+	}
+	if len(*fileName) == 0 {
+		*fileName = config.AuditLog
+	}
+	if len(*fileName) == 0 {
+		printMessage("auditUsage")
+		return 2
+	}
+	records, err := readAuditLog(*fileName)
+	if err != nil {
+		printMessage("auditReadFailed", *fileName, err)
+		return 1
+	}
+	if broken := verifyAuditChain(records); broken > 0 {
+		printMessage("auditChainBroken", *fileName, broken, len(records))
+		return 1
+	}
+	printMessage("auditVerified", *fileName, len(records))
+	return 0
+}
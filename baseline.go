@@ -0,0 +1,269 @@
+/*
+© Copyright IBM Corporation 2022, 2022
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+/*
+* This file contains the comparison of a run against the previous run of the
+* same route. When the program runs on the source agent host, the files of the
+* source item are listed with their sizes before the transfer is submitted and
+* compared with the list recorded by the previous run. Large changes in file
+* count or total size are reported as warnings, which catches upstream data
+* problems before the data reaches its destination.
+*
+* The comparison is enabled by "compareBaseline" on a route or by the -compare
+* flag of the submit command. The list of the last submitted run is kept in the
+* "baselines" directory under the state directory.
+*
+* The limits of change are set by "anomalyThresholds" in the configuration or
+* on a route. A route with "holdOnAnomaly" set is not submitted when an
+* anomaly is found. Its transfer is held until reviewed and released with the
+* "release" command, and the "alertCommand" of the configuration is run to
+* alert a human. The command receives the route, held transfer id, run ID and
+* anomalies in the MFT_ALERT_ROUTE, MFT_ALERT_HELD_ID, MFT_ALERT_RUN_ID and
+* MFT_ALERT_MESSAGE environment variables, and the "notificationEmail" of the
+* route, if any, in MFT_ALERT_EMAIL.
+ */
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Files of a source item with their sizes at a point in time.
+type Snapshot struct {
+	Route string           `json:"route"`
+	Time  time.Time        `json:"time"`
+	Files map[string]int64 `json:"files"`
+}
+
+// Limits of change between two runs, in percent of the previous run.
+type AnomalyThresholds struct {
+	MaxFileCountDrop   float64 `json:"maxFileCountDrop"`
+	MaxFileCountGrowth float64 `json:"maxFileCountGrowth"`
+	MaxBytesDrop       float64 `json:"maxBytesDrop"`
+	MaxBytesGrowth     float64 `json:"maxBytesGrowth"`
+}
+
+// Thresholds used to compare runs when none are configured.
+var defaultAnomalyThresholds = AnomalyThresholds{
+	MaxFileCountDrop:   50,
+	MaxFileCountGrowth: 100,
+	MaxBytesDrop:       50,
+	MaxBytesGrowth:     100,
+}
+
+// Return the total size of the files in a snapshot.
+func (snapshot Snapshot) totalBytes() int64 {
+	var total int64
+	for _, size := range snapshot.Files {
+		total += size
+	}
+	return total
+}
+
+/* List the files of the source items of a route. The sources must be
+* accessible from the host running this program.
+* route - Route of the transfer
+ */
+func snapshotSource(route Route) (Snapshot, error) {
+	snapshot := Snapshot{Route: route.Name, Time: time.Now(), Files: map[string]int64{}}
+	if route.hasRemoteSources() {
+		return snapshot, fmt.Errorf("the sources are not on this host but on bridge agent %s", route.SourceAgent)
+	}
+	for _, item := range route.transferItems() {
+		// A pattern matching no file is an empty source, not an error
+		sources := []string{item.Source}
+		if hasWildcard(item.Source) {
+			sources = localSourcePaths(item.Source)
+		}
+		for _, source := range sources {
+			err := walkSourceFiles(item, source, func(path string, info os.FileInfo, err error) error {
+				if err != nil {
+					return err
+				}
+				snapshot.Files[path] = info.Size()
+				return nil
+			})
+			if err != nil {
+				return snapshot, err
+			}
+		}
+	}
+	return snapshot, nil
+}
+
+// Returns the file holding the baseline of a route.
+func baselineFile(routeName string) string {
+	return filepath.Join(config.StateDirectory, "baselines", routeName+".json")
+}
+
+// Read the baseline of a route. A nil snapshot is returned if there is none.
+func readBaseline(routeName string) (*Snapshot, error) {
+	content, err := os.ReadFile(baselineFile(routeName))
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	var snapshot Snapshot
+	if err := json.Unmarshal(content, &snapshot); err != nil {
+		return nil, err
+	}
+	return &snapshot, nil
+}
+
+// Record a snapshot as the baseline of its route.
+func saveBaseline(snapshot Snapshot) error {
+	fileName := baselineFile(snapshot.Route)
+	if err := os.MkdirAll(filepath.Dir(fileName), 0700); err != nil {
+		return err
+	}
+	content, err := json.MarshalIndent(snapshot, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(fileName, content, 0600)
+}
+
+/* Compare a snapshot with the previous one and return the anomalies found.
+* previous   - Snapshot of the previous run
+* current    - Snapshot of this run
+* thresholds - Limits of change
+ */
+func compareSnapshots(previous Snapshot, current Snapshot, thresholds AnomalyThresholds) []string {
+	anomalies := []string{}
+	previousCount, currentCount := float64(len(previous.Files)), float64(len(current.Files))
+	if anomaly := checkChange("file count", previousCount, currentCount, thresholds.MaxFileCountDrop, thresholds.MaxFileCountGrowth); len(anomaly) > 0 {
+		anomalies = append(anomalies, anomaly)
+	}
+	previousBytes, currentBytes := float64(previous.totalBytes()), float64(current.totalBytes())
+	if anomaly := checkChange("total bytes", previousBytes, currentBytes, thresholds.MaxBytesDrop, thresholds.MaxBytesGrowth); len(anomaly) > 0 {
+		anomalies = append(anomalies, anomaly)
+	}
+	return anomalies
+}
+
+/* Check the change of a measure against its limits.
+* measure   - Name of the measure
+* previous  - Value in the previous run
+* current   - Value in this run
+* maxDrop   - Largest decrease allowed in percent, 0 for no limit
+* maxGrowth - Largest increase allowed in percent, 0 for no limit
+ */
+func checkChange(measure string, previous float64, current float64, maxDrop float64, maxGrowth float64) string {
+	if previous == 0 {
+		return ""
+	}
+	change := (current - previous) * 100 / previous
+	if maxDrop > 0 && -change >= maxDrop {
+		return fmt.Sprintf("%s dropped %.0f%% from %.0f to %.0f", measure, -change, previous, current)
+	}
+	if maxGrowth > 0 && change >= maxGrowth {
+		return fmt.Sprintf("%s grew %.0f%% from %.0f to %.0f", measure, change, previous, current)
+	}
+	return ""
+}
+
+/* Compare the source of a route with its baseline and print the anomalies.
+* Returns the snapshot of this run, to be saved once the transfer has been
+* submitted, and the anomalies found.
+* route - Route of the transfer
+ */
+func compareWithBaseline(route Route) (*Snapshot, []string) {
+	current, err := snapshotSource(route)
+	if err != nil {
+		printMessage("baselineCompareFailed", route.Name, err)
+		return nil, nil
+	}
+	previous, err := readBaseline(route.Name)
+	if err != nil {
+		printMessage("baselineReadFailed", route.Name, err)
+		return &current, nil
+	}
+	if previous == nil {
+		printMessage("baselineNoPreviousRun", route.Name, len(current.Files), current.totalBytes())
+		return &current, nil
+	}
+	anomalies := compareSnapshots(*previous, current, anomalyThresholds(route))
+	for _, anomaly := range anomalies {
+		printMessage("baselineAnomaly", route.Name, anomaly, previous.Time.Format(time.RFC1123))
+	}
+	return &current, anomalies
+}
+
+// Return the thresholds that apply to a route.
+func anomalyThresholds(route Route) AnomalyThresholds {
+	if route.AnomalyThresholds != nil {
+		return *route.AnomalyThresholds
+	}
+	if config.AnomalyThresholds != nil {
+		return *config.AnomalyThresholds
+	}
+	return defaultAnomalyThresholds
+}
+
+/* Hold a transfer whose source is anomalous until it is reviewed, and alert
+* a human. Returns the ID of the held transfer and the exit code of the
+* submit command.
+* route           - Route of the transfer
+* transferRequest - Transfer request in JSON format
+* snapshot        - Snapshot of the source, saved as baseline on release
+* anomalies       - Anomalies found
+ */
+func holdAnomalousTransfer(route Route, transferRequest string, snapshot *Snapshot, anomalies []string) (string, int) {
+	held, err := holdTransfer(HeldTransfer{
+		Route:          route.Name,
+		Reason:         "anomaly: " + strings.Join(anomalies, ", "),
+		ReviewRequired: true,
+		Request:        transferRequest,
+		TransferUrl:    route.transferUrl(),
+		Snapshot:       snapshot,
+	})
+	if err != nil {
+		printMessage("holdFailed", err)
+		return "", 1
+	}
+	printMessage("heldForReview", route.Name, held.Id, held.Id)
+	if err := runAlertCommand(held, anomalies); err != nil {
+		printMessage("alertCommandFailed", err)
+	}
+	// Not submitting is a failure for the scheduler that started the program
+	return held.Id, 1
+}
+
+/* Run the configured alert command for a held transfer.
+* held      - Held transfer
+* anomalies - Anomalies found
+ */
+func runAlertCommand(held HeldTransfer, anomalies []string) error {
+	if len(config.AlertCommand) == 0 {
+		return nil
+	}
+	command := exec.Command(config.AlertCommand[0], config.AlertCommand[1:]...)
+	command.Env = append(os.Environ(),
+		"MFT_ALERT_ROUTE="+held.Route,
+		"MFT_ALERT_HELD_ID="+held.Id,
+		"MFT_ALERT_RUN_ID="+held.RunId,
+		"MFT_ALERT_MESSAGE=Route "+held.Route+" held for review: "+strings.Join(anomalies, ", "))
+	if route, err := findRoute(held.Route); err == nil && len(route.NotificationEmail) > 0 {
+		command.Env = append(command.Env, "MFT_ALERT_EMAIL="+route.NotificationEmail)
+	}
+	command.Stdout = os.Stdout
+	command.Stderr = os.Stderr
+	return command.Run()
+}
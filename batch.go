@@ -0,0 +1,356 @@
+/*
+© Copyright IBM Corporation 2022, 2022
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+/*
+* This file contains the "batch" command, which submits the transfers of
+* several routes, or of all the routes of the configuration with -all, and
+* waits for them to end. Before the first transfer is submitted, the
+* coordination and command queue managers are checked, see qmgr.go, and with
+* "checkAgents" in the configuration the agents of every route. The transfers
+* are submitted one after the other and their status is polled concurrently
+* by a pool of -workers, each polling one transfer at a time as "submit" does,
+* see poll.go. So as not to overload an agent, the transfers in progress of a
+* source agent can be limited, by "maxInFlightPerAgent" or -per-agent for
+* every agent and by "agentInFlightLimits" for some agents:
+*
+*   "maxInFlightPerAgent": 4, "agentInFlightLimits": { "MAINFRAME": 1 }
+*
+* The routes of an agent at its limit wait until one of its transfers ends,
+* while the routes of other agents are submitted. While they
+* run, the changes of state of the transfers are printed, and when all have
+* ended the final state of each transfer is listed.
+*
+* A scheduled route is submitted but not polled, its transfer starting
+* later. The results are followed by a summary of the batch:
+*
+*   4 transfers: 2 successful, 1 partially successful, 1 failed, 0 not ended
+*   Transferred 12.5 MiB in 42.3s, slowest transfer 414D...070A of route nightly in 31.9s
+*
+* With -csv the results are also written to a CSV file, see export.go, and
+* with -junit to a JUnit XML report, see junit.go.
+*
+* A transfer is failed when it failed, was cancelled or could not be
+* submitted, and not ended when its polling timed out. Scheduled transfers
+* count as successful. The exit code is 0 when every transfer is successful,
+* 3 when some are partially successful and none failed or did not end, and 1
+* otherwise.
+ */
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tidwall/gjson"
+)
+
+// Default number of transfers polled at the same time.
+const defaultBatchWorkers = 4
+
+// Exit codes of the batch command, and of the commands submitting a
+// transfer, see transferExitCode.
+const (
+	exitSuccessful = 0
+	exitFailed     = 1
+	exitPartial    = 3
+)
+
+// Transfer of a batch.
+type BatchTransfer struct {
+	Route       string
+	SourceAgent string
+	TransferUrl string
+	Request     string
+	TransferId  string
+	State       string
+	Status      int
+	Body        string
+	SubmittedAt time.Time
+	Bytes       int64
+	Duration    time.Duration
+}
+
+// Outcome of a batch.
+type BatchSummary struct {
+	Transfers  int
+	Successful int
+	Partial    int
+	Failed     int
+	NotEnded   int
+	Bytes      int64
+	Duration   time.Duration
+	Slowest    *BatchTransfer
+}
+
+// Command "batch" - submit the transfers of several routes.
+func batchCommand(args []string) int {
+	flags := newFlagSet("batch")
+	all := flags.Bool("all", false, "Submit the transfers of all the routes of the configuration")
+	workers := flags.Int("workers", defaultBatchWorkers, "Number of transfers polled at the same time")
+	perAgent := flags.Int("per-agent", -1, "Maximum number of transfers in progress per source agent, 0 for no limit")
+	csvFile := flags.String("csv", "", "Write the result of each transfer to a CSV file")
+	junitFile := flags.String("junit", "", "Write the results of the transfers to a JUnit XML report")
+	if !parseCommandLine(flags, args) || *workers < 1 || (*all == (flags.NArg() > 0)) {
+		printMessage("batchUsage")
+		return 2
+	}
+	routeNames := flags.Args()
+	if *all {
+		for _, route := range config.Routes {
+			routeNames = append(routeNames, route.Name)
+		}
+	}
+	// Check every route before submitting any transfer
+	routes := []Route{}
+	for _, name := range routeNames {
+		route, err := findSubmissionRoute(name, nil)
+		if err != nil {
+			printError(err)
+			return 2
+		}
+		if err := checkQueueManagers(route); err != nil {
+			printMessage("qmgrCheckFailed", route.Name, err)
+			return 1
+		}
+		if config.CheckAgents {
+			if err := checkRouteAgents(route); err != nil {
+				printMessage("agentCheckFailed", route.Name, err)
+				return 1
+			}
+		}
+		routes = append(routes, route)
+	}
+
+	printMessage("runId", runId)
+	started := time.Now()
+	transfers := runBatch(routes, *workers, *perAgent)
+	for index, transfer := range transfers {
+		outcome := submissionOutcome(transfer.Status)
+		switch {
+		case transfer.State == "notSubmitted":
+			outcome = "failed"
+		case routes[index].Schedule != nil:
+			outcome = "scheduled"
+		}
+		auditSubmission("batch", transfer.Route, routes[index].transferUrl(), transfer.Request, outcome, transfer.Body)
+	}
+
+	printMessage("batchResults", len(transfers))
+	if quietFlag {
+		for _, transfer := range transfers {
+			printQuietResult(transfer.TransferId, transfer.State)
+		}
+	} else {
+		fmt.Printf("%-20s %-48s %-20s %12s %s\n", "ROUTE", "ID", "STATE", "BYTES", "DURATION")
+		for _, transfer := range transfers {
+			duration := ""
+			if transfer.Duration > 0 {
+				duration = transfer.Duration.Round(time.Millisecond).String()
+			}
+			fmt.Printf("%-20s %-48s %-20s %12s %s\n", transfer.Route, transfer.TransferId, transfer.State, formatBytes(transfer.Bytes), duration)
+		}
+	}
+	if len(*csvFile) > 0 {
+		if err := writeBatchCsv(*csvFile, transfers); err != nil {
+			printMessage("batchCsvWriteFailed", *csvFile, err)
+		} else {
+			printMessage("batchCsvWritten", len(transfers), *csvFile)
+		}
+	}
+	if len(*junitFile) > 0 {
+		suites := []JUnitTestSuite{}
+		for _, transfer := range transfers {
+			suites = append(suites, transferTestSuite(transfer.Route, transfer.State, transfer.Body, transfer.Duration))
+		}
+		writeJUnitReport(*junitFile, suites)
+	}
+	summary := summarizeBatch(transfers, time.Since(started))
+	printMessage("batchSummary", summary.Transfers, summary.Successful, summary.Partial, summary.Failed, summary.NotEnded)
+	if summary.Slowest != nil {
+		printMessage("batchSlowest", formatBytes(summary.Bytes), summary.Duration.Round(time.Millisecond).String(),
+			summary.Slowest.TransferId, summary.Slowest.Route, summary.Slowest.Duration.Round(time.Millisecond).String())
+	} else {
+		printMessage("batchTransferred", formatBytes(summary.Bytes), summary.Duration.Round(time.Millisecond).String())
+	}
+	return summary.exitCode()
+}
+
+/* Summarize the outcome of a batch.
+* transfers - Transfers of the batch, with their final state
+* duration  - Wall-clock duration of the batch
+ */
+func summarizeBatch(transfers []*BatchTransfer, duration time.Duration) BatchSummary {
+	summary := BatchSummary{Transfers: len(transfers), Duration: duration}
+	for _, transfer := range transfers {
+		switch {
+		case transfer.State == "scheduled" || strings.EqualFold(transfer.State, "successful"):
+			summary.Successful++
+		case strings.EqualFold(transfer.State, "partiallySuccessful"):
+			summary.Partial++
+		case transfer.Status == pollTimedOutStatus:
+			summary.NotEnded++
+		default:
+			summary.Failed++
+		}
+		summary.Bytes += transfer.Bytes
+		if summary.Slowest == nil || transfer.Duration > summary.Slowest.Duration {
+			if transfer.Duration > 0 {
+				summary.Slowest = transfer
+			}
+		}
+	}
+	return summary
+}
+
+// Return the exit code of a batch: 0 when every transfer is successful, 3
+// when some are partially successful and the others successful, 1 otherwise.
+func (summary BatchSummary) exitCode() int {
+	switch {
+	case summary.Failed > 0 || summary.NotEnded > 0:
+		return exitFailed
+	case summary.Partial > 0:
+		return exitPartial
+	}
+	return exitSuccessful
+}
+
+/* Record the final state, bytes and duration of a polled transfer. The
+* duration is given by the statistics of the transfer, or else measured from
+* its submission.
+* transfer - Transfer of the batch
+ */
+func (transfer *BatchTransfer) record() {
+	status := gjson.Get(transfer.Body, "transfer.0")
+	if state := status.Get("status.state"); state.Exists() {
+		transfer.State = state.String()
+	} else {
+		transfer.State = "unknown"
+	}
+	progress := transferProgress(status)
+	transfer.Bytes = progress.Bytes
+	if transfer.Bytes == 0 && strings.EqualFold(transfer.State, "successful") {
+		transfer.Bytes = progress.TotalBytes
+	}
+	start, startErr := time.Parse(time.RFC3339, status.Get("statistics.startTime").String())
+	end, endErr := time.Parse(time.RFC3339, status.Get("statistics.endTime").String())
+	if startErr == nil && endErr == nil && end.After(start) {
+		transfer.Duration = end.Sub(start)
+	} else {
+		transfer.Duration = time.Since(transfer.SubmittedAt)
+	}
+}
+
+/* Submit the transfers of a batch and poll their status until they end, with
+* a pool of workers each polling one transfer at a time. A route is submitted
+* once its source agent has fewer transfers in progress than its limit,
+* routes whose agent is busy waiting until one of its transfers ends, while
+* the routes of other agents are submitted.
+* routes   - Routes of the batch, in the order of submission
+* workers  - Number of transfers polled at the same time
+* perAgent - Limit of the transfers in progress per source agent, -1 for the
+*            limit of the configuration
+ */
+func runBatch(routes []Route, workers int, perAgent int) []*BatchTransfer {
+	pending := make(chan *BatchTransfer)
+	// Buffered so that workers never wait for the submission loop
+	ended := make(chan string, len(routes))
+	var wait sync.WaitGroup
+	for worker := 0; worker < workers; worker++ {
+		wait.Add(1)
+		go func() {
+			defer wait.Done()
+			for transfer := range pending {
+				transfer.Status, transfer.Body = pollTransfer(transfer.TransferUrl, config.Polling, false)
+				transfer.record()
+				ended <- transfer.SourceAgent
+			}
+		}()
+	}
+
+	transfers := []*BatchTransfer{}
+	for _, route := range routes {
+		transfers = append(transfers, &BatchTransfer{Route: route.Name, SourceAgent: route.SourceAgent})
+	}
+	inProgress := map[string]int{}
+	waiting := map[int]bool{}
+	queued := []int{}
+	for index := range routes {
+		queued = append(queued, index)
+	}
+	for len(queued) > 0 {
+		next := -1
+		for position, index := range queued {
+			agent := routes[index].SourceAgent
+			if limit := agentInFlightLimit(agent, perAgent); limit == 0 || inProgress[agent] < limit {
+				next = position
+				break
+			}
+			if !waiting[index] {
+				printMessage("batchAgentBusy", routes[index].Name, agent, inProgress[agent])
+				waiting[index] = true
+			}
+		}
+		if next < 0 {
+			inProgress[<-ended]--
+			continue
+		}
+		index := queued[next]
+		queued = append(queued[:next], queued[next+1:]...)
+		route, transfer := routes[index], transfers[index]
+		transfer.SubmittedAt = time.Now()
+		transfer.Request = buildTransferJsonRequest(route)
+		status, transferUrl := postTransferRequest(route.transferUrl(), transfer.Request)
+		switch {
+		case status != http.StatusAccepted:
+			transfer.State = "notSubmitted"
+		case route.Schedule != nil:
+			transfer.State, transfer.Status = "scheduled", http.StatusOK
+		default:
+			transfer.TransferUrl, transfer.TransferId = transferUrl, path.Base(transferUrl)
+			inProgress[route.SourceAgent]++
+			pending <- transfer
+		}
+		// Take the transfers that ended into account before the next choice
+		for drained := false; !drained; {
+			select {
+			case agent := <-ended:
+				inProgress[agent]--
+			default:
+				drained = true
+			}
+		}
+	}
+	close(pending)
+	wait.Wait()
+	return transfers
+}
+
+/* Return the limit of the transfers in progress of a source agent, 0 for no
+* limit: the limit of the agent in "agentInFlightLimits", or else perAgent,
+* or else "maxInFlightPerAgent" of the configuration.
+* agent    - Name of the source agent
+* perAgent - Limit given on the command line, -1 if none
+ */
+func agentInFlightLimit(agent string, perAgent int) int {
+	if limit, found := config.AgentInFlightLimits[agent]; found {
+		return limit
+	}
+	if perAgent >= 0 {
+		return perAgent
+	}
+	return config.MaxInFlightPerAgent
+}
@@ -0,0 +1,130 @@
+/*
+© Copyright IBM Corporation 2022, 2022
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+/*
+* This file contains the transfers from or to a protocol bridge agent, which
+* reads and writes the files of a FTP, FTPS or SFTP server. The source or
+* destination agent of a route is marked as a bridge with "sourceBridge" or
+* "destinationBridge":
+*
+*   "destinationBridge": { "server": "sftp1" }
+*
+* The items of a bridge are paths on the file server, absolute or relative to
+* the home directory of the user the bridge logs in as. A bridge connected to
+* several servers selects one with a server:path prefix, added to the items
+* of the route that have none from the "server" of the bridge. The sources of
+* a bridge are not on this host, so the estimate, baseline and checksums that
+* read local files skip them.
+*
+* The transfer request of the MQ REST API has no attribute for the credentials
+* of the file server. The bridge agent maps the MQ user submitting the
+* transfer to server credentials with its ProtocolBridgeCredentials.xml file
+* or credentials exit, so a different server user is selected by submitting
+* with a different MQ Web Server user, see the "user" of the configuration.
+ */
+package main
+
+import (
+	"strings"
+)
+
+// Protocol bridge agent at one end of a route.
+type BridgeEndpoint struct {
+	Server string `json:"server"`
+}
+
+/* Return the path of an item on the file server of a bridge, with the
+* server:path prefix of the bridge unless the item has one.
+* name - Name of the item
+ */
+func (bridge *BridgeEndpoint) path(name string) string {
+	if bridge == nil || len(bridge.Server) == 0 || len(name) == 0 || len(bridgeServer(name)) > 0 {
+		return name
+	}
+	return bridge.Server + ":" + name
+}
+
+// Return the server of a server:path item name, blank if it has none.
+func bridgeServer(name string) string {
+	if server, _, found := strings.Cut(name, ":"); found && isBridgeServerName(server) {
+		return server
+	}
+	return ""
+}
+
+// Check if a name is a valid name of a protocol bridge server.
+func isBridgeServerName(name string) bool {
+	if len(name) == 0 {
+		return false
+	}
+	for _, char := range name {
+		if !(char >= 'A' && char <= 'Z' || char >= 'a' && char <= 'z' || char >= '0' && char <= '9' ||
+			char == '.' || char == '_' || char == '-') {
+			return false
+		}
+	}
+	return true
+}
+
+// Return an item with the paths of the bridges of the route. Items that are
+// not files or directories are left for validateBridgeOptions to report.
+func (route Route) withBridgePaths(item TransferItem) TransferItem {
+	if item.SourceType == "file" || item.SourceType == "directory" {
+		item.Source = route.SourceBridge.path(item.Source)
+	}
+	if item.DestinationType == "file" || item.DestinationType == "directory" {
+		item.Destination = route.DestinationBridge.path(item.Destination)
+	}
+	return item
+}
+
+// Check if the sources of a route are on the file server of a bridge or on a
+// Connect:Direct node rather than on this host.
+func (route Route) hasRemoteSources() bool {
+	return route.SourceBridge != nil || route.SourceConnectDirect != nil
+}
+
+/* Check the items of a route with a bridge agent. Files and directories are
+* the only items of a file server.
+* route    - Route of the transfer
+* item     - Item of the transfer
+* pointer  - Returns the JSON pointer of an attribute of the item
+* problems - Problems found
+ */
+func validateBridgeOptions(route Route, item TransferItem, pointer func(tokens ...interface{}) string, problems *requestProblems) {
+	if route.SourceBridge != nil {
+		validateBridgeItem(route.SourceBridge, item.Source, item.SourceType, pointer("source"), problems)
+	}
+	if route.DestinationBridge != nil {
+		validateBridgeItem(route.DestinationBridge, item.Destination, item.DestinationType, pointer("destination"), problems)
+	}
+}
+
+/* Check an item of a bridge.
+* bridge   - Bridge of the item
+* name     - Name of the item
+* itemType - Type of the item
+* pointer  - JSON pointer of the item
+* problems - Problems found
+ */
+func validateBridgeItem(bridge *BridgeEndpoint, name string, itemType string, pointer string, problems *requestProblems) {
+	if len(bridge.Server) > 0 && !isBridgeServerName(bridge.Server) {
+		problems.add(pointer+"/name", bridge.Server, "is not a server name of letters, digits, '.', '_' or '-'")
+	}
+	if itemType != "file" && itemType != "directory" {
+		problems.add(pointer+"/type", itemType, "must be file or directory for a protocol bridge agent")
+	}
+	if server := bridgeServer(name); len(server) > 0 && len(name) == len(server)+1 {
+		problems.add(pointer+"/name", name, "has no path on server %s", server)
+	}
+}
@@ -0,0 +1,95 @@
+/*
+© Copyright IBM Corporation 2022, 2022
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+/*
+* This file contains the in-memory cache of the responses of the MQ Web Server
+* to GET requests, such as transfer status queries. When several parts of the
+* program need the status of the same transfer at about the same time, the
+* MQ Web Server is queried once and the response is reused for
+* "responseCacheTtl" in the configuration, 1s by default, 0 to disable the
+* cache. Only successful responses are cached, and a POST or DELETE to a
+* resource discards the cached responses of that resource.
+ */
+package main
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Default time a response is cached.
+const defaultResponseCacheTtl = time.Second
+
+// A cached response with its body, already read.
+type cachedResponse struct {
+	response *http.Response
+	body     string
+	expires  time.Time
+}
+
+// Cache of the responses to GET requests, by URL.
+type responseCache struct {
+	mutex   sync.Mutex
+	entries map[string]cachedResponse
+}
+
+// Responses cached by callMQWeb.
+var webResponses = &responseCache{entries: map[string]cachedResponse{}}
+
+/* Return the cached response to a GET request, if it has not expired.
+* url - URL of the request
+ */
+func (cache *responseCache) get(url string) (*http.Response, string, bool) {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+	entry, found := cache.entries[url]
+	if !found {
+		return nil, "", false
+	}
+	if time.Now().After(entry.expires) {
+		delete(cache.entries, url)
+		return nil, "", false
+	}
+	return entry.response, entry.body, true
+}
+
+/* Cache the response to a GET request if it is successful.
+* url      - URL of the request
+* response - Response received
+* body     - Body of the response
+ */
+func (cache *responseCache) put(url string, response *http.Response, body string) {
+	ttl := time.Duration(config.ResponseCacheTtl)
+	if ttl <= 0 || response == nil || response.StatusCode != http.StatusOK {
+		return
+	}
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+	cache.entries[url] = cachedResponse{response: response, body: body, expires: time.Now().Add(ttl)}
+}
+
+/* Discard the cached responses of a resource and of the resources below it.
+* url - URL of the resource, with or without query
+ */
+func (cache *responseCache) invalidate(url string) {
+	resource, _, _ := strings.Cut(url, "?")
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+	for cached := range cache.entries {
+		if strings.HasPrefix(cached, resource) {
+			delete(cache.entries, cached)
+		}
+	}
+}
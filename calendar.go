@@ -0,0 +1,183 @@
+/*
+© Copyright IBM Corporation 2022, 2022
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+/*
+* This file contains the support for holiday calendars. A route with
+* "businessDaysOnly" set is not submitted on Saturdays, Sundays and on the
+* holidays listed in the calendars named by "holidayCalendars" in the
+* configuration, so that the program can be started every day by cron or
+* any other scheduler.
+*
+* A calendar is either
+* 1) a text file with one date (YYYY-MM-DD) per line. Text following a # is
+*    a comment, or
+* 2) an iCalendar (.ics) file. The start date of every event is a holiday.
+*    Events repeated with RRULE:FREQ=YEARLY apply on the same day every year.
+ */
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Layout of dates in text holiday calendars.
+const calendarDateLayout = "2006-01-02"
+
+// Holidays read from the calendars of a holiday set.
+type holidaySet struct {
+	dates  map[string]string // YYYY-MM-DD to holiday name
+	yearly map[string]string // MM-DD to holiday name
+}
+
+/* Check whether a route may be submitted on the given day. The returned
+* string explains why the day is not a business day.
+* route - Route of the transfer
+* day   - Day to check
+ */
+func isBusinessDay(route Route, day time.Time) (bool, string, error) {
+	if !route.BusinessDaysOnly {
+		return true, "", nil
+	}
+	if day.Weekday() == time.Saturday || day.Weekday() == time.Sunday {
+		return false, day.Weekday().String(), nil
+	}
+	holidays, err := readHolidayCalendars(config.HolidayCalendars)
+	if err != nil {
+		return false, "", err
+	}
+	if name, found := holidays.lookup(day); found {
+		return false, name, nil
+	}
+	return true, "", nil
+}
+
+// Return the name of the holiday on a day, if any.
+func (holidays holidaySet) lookup(day time.Time) (string, bool) {
+	if name, found := holidays.dates[day.Format(calendarDateLayout)]; found {
+		return name, true
+	}
+	name, found := holidays.yearly[day.Format("01-02")]
+	return name, found
+}
+
+/* Read holiday calendar files.
+* fileNames - Text or iCalendar files
+ */
+func readHolidayCalendars(fileNames []string) (holidaySet, error) {
+	holidays := holidaySet{dates: map[string]string{}, yearly: map[string]string{}}
+	for _, fileName := range fileNames {
+		var err error
+		if strings.EqualFold(filepath.Ext(fileName), ".ics") {
+			err = readICalendar(fileName, holidays)
+		} else {
+			err = readTextCalendar(fileName, holidays)
+		}
+		if err != nil {
+			return holidays, fmt.Errorf("holiday calendar %s: %v", fileName, err)
+		}
+	}
+	return holidays, nil
+}
+
+// Read a text calendar with one date per line.
+func readTextCalendar(fileName string, holidays holidaySet) error {
+	file, err := os.Open(fileName)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := scanner.Text()
+		name := ""
+		if index := strings.Index(line, "#"); index >= 0 {
+			name = strings.TrimSpace(line[index+1:])
+			line = line[:index]
+		}
+		line = strings.TrimSpace(line)
+		if len(line) == 0 {
+			continue
+		}
+		date, err := time.Parse(calendarDateLayout, line)
+		if err != nil {
+			return fmt.Errorf("line %d: invalid date %q", lineNumber, line)
+		}
+		if len(name) == 0 {
+			name = "holiday"
+		}
+		holidays.dates[date.Format(calendarDateLayout)] = name
+	}
+	return scanner.Err()
+}
+
+// Read the events of an iCalendar file.
+func readICalendar(fileName string, holidays holidaySet) error {
+	content, err := os.ReadFile(fileName)
+	if err != nil {
+		return err
+	}
+	// Unfold continuation lines, which start with a space or tab
+	text := strings.ReplaceAll(string(content), "\r\n", "\n")
+	text = strings.ReplaceAll(text, "\n ", "")
+	text = strings.ReplaceAll(text, "\n\t", "")
+
+	var start, summary string
+	var yearly bool
+	for _, line := range strings.Split(text, "\n") {
+		colon := strings.Index(line, ":")
+		if colon < 0 {
+			continue
+		}
+		name, value := strings.ToUpper(line[:colon]), strings.TrimSpace(line[colon+1:])
+		if semicolon := strings.Index(name, ";"); semicolon >= 0 {
+			name = name[:semicolon]
+		}
+		switch name {
+		case "BEGIN":
+			if value == "VEVENT" {
+				start, summary, yearly = "", "", false
+			}
+		case "DTSTART":
+			start = value
+		case "SUMMARY":
+			summary = value
+		case "RRULE":
+			yearly = strings.Contains(strings.ToUpper(value), "FREQ=YEARLY")
+		case "END":
+			if value != "VEVENT" || len(start) < 8 {
+				continue
+			}
+			date, err := time.Parse("20060102", start[:8])
+			if err != nil {
+				return fmt.Errorf("invalid event start %q", start)
+			}
+			if len(summary) == 0 {
+				summary = "holiday"
+			}
+			if yearly {
+				holidays.yearly[date.Format("01-02")] = summary
+			} else {
+				holidays.dates[date.Format(calendarDateLayout)] = summary
+			}
+		}
+	}
+	return nil
+}
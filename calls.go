@@ -0,0 +1,136 @@
+/*
+© Copyright IBM Corporation 2022, 2022
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+/*
+* This file contains the programs run by the agents before and after a
+* transfer, such as an antivirus scan of the source, a rename or the start of
+* a downstream job. A route sets them with "preSourceCall", "postSourceCall",
+* "preDestinationCall" and "postDestinationCall":
+*
+*   "preSourceCall": { "name": "/opt/av/scan", "arguments": ["/usr/srcdir"],
+*                      "retryCount": 2, "retryWait": 30, "successReturnCodes": "0|2" }
+*
+* type is executable, the default, antScript or jcl. The program must be in a
+* directory allowed by the commandPath property of the agent. It is run again
+* retryCount times, retryWait seconds apart, while its return code is not a
+* success. successReturnCodes lists the successful return codes, 0 by default,
+* as conditions combined with | (or) and & (and): a code, or a code preceded
+* by >, < or ! for greater than, less than or not equal, such as ">-1&<8".
+ */
+package main
+
+import (
+	"regexp"
+	"strings"
+
+	j "github.com/ricardolonga/jsongo"
+)
+
+// Types of the programs called by the agents.
+var programCallTypes = map[string]bool{"executable": true, "antScript": true, "jcl": true}
+
+// Sections of the program calls in the transfer request, in order of call.
+var programCallSections = []string{"preSourceCall", "preDestinationCall", "postSourceCall", "postDestinationCall"}
+
+// Condition on the return code of a program, such as 0, >2, <8 or !4.
+var returnCodeCondition = regexp.MustCompile(`^[<>!]?-?[0-9]+$`)
+
+// Program run by an agent before or after a transfer.
+type ProgramCall struct {
+	Name               string   `json:"name"`
+	Type               string   `json:"type"`
+	Arguments          []string `json:"arguments"`
+	RetryCount         int      `json:"retryCount"`
+	RetryWait          int      `json:"retryWait"`
+	SuccessReturnCodes string   `json:"successReturnCodes"`
+}
+
+// Return the program calls of a route by the name of their section in the
+// transfer request.
+func (route Route) programCalls() map[string]*ProgramCall {
+	return map[string]*ProgramCall{
+		"preSourceCall":       route.PreSourceCall,
+		"postSourceCall":      route.PostSourceCall,
+		"preDestinationCall":  route.PreDestinationCall,
+		"postDestinationCall": route.PostDestinationCall,
+	}
+}
+
+// Return the section of a program call in the transfer request.
+func (call ProgramCall) jsonObject() j.O {
+	object := j.Object().Put("name", call.Name)
+	if len(call.Type) > 0 {
+		object.Put("type", call.Type)
+	}
+	if len(call.Arguments) > 0 {
+		arguments := j.Array()
+		for _, argument := range call.Arguments {
+			arguments.Put(argument)
+		}
+		object.Put("arguments", arguments)
+	}
+	if call.RetryCount > 0 {
+		object.Put("retryCount", call.RetryCount)
+	}
+	if call.RetryWait > 0 {
+		object.Put("retryWait", call.RetryWait)
+	}
+	if len(call.SuccessReturnCodes) > 0 {
+		object.Put("successReturnCodes", call.SuccessReturnCodes)
+	}
+	return object
+}
+
+// Check the program calls of a route.
+func validateProgramCalls(route Route) error {
+	var problems requestProblems
+	calls := route.programCalls()
+	for _, section := range programCallSections {
+		call := calls[section]
+		if call == nil {
+			continue
+		}
+		pointer := func(name string) string {
+			return jsonPointer("transferSet", section, name)
+		}
+		if len(strings.TrimSpace(call.Name)) == 0 {
+			problems.add(pointer("name"), nil, "program name is missing")
+		}
+		if len(call.Type) > 0 && !programCallTypes[call.Type] {
+			problems.add(pointer("type"), call.Type, "must be executable, antScript or jcl")
+		}
+		if call.RetryCount < 0 {
+			problems.add(pointer("retryCount"), call.RetryCount, "must not be negative")
+		}
+		if call.RetryWait < 0 {
+			problems.add(pointer("retryWait"), call.RetryWait, "must be a number of seconds")
+		}
+		if len(call.SuccessReturnCodes) > 0 && !isReturnCodeExpression(call.SuccessReturnCodes) {
+			problems.add(pointer("successReturnCodes"), call.SuccessReturnCodes, "must be return code conditions such as 0, >2, <8 or !4 combined with | or &")
+		}
+	}
+	return problems.err()
+}
+
+// Check if an expression is a list of return code conditions combined with |
+// and &.
+func isReturnCodeExpression(expression string) bool {
+	for _, alternative := range strings.Split(expression, "|") {
+		for _, condition := range strings.Split(alternative, "&") {
+			if !returnCodeCondition.MatchString(strings.TrimSpace(condition)) {
+				return false
+			}
+		}
+	}
+	return true
+}
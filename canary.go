@@ -0,0 +1,125 @@
+/*
+© Copyright IBM Corporation 2022, 2022
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+/*
+* This file contains the "canary" command, a synthetic check for external
+* monitoring. A tiny file is created in -source-dir, the temporary directory
+* by default, and transferred between the agents of a route to a designated
+* canary directory, -destination-dir or the canaryDirectory of the route,
+* never to the destination of the route so that no real data is replaced.
+* The file is sent to the directory with a unique name and fails rather than
+* overwrite an existing file. The check passes when the transfer is
+* successful within -timeout and the file arrived in the directory, which must
+* be reachable from this host for the file to be verified and removed. The
+* source file is removed afterwards.
+*
+* A single line is printed and the exit code follows the convention of
+* Nagios compatible monitoring plugins: 0 OK, 1 WARNING when the canary file
+* cannot be verified and removed, 2 CRITICAL, 3 UNKNOWN.
+ */
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Exit codes of the canary command.
+const (
+	canaryOk       = 0
+	canaryWarning  = 1
+	canaryCritical = 2
+	canaryUnknown  = 3
+)
+
+// Size in bytes of the canary file.
+const canaryFileSize = 64
+
+// Command "canary" - transfer a tiny file and report the outcome for monitoring.
+func canaryCommand(args []string) int {
+	flags := newFlagSet("canary")
+	routeName := flags.String("route", "", "Name of the route to transfer")
+	sourceDir := flags.String("source-dir", os.TempDir(), "Directory in which the canary file is created")
+	destinationDir := flags.String("destination-dir", "", "Canary directory of the destination agent, by default the canaryDirectory of the route")
+	timeout := flags.Duration("timeout", 2*time.Minute, "Time allowed for the transfer to complete")
+	if !parseCommandLine(flags, args) || flags.NArg() != 0 {
+		printMessage("canaryUsage")
+		return canaryUnknown
+	}
+	route, err := findSubmissionRoute(*routeName, nil)
+	if err != nil {
+		printMessage("canaryUnknown", err)
+		return canaryUnknown
+	}
+	canaryDir := *destinationDir
+	if len(canaryDir) == 0 {
+		canaryDir = route.CanaryDirectory
+	}
+	if len(canaryDir) == 0 {
+		printMessage("canaryUnknown", message("canaryDirectoryRequired", route.Name))
+		return canaryUnknown
+	}
+
+	name := fmt.Sprintf("mft-canary-%d-%d.dat", os.Getpid(), time.Now().Unix())
+	sourceFile, err := createSyntheticFile(*sourceDir, name, canaryFileSize)
+	if err != nil {
+		printMessage("canaryUnknown", err)
+		return canaryUnknown
+	}
+	defer os.Remove(sourceFile)
+	route.Items = []TransferItem{{Source: sourceFile, SourceType: "file", Destination: canaryDir,
+		DestinationType: "directory", SourceDisposition: "leave", ActionIfExists: "error"}}
+	route.Schedule = nil
+
+	outcome := submitAndAwait(route, *timeout)
+	if outcome.Err != nil {
+		printMessage("canaryCritical", route.Name, outcome.Err)
+		return canaryCritical
+	}
+	if outcome.State != "successful" {
+		printMessage("canaryCritical", route.Name, message("canaryTransferState", outcome.TransferId, outcome.State))
+		return canaryCritical
+	}
+	canaryFile := filepath.Join(canaryDir, name)
+	if _, err := os.Stat(canaryDir); err != nil {
+		printMessage("canaryNotRemoved", route.Name, outcome.TransferId, canaryFile,
+			message("canaryDirectoryNotLocal", canaryDir), outcome.Latency.Seconds())
+		return canaryWarning
+	}
+	if err := verifyCanaryArrival(canaryFile); err != nil {
+		printMessage("canaryCritical", route.Name, err)
+		return canaryCritical
+	}
+	if err := os.Remove(canaryFile); err != nil {
+		printMessage("canaryNotRemoved", route.Name, outcome.TransferId, canaryFile, err, outcome.Latency.Seconds())
+		return canaryWarning
+	}
+	printMessage("canaryOk", route.Name, outcome.TransferId, outcome.Latency.Round(time.Millisecond), outcome.Latency.Seconds())
+	return canaryOk
+}
+
+/* Check that the canary file arrived at the destination.
+* fileName - Path of the canary file at the destination
+ */
+func verifyCanaryArrival(fileName string) error {
+	info, err := os.Stat(fileName)
+	if err != nil {
+		return fmt.Errorf("canary file did not arrive at %s", fileName)
+	}
+	if info.Size() != canaryFileSize {
+		return fmt.Errorf("canary file %s has %d bytes instead of %d", fileName, info.Size(), canaryFileSize)
+	}
+	return nil
+}
@@ -0,0 +1,140 @@
+/*
+© Copyright IBM Corporation 2022, 2022
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+/*
+* This file contains the "cancel" command, which cancels a transfer that has
+* not ended by posting the cancel action to its transfer resource:
+*
+*   POST /ibmmq/rest/v2/admin/mft/transfer/{id}   { "action": "cancel" }
+*
+* The user is asked to confirm unless -force is given. The MQ Web Server
+* accepts the action before the agents act on it, so with -wait the status of
+* the transfer is queried every -interval until it is cancelled, it ends in
+* another state or -timeout expires.
+ */
+package main
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	j "github.com/ricardolonga/jsongo"
+	"github.com/tidwall/gjson"
+)
+
+// Command "cancel" - cancel a transfer.
+func cancelCommand(args []string) int {
+	flags := newFlagSet("cancel")
+	force := flags.Bool("force", false, "Cancel the transfer without asking for confirmation")
+	wait := flags.Bool("wait", false, "Wait until the transfer is cancelled")
+	interval := flags.Duration("interval", 5*time.Second, "Interval between the status queries of -wait")
+	timeout := flags.Duration("timeout", 2*time.Minute, "Longest time to wait for the transfer to be cancelled")
+	routeName := flags.String("route", "", "Route whose MQ Web Server is queried")
+	if !parseCommandLine(flags, args) || flags.NArg() != 1 || *interval <= 0 {
+		printMessage("cancelUsage")
+		return 2
+	}
+	transferId, err := normalizeTransferId(flags.Arg(0))
+	if err != nil {
+		printError(err)
+		return 2
+	}
+	route, err := findRoute(*routeName)
+	if err != nil {
+		printError(err)
+		return 2
+	}
+	transferUrl := transferResourceUrl(route, transferId)
+	transfer, found := queryTransfer(transferUrl)
+	if !found {
+		return 1
+	}
+	state := transfer.Get("status.state").String()
+	if isTerminalTransferState(state) {
+		printMessage("cancelEnded", transferId, state)
+		return 1
+	}
+	if !*force && !askConfirmation(message("cancelConfirm", transferId, transfer.Get("sourceAgent.name").String(),
+		transfer.Get("destinationAgent.name").String(), state)) {
+		printMessage("cancelNotCancelled", transferId)
+		return 1
+	}
+
+	if err := postCancel(transferUrl); err != nil {
+		logMessage("cancelFailed", []any{logTransferId, transferId}, transferId, err)
+		return 1
+	}
+	logMessage("cancelRequested", []any{logTransferId, transferId}, transferId)
+	if !*wait {
+		return 0
+	}
+
+	deadline := time.Now().Add(*timeout)
+	for {
+		time.Sleep(*interval)
+		// A cached status would hide the change of state
+		webResponses.invalidate(transferUrl)
+		if transfer, found = queryTransfer(transferUrl); !found {
+			return 1
+		}
+		state = transfer.Get("status.state").String()
+		if strings.EqualFold(state, "cancelled") {
+			printMessage("cancelDone", transferId)
+			return 0
+		}
+		if isTerminalTransferState(state) {
+			printMessage("cancelEnded", transferId, state)
+			return 1
+		}
+		if time.Now().After(deadline) {
+			printMessage("cancelTimedOut", transferId, timeout.String(), state)
+			return 1
+		}
+	}
+}
+
+/* Post the cancel action of a transfer.
+* transferUrl - URL of the transfer resource
+ */
+func postCancel(transferUrl string) error {
+	response, body, err := callMQWeb("POST", transferUrl, j.Object().Put("action", "cancel").String())
+	if err != nil {
+		return err
+	}
+	if response.StatusCode != http.StatusAccepted && response.StatusCode != http.StatusOK {
+		return mqWebError(response, body)
+	}
+	return nil
+}
+
+/* Query a transfer, printing a message if it cannot be found.
+* transferUrl - URL of the transfer resource
+ */
+func queryTransfer(transferUrl string) (gjson.Result, bool) {
+	response, body, err := callMQWeb("GET", transferUrl+"?attributes=*", "")
+	if err != nil {
+		logMessage("statusQueryFailed", []any{logUrl, transferUrl}, transferUrl, err)
+		return gjson.Result{}, false
+	}
+	if response.StatusCode != http.StatusOK {
+		logMessage("statusQueryFailed", []any{logUrl, transferUrl}, transferUrl, mqWebError(response, body))
+		return gjson.Result{}, false
+	}
+	transfer := gjson.Get(body, "transfer.0")
+	if !transfer.Exists() {
+		printMessage("transferIdNotFound", transferUrl[strings.LastIndex(transferUrl, "/")+1:])
+		return transfer, false
+	}
+	return transfer, true
+}
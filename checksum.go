@@ -0,0 +1,72 @@
+/*
+© Copyright IBM Corporation 2022, 2022
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+/*
+* This file contains the checksum options of transfers. The agents compute a
+* checksum of each item with the method given by the "checksumMethod" of the
+* route or the -checksum flag of the submit command, MD5 or none to skip the
+* check, and compare the source and destination checksums. The agent default
+* applies when no method is set.
+*
+* The checksums reported for the items of a transfer are printed with its
+* status for integrity auditing, and a difference between the source and
+* destination checksums of an item is reported.
+ */
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/tidwall/gjson"
+)
+
+// Checksum methods supported by the agents, by lower case name.
+var checksumMethods = map[string]string{
+	"md5":  "MD5",
+	"none": "none",
+}
+
+/* Return the name of a checksum method as expected by the MQ Web Server.
+* method - Checksum method in any case, blank for the agent default
+ */
+func normalizeChecksumMethod(method string) (string, error) {
+	if len(method) == 0 {
+		return "", nil
+	}
+	if name, found := checksumMethods[strings.ToLower(method)]; found {
+		return name, nil
+	}
+	names := []string{}
+	for _, name := range checksumMethods {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return "", fmt.Errorf("checksum method %s is not supported, use one of %s", method, strings.Join(names, ", "))
+}
+
+/* Print the checksums reported for the items of a transfer.
+* transfer - Transfer as returned by the status query
+ */
+func printItemChecksums(transfer gjson.Result) {
+	for _, item := range transferItemResults(transfer) {
+		if len(item.Checksum) == 0 && len(item.DestinationChecksum) == 0 {
+			continue
+		}
+		printMessage("itemChecksums", item.Source, item.Checksum, item.DestinationChecksum)
+		if len(item.Checksum) > 0 && len(item.DestinationChecksum) > 0 && !strings.EqualFold(item.Checksum, item.DestinationChecksum) {
+			printMessage("itemChecksumMismatch", item.Source, item.Destination)
+		}
+	}
+}
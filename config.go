@@ -0,0 +1,675 @@
+/*
+© Copyright IBM Corporation 2022, 2022
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+/*
+* This file contains the configuration of the program. Configuration is read
+* from an optional JSON file given with the -config flag. Any attribute not
+* present in the file keeps the default taken from the constants declared in
+* submitrequest.go.
+*
+* A sample configuration file:
+*
+* {
+*   "transferUrl": "https://mqweb.example.com:9443/ibmmq/rest/v2/admin/mft/transfer",
+*   "userId": "mqmftadminusr",
+*   "password": "mqmftpassw0rd",
+*   "routes": [
+*     {
+*       "name": "payroll",
+*       "sourceAgent": "SRC", "sourceQM": "SRCQM",
+*       "destinationAgent": "DEST", "destinationQM": "DESTQM",
+*       "sourceItem": "/usr/srcdir/payroll.csv", "sourceItemType": "file",
+*       "destinationItem": "/usr/destdir", "destinationItemType": "directory",
+*       "businessDaysOnly": true,
+*       "metadata": { "department": "finance" }
+*     },
+*     {
+*       "name": "reports",
+*       "sourceAgent": "SRC", "sourceQM": "SRCQM",
+*       "destinationAgent": "DEST", "destinationQM": "DESTQM",
+*       "sourceItemType": "file", "destinationItemType": "file",
+*       "items": [
+*         { "source": "/usr/srcdir/daily.pdf", "destination": "/usr/destdir/daily.pdf" },
+*         { "source": "/usr/srcdir/archive", "sourceType": "directory",
+*           "destination": "/usr/destdir/archive", "destinationType": "directory" }
+*       ]
+*     }
+*   ],
+*   "holidayCalendars": ["holidays.ics"],
+*   "maintenanceWindows": [
+*     { "agent": "DEST", "days": ["Sat"], "start": "22:00", "end": "02:00" }
+*   ]
+* }
+ */
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Name of the route built from the constants when no route is configured.
+const defaultRouteName = "default"
+
+// Configuration of the program.
+type Config struct {
+	TransferUrl        string              `json:"transferUrl"`
+	UserId             string              `json:"userId"`
+	Password           string              `json:"password"`
+	StateDirectory     string              `json:"stateDirectory"`
+	Locale             string              `json:"locale"`
+	MessageDirectory   string              `json:"messageDirectory"`
+	TLS                TLSPolicy           `json:"tls"`
+	Timeouts           HTTPTimeouts        `json:"timeouts"`
+	ForceHTTP1         bool                `json:"forceHttp1"`
+	ConnectionPool     ConnectionPool      `json:"connectionPool"`
+	Retry              RetryPolicy         `json:"retry"`
+	Polling            PollPolicy          `json:"polling"`
+	ConfirmThresholds  ConfirmThresholds   `json:"confirmThresholds"`
+	Proxy              ProxySettings       `json:"proxy"`
+	Routes             []Route             `json:"routes"`
+	MaintenanceWindows []MaintenanceWindow `json:"maintenanceWindows"`
+	HolidayCalendars   []string            `json:"holidayCalendars"`
+	AnomalyThresholds  *AnomalyThresholds  `json:"anomalyThresholds"`
+	AlertCommand       []string            `json:"alertCommand"`
+	Originator         OriginatorSettings  `json:"originator"`
+	IntegrityAlgorithm string              `json:"integrityAlgorithm"`
+	Retention          RetentionPolicy     `json:"retention"`
+	AgentCacheTtl      Duration            `json:"agentCacheTtl"`
+	CheckAgents        bool                `json:"checkAgents"`
+	AgentHealth        AgentHealthPolicy   `json:"agentHealth"`
+	CoordinationQM     string              `json:"coordinationQM"`
+	CommandQM          string              `json:"commandQM"`
+	ResponseCacheTtl   Duration            `json:"responseCacheTtl"`
+
+	MaxInFlightPerAgent int            `json:"maxInFlightPerAgent"`
+	AgentInFlightLimits map[string]int `json:"agentInFlightLimits"`
+	Gateways            []string       `json:"gateways"`
+
+	Logging  LoggingSettings `json:"logging"`
+	AuditLog string          `json:"auditLog"`
+	Syslog   SyslogSettings  `json:"syslog"`
+	Webhooks []Webhook       `json:"webhooks"`
+
+	RequestFieldMappings map[string]string `json:"requestFieldMappings"`
+}
+
+// A route is a named source to destination transfer definition.
+type Route struct {
+	Name                string `json:"name"`
+	SourceAgent         string `json:"sourceAgent"`
+	SourceQM            string `json:"sourceQM"`
+	DestinationAgent    string `json:"destinationAgent"`
+	DestinationQM       string `json:"destinationQM"`
+	SourceItem          string `json:"sourceItem"`
+	SourceItemType      string `json:"sourceItemType"`
+	DestinationItem     string `json:"destinationItem"`
+	DestinationItemType string `json:"destinationItemType"`
+	BusinessDaysOnly    bool   `json:"businessDaysOnly"`
+	CompareBaseline     bool   `json:"compareBaseline"`
+	HoldOnAnomaly       bool   `json:"holdOnAnomaly"`
+	NotificationEmail   string `json:"notificationEmail"`
+	TransferUrl         string `json:"transferUrl"`
+	CanaryDirectory     string `json:"canaryDirectory"`
+	Manifest            string `json:"manifest"`
+	Mode                string `json:"mode"`
+	SourceEncoding      string `json:"sourceEncoding"`
+	DestinationEncoding string `json:"destinationEncoding"`
+	DestinationEOL      string `json:"destinationEOL"`
+	ChecksumMethod      string `json:"checksumMethod"`
+	SourceDisposition   string `json:"sourceDisposition"`
+	ActionIfExists      string `json:"actionIfExists"`
+	Recursive           *bool  `json:"recursive"`
+	Priority            *int   `json:"priority"`
+	JobName             string `json:"jobName"`
+
+	Exclude           []string           `json:"exclude"`
+	AnomalyThresholds *AnomalyThresholds `json:"anomalyThresholds"`
+	Schedule          *TransferSchedule  `json:"schedule"`
+	RecoveryTimeout   *int               `json:"recoveryTimeout"`
+	Reply             *TransferReply     `json:"reply"`
+
+	PreSourceCall       *ProgramCall `json:"preSourceCall"`
+	PostSourceCall      *ProgramCall `json:"postSourceCall"`
+	PreDestinationCall  *ProgramCall `json:"preDestinationCall"`
+	PostDestinationCall *ProgramCall `json:"postDestinationCall"`
+
+	SourceBridge      *BridgeEndpoint `json:"sourceBridge"`
+	DestinationBridge *BridgeEndpoint `json:"destinationBridge"`
+
+	SourceConnectDirect      *ConnectDirectEndpoint `json:"sourceConnectDirect"`
+	DestinationConnectDirect *ConnectDirectEndpoint `json:"destinationConnectDirect"`
+
+	Metadata map[string]string `json:"metadata"`
+	Items    []TransferItem    `json:"items"`
+}
+
+// A source and destination pair of a transfer. Types left blank are taken
+// from sourceItemType and destinationItemType of the route, or are file and
+// directory for a source with wildcards. Mode is text or binary, the default
+// of the agent when blank. A text transfer converts the source encoding to the
+// destination encoding, code page names such as UTF-8 or IBM-1047, and writes
+// the end of line given by destinationEOL, LF or CRLF. The mode, encodings and
+// end of line left blank are taken from the route. Checksum is the expected
+// checksum of the source, see integrity.go. SourceDisposition is leave to copy
+// the source or delete to move it, and ActionIfExists is error to fail or
+// overwrite to replace a destination that exists. Both are taken from the
+// route when blank. For a directory source, Recursive includes or skips its
+// subdirectories and Exclude lists glob patterns of files to skip, matched
+// against the file name and the path relative to the source. The route
+// settings apply when they are not set. MessagePersistence and SetMqProps
+// apply to a queue destination, and the message options to a queue source,
+// see queue.go. DatasetAttributes apply to a z/OS dataset destination, see
+// dataset.go.
+type TransferItem struct {
+	Source              string   `json:"source"`
+	SourceType          string   `json:"sourceType"`
+	Destination         string   `json:"destination"`
+	DestinationType     string   `json:"destinationType"`
+	Mode                string   `json:"mode"`
+	SourceEncoding      string   `json:"sourceEncoding"`
+	DestinationEncoding string   `json:"destinationEncoding"`
+	DestinationEOL      string   `json:"destinationEOL"`
+	Checksum            string   `json:"checksum"`
+	SourceDisposition   string   `json:"sourceDisposition"`
+	ActionIfExists      string   `json:"actionIfExists"`
+	Recursive           *bool    `json:"recursive"`
+	Exclude             []string `json:"exclude"`
+	MessagePersistence  string   `json:"messagePersistence"`
+	SetMqProps          bool     `json:"setMqProps"`
+
+	MessageDelimiter         string `json:"messageDelimiter"`
+	MessageDelimiterType     string `json:"messageDelimiterType"`
+	MessageDelimiterPosition string `json:"messageDelimiterPosition"`
+	GroupMessages            bool   `json:"groupMessages"`
+	WaitTime                 *int   `json:"waitTime"`
+
+	DatasetAttributes *DatasetAttributes `json:"datasetAttributes"`
+}
+
+// Configuration in use. Populated by loadConfiguration.
+var config = defaultConfiguration()
+
+// Path of the configuration file, set by the -config flag.
+var configFile string
+
+// Skip verification of the server certificate, set by -insecure-skip-verify.
+var insecureSkipVerify bool
+
+// Use HTTP/1.1 even when the server supports HTTP/2, set by -http1.1.
+var forceHTTP1 bool
+
+// Returns the configuration built from the constants of this program.
+func defaultConfiguration() Config {
+	return Config{
+		TransferUrl:       mqRestXferUrl,
+		UserId:            mqWebUserId,
+		Password:          mqWebPassword,
+		StateDirectory:    "mftstate",
+		Timeouts:          defaultHTTPTimeouts(),
+		ConnectionPool:    defaultConnectionPool(),
+		Retry:             defaultRetryPolicy(),
+		Polling:           defaultPollPolicy(),
+		ConfirmThresholds: defaultConfirmThresholds(),
+		Retention:         defaultRetentionPolicy(),
+		AgentCacheTtl:     Duration(defaultAgentCacheTtl),
+		ResponseCacheTtl:  Duration(defaultResponseCacheTtl),
+	}
+}
+
+// Returns the route built from the constants of this program.
+func defaultRoute() Route {
+	return Route{
+		Name:                defaultRouteName,
+		SourceAgent:         sourceAgentName,
+		SourceQM:            sourceQMName,
+		DestinationAgent:    destinationAgentName,
+		DestinationQM:       destinationQMName,
+		SourceItem:          sourceItemName,
+		SourceItemType:      sourceItemType,
+		DestinationItem:     destinationItemName,
+		DestinationItemType: destinationItemType,
+	}
+}
+
+// A duration in the configuration, written as "90s", "5m" or a number of seconds.
+type Duration time.Duration
+
+func (duration *Duration) UnmarshalJSON(data []byte) error {
+	var value interface{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+	switch value := value.(type) {
+	case float64:
+		*duration = Duration(value * float64(time.Second))
+	case string:
+		parsed, err := time.ParseDuration(value)
+		if err != nil {
+			return err
+		}
+		*duration = Duration(parsed)
+	default:
+		return fmt.Errorf("invalid duration %s", data)
+	}
+	return nil
+}
+
+func (duration Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(duration).String())
+}
+
+/* Create a flag set for a command with the flags common to all commands.
+* name - Name of the command
+ */
+func newFlagSet(name string) *flag.FlagSet {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	flags.StringVar(&configFile, "config", "", "Path of the JSON configuration file")
+	flags.BoolVar(&insecureSkipVerify, "insecure-skip-verify", false, "Do not verify the server certificate. For development and test only.")
+	flags.BoolVar(&forceHTTP1, "http1.1", false, "Use HTTP/1.1 instead of HTTP/2")
+	flags.StringVar(&proxyFlag, "proxy", "", "URL of the HTTP(S) proxy to the MQ Web Server")
+	flags.StringVar(&socks5Flag, "socks5", "", "HOST:PORT of a SOCKS5 proxy to the MQ Web Server")
+	flags.StringVar(&proxyUserFlag, "proxy-user", "", "User and optional password of the proxy, as USER[:PASSWORD]")
+	flags.StringVar(&logLevelFlag, "log-level", "", "Level of the messages logged, debug, info, warn or error")
+	flags.StringVar(&logFormatFlag, "log-format", "", "Format of the log, plain, text or json")
+	flags.BoolVar(&quietFlag, "q", false, "Print only the ID and final state of the transfers, and the errors")
+	flags.BoolVar(&verboseFlag, "v", false, "Also print the requests to the MQ Web Server and their responses")
+	flags.BoolVar(&veryVerboseFlag, "vv", false, "Also print the headers and bodies of the requests and responses")
+	flags.StringVar(&traceHttpFlag, "trace-http", "", "Write the requests and responses to a trace file, - for standard error")
+	return flags
+}
+
+/* Parse the command line of a command and load the configuration.
+* flags - Flag set created by newFlagSet
+* args  - Command line arguments following the command name
+ */
+func parseCommandLine(flags *flag.FlagSet, args []string) bool {
+	if err := flags.Parse(args); err != nil {
+		return false
+	}
+	if err := loadConfiguration(configFile); err != nil {
+		printMessage("configReadFailed", configFile, err)
+		return false
+	}
+	if err := applyProxyFlags(); err != nil {
+		printError(err)
+		return false
+	}
+	if len(config.Locale) > 0 || len(config.MessageDirectory) > 0 {
+		loadMessages(config.Locale, config.MessageDirectory)
+	}
+	if err := configureLogging(config.Logging); err != nil {
+		printError(err)
+		return false
+	}
+	return true
+}
+
+/* Load configuration from a JSON file. Attributes not present in the file
+* keep their default value.
+* fileName - Path of the configuration file. If blank, defaults are used.
+ */
+func loadConfiguration(fileName string) error {
+	config = defaultConfiguration()
+	if len(fileName) == 0 {
+		return nil
+	}
+	content, err := os.ReadFile(fileName)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(content, &config)
+}
+
+/* Find a route by name. The default route is returned for a blank name
+* when no routes are configured.
+* name - Name of the route
+ */
+func findRoute(name string) (Route, error) {
+	if len(name) == 0 {
+		switch len(config.Routes) {
+		case 0:
+			return defaultRoute(), nil
+		case 1:
+			return config.Routes[0], nil
+		}
+		return Route{}, fmt.Errorf("%d routes are configured, use -route to select one", len(config.Routes))
+	}
+	for _, route := range config.Routes {
+		if route.Name == name {
+			return route, nil
+		}
+	}
+	if name == defaultRouteName {
+		return defaultRoute(), nil
+	}
+	return Route{}, fmt.Errorf("route %s is not defined in the configuration", name)
+}
+
+/* Return the items of the transfer of a route: the "items" of the route, or
+* its single source and destination item. The paths of items on a protocol
+* bridge are given their server, see bridge.go, and those on a Connect:Direct
+* bridge their node, see connectdirect.go.
+ */
+func (route Route) transferItems() []TransferItem {
+	if len(route.Items) == 0 {
+		item := route.withItemDefaults(TransferItem{Source: route.SourceItem, SourceType: route.SourceItemType,
+			Destination: route.DestinationItem, DestinationType: route.DestinationItemType})
+		return []TransferItem{route.withConnectDirectPaths(route.withBridgePaths(item))}
+	}
+	items := []TransferItem{}
+	for _, item := range route.Items {
+		item = route.withItemDefaults(item)
+		sourceType, destinationType := route.SourceItemType, route.DestinationItemType
+		if hasWildcard(item.Source) {
+			// The files matched by a pattern go to a directory
+			sourceType, destinationType = "file", "directory"
+		}
+		if isDatasetPath(item.Source) {
+			sourceType = "dataset"
+		}
+		if isDatasetPath(item.Destination) {
+			destinationType = "dataset"
+		}
+		if len(item.SourceType) == 0 {
+			item.SourceType = sourceType
+		}
+		if len(item.DestinationType) == 0 {
+			item.DestinationType = destinationType
+		}
+		items = append(items, route.withConnectDirectPaths(route.withBridgePaths(item)))
+	}
+	return items
+}
+
+// Return an item with the mode, encodings, end of line, source disposition,
+// action if the destination exists, recursion and exclusions it leaves unset
+// taken from the route.
+func (route Route) withItemDefaults(item TransferItem) TransferItem {
+	if len(item.Mode) == 0 {
+		item.Mode = route.Mode
+	}
+	if len(item.SourceEncoding) == 0 {
+		item.SourceEncoding = route.SourceEncoding
+	}
+	if len(item.DestinationEncoding) == 0 {
+		item.DestinationEncoding = route.DestinationEncoding
+	}
+	if len(item.DestinationEOL) == 0 {
+		item.DestinationEOL = route.DestinationEOL
+	}
+	if len(item.SourceDisposition) == 0 {
+		item.SourceDisposition = route.SourceDisposition
+	}
+	if len(item.ActionIfExists) == 0 {
+		item.ActionIfExists = route.ActionIfExists
+	}
+	if item.Recursive == nil {
+		item.Recursive = route.Recursive
+	}
+	if item.Exclude == nil {
+		item.Exclude = route.Exclude
+	}
+	return item
+}
+
+// Types of the source and destination of a transfer item.
+var (
+	sourceItemTypes      = map[string]bool{"file": true, "directory": true, "queue": true, "dataset": true, "pds": true}
+	destinationItemTypes = map[string]bool{"file": true, "directory": true, "queue": true, "dataset": true, "pds": true, "fileSpace": true}
+)
+
+/* Check the items of a route. Items must be a file, directory, queue or z/OS
+* dataset with a name, and a destination may also be a file space. A source
+* with wildcards must be of type file and its matches are written to a
+* directory. Encodings and end of line require text mode. All problems found
+* are reported with the JSON pointer of the attribute at fault.
+* route - Route of the transfer
+ */
+func validateTransferItems(route Route) error {
+	var problems requestProblems
+	for index, item := range route.transferItems() {
+		pointer := func(tokens ...interface{}) string {
+			return jsonPointer(append([]interface{}{"transferSet", "item", index}, tokens...)...)
+		}
+		if len(item.Source) == 0 {
+			problems.add(pointer("source", "name"), nil, "source is missing")
+		}
+		if !sourceItemTypes[item.SourceType] {
+			problems.add(pointer("source", "type"), item.SourceType, "must be file, directory, queue, dataset or pds")
+		}
+		if len(item.Destination) == 0 {
+			problems.add(pointer("destination", "name"), nil, "destination is missing")
+		}
+		if !destinationItemTypes[item.DestinationType] {
+			problems.add(pointer("destination", "type"), item.DestinationType, "must be file, directory, queue, dataset, pds or fileSpace")
+		}
+		validateQueueOptions(item, pointer, &problems)
+		validateQueueSourceOptions(item, pointer, &problems)
+		validateDatasetOptions(item, pointer, &problems)
+		validateFileSpaceOptions(item, pointer, &problems)
+		validateBridgeOptions(route, item, pointer, &problems)
+		validateConnectDirectOptions(route, item, pointer, &problems)
+		if item.Mode != "" && item.Mode != "text" && item.Mode != "binary" {
+			problems.add(pointer("mode"), item.Mode, "must be text or binary")
+		}
+		if item.DestinationEOL != "" && item.DestinationEOL != "LF" && item.DestinationEOL != "CRLF" {
+			problems.add(pointer("destination", "endOfLine"), item.DestinationEOL, "must be LF or CRLF")
+		}
+		if item.SourceDisposition != "" && item.SourceDisposition != "leave" && item.SourceDisposition != "delete" {
+			problems.add(pointer("source", "disposition"), item.SourceDisposition, "must be leave or delete")
+		}
+		if item.ActionIfExists != "" && item.ActionIfExists != "error" && item.ActionIfExists != "overwrite" {
+			problems.add(pointer("destination", "actionIfExists"), item.ActionIfExists, "must be error or overwrite")
+		}
+		if item.SourceType != "directory" {
+			if item.Recursive != nil {
+				problems.add(pointer("source", "recursive"), *item.Recursive, "only applies to a directory source")
+			}
+			if len(item.Exclude) > 0 {
+				problems.add(pointer("source", "exclude"), nil, "only applies to a directory source")
+			}
+		}
+		for index, pattern := range item.Exclude {
+			if _, err := filepath.Match(pattern, ""); err != nil || len(pattern) == 0 {
+				problems.add(pointer("source", "exclude", index), pattern, "is not a valid glob pattern")
+			}
+		}
+		if item.Mode != "text" {
+			if item.SourceEncoding != "" {
+				problems.add(pointer("source", "encoding"), item.SourceEncoding, "is only converted in text mode")
+			}
+			if item.DestinationEncoding != "" {
+				problems.add(pointer("destination", "encoding"), item.DestinationEncoding, "is only converted in text mode")
+			}
+			if item.DestinationEOL != "" {
+				problems.add(pointer("destination", "endOfLine"), item.DestinationEOL, "is only converted in text mode")
+			}
+		}
+		if !hasWildcard(item.Source) {
+			continue
+		}
+		if hasWildcard(filepath.Dir(item.Source)) {
+			problems.add(pointer("source", "name"), item.Source, "has wildcards outside the file name")
+		}
+		if item.SourceType != "file" {
+			problems.add(pointer("source", "type"), item.SourceType, "must be file for a source with wildcards")
+		}
+		if item.DestinationType == "file" {
+			problems.add(pointer("destination", "type"), item.DestinationType, "must be directory for a source with wildcards")
+		}
+	}
+	return problems.err()
+}
+
+// Check if a source item name contains the wildcards * or ?.
+func hasWildcard(name string) bool {
+	return strings.ContainsAny(name, "*?")
+}
+
+/* Return the paths on this host matching a source item, which may contain
+* wildcards. Returns nothing if the source is not accessible from this host.
+* source - Name of the source item
+ */
+func localSourcePaths(source string) []string {
+	if hasWildcard(source) {
+		matches, _ := filepath.Glob(source)
+		return matches
+	}
+	if _, err := os.Stat(source); err != nil {
+		return nil
+	}
+	return []string{source}
+}
+
+// Lowest and highest priority of a transfer.
+const (
+	minTransferPriority = 0
+	maxTransferPriority = 9
+)
+
+// Check that a transfer priority, if set, is between 0 and 9.
+func validatePriority(priority *int) error {
+	if priority != nil && (*priority < minTransferPriority || *priority > maxTransferPriority) {
+		var problems requestProblems
+		problems.add(jsonPointer("transferSet", "priority"), *priority, "is not between %d and %d", minTransferPriority, maxTransferPriority)
+		return problems.err()
+	}
+	return nil
+}
+
+/* Walk the files of a source accessible from this host, skipping the files
+* excluded by the item and, unless it is recursive, its subdirectories. A
+* directory source is walked recursively when recursion is not set.
+* item   - Item of the transfer
+* source - Path of the source, or of a file matching a source with wildcards
+* walk   - Function called for each file, or with the error of a path
+ */
+func walkSourceFiles(item TransferItem, source string, walk filepath.WalkFunc) error {
+	return filepath.Walk(source, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return walk(path, info, err)
+		}
+		if info.IsDir() {
+			if path != source && item.Recursive != nil && !*item.Recursive {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !info.Mode().IsRegular() || item.excludes(source, path) {
+			return nil
+		}
+		return walk(path, info, nil)
+	})
+}
+
+/* Check if a file of a directory source matches an exclude pattern.
+* source - Path of the source
+* path   - Path of the file
+ */
+func (item TransferItem) excludes(source string, path string) bool {
+	relative, err := filepath.Rel(source, path)
+	if err != nil {
+		relative = path
+	}
+	for _, pattern := range item.Exclude {
+		if matched, _ := filepath.Match(pattern, filepath.Base(path)); matched {
+			return true
+		}
+		if matched, _ := filepath.Match(pattern, filepath.ToSlash(relative)); matched {
+			return true
+		}
+	}
+	return false
+}
+
+/* Parse a transfer item given on the command line as SOURCE=DESTINATION.
+* value - Value of the -item flag
+ */
+func parseTransferItem(value string) (TransferItem, error) {
+	source, destination, found := strings.Cut(value, "=")
+	if !found || len(source) == 0 || len(destination) == 0 {
+		return TransferItem{}, fmt.Errorf("item %q must be given as SOURCE=DESTINATION", value)
+	}
+	return TransferItem{Source: source, Destination: destination}, nil
+}
+
+/* Return the URL of the transfer resource to which requests for the route are
+* submitted. The MQ Web Server routes the request through the coordination and
+* command queue managers set in its configuration, so a route with its own
+* command path names the MQ Web Server configured for that path.
+ */
+func (route Route) transferUrl() string {
+	if len(route.TransferUrl) > 0 {
+		return route.TransferUrl
+	}
+	return config.TransferUrl
+}
+
+/* Find the route of a transfer to submit, complete it with the items of its
+* manifest and the originator metadata, and check that a transfer request can
+* be built for it.
+* name  - Name of the route
+* items - Items replacing those of the route, may be empty
+ */
+func findSubmissionRoute(name string, items []TransferItem) (Route, error) {
+	route, err := findRoute(name)
+	if err != nil {
+		return route, err
+	}
+	return route.forSubmission(items)
+}
+
+/* Prepare a route for submission: take its items from the given items or from
+* its manifest, add the metadata of the run and check its transfer.
+* items - Items replacing those of the route, if any
+ */
+func (route Route) forSubmission(items []TransferItem) (Route, error) {
+	var err error
+	if len(items) == 0 && len(route.Manifest) > 0 {
+		if items, err = readManifest(route.Manifest); err != nil {
+			return route, fmt.Errorf("route %s: %v", route.Name, err)
+		}
+	}
+	if len(items) > 0 {
+		route.Items = items
+	}
+	route.Metadata = withRunIdMetadata(withOriginatorMetadata(route.withConnectDirectMetadata(route.Metadata)))
+	if err := validateMetadata(route.Metadata); err != nil {
+		return route, fmt.Errorf("route %s:\n%v", route.Name, err)
+	}
+	if err := validateTransferItems(route); err != nil {
+		return route, fmt.Errorf("route %s:\n%v", route.Name, err)
+	}
+	if route.ChecksumMethod, err = normalizeChecksumMethod(route.ChecksumMethod); err != nil {
+		return route, fmt.Errorf("route %s: %v", route.Name, err)
+	}
+	if err := validatePriority(route.Priority); err != nil {
+		return route, fmt.Errorf("route %s:\n%v", route.Name, err)
+	}
+	if err := validateSchedule(route.Schedule, time.Now()); err != nil {
+		return route, fmt.Errorf("route %s:\n%v", route.Name, err)
+	}
+	if err := validateProgramCalls(route); err != nil {
+		return route, fmt.Errorf("route %s:\n%v", route.Name, err)
+	}
+	if err := validateTransferSetOptions(route); err != nil {
+		return route, fmt.Errorf("route %s:\n%v", route.Name, err)
+	}
+	return route, nil
+}
@@ -0,0 +1,161 @@
+/*
+© Copyright IBM Corporation 2022, 2022
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+/*
+* This file contains the transfers through a Connect:Direct bridge agent,
+* which sends and receives the files of Connect:Direct nodes. The source or
+* destination agent of a route is marked as a Connect:Direct bridge with
+* "sourceConnectDirect" or "destinationConnectDirect":
+*
+*   "destinationConnectDirect": { "node": "CDNODE1",
+*                                 "processParameters": { "CLASS": "2" } }
+*
+* The items of a Connect:Direct bridge are node-qualified paths, node:path.
+* The "node" of the bridge is added to the items of the route that have no
+* node. The sources of a bridge are not on this host, see bridge.go.
+*
+* The MQ REST API has no attributes for Connect:Direct processes. The process
+* parameters are sent as user defined metadata, replacing any metadata of the
+* route with the same key, for the processes the bridge selects in its
+* ConnectDirectProcessDefinitions.xml file to use.
+ */
+package main
+
+import (
+	"strings"
+)
+
+// Maximum length of the name of a Connect:Direct node.
+const maxConnectDirectNodeLength = 16
+
+// Connect:Direct bridge agent at one end of a route.
+type ConnectDirectEndpoint struct {
+	Node              string            `json:"node"`
+	ProcessParameters map[string]string `json:"processParameters"`
+}
+
+/* Return the node-qualified path of an item, with the node of the bridge
+* unless the item has one.
+* name - Name of the item
+ */
+func (bridge *ConnectDirectEndpoint) path(name string) string {
+	if bridge == nil || len(bridge.Node) == 0 || len(name) == 0 || len(connectDirectNode(name)) > 0 {
+		return name
+	}
+	return bridge.Node + ":" + name
+}
+
+// Return the node of a node:path item name, blank if it has none.
+func connectDirectNode(name string) string {
+	if node, _, found := strings.Cut(name, ":"); found && isConnectDirectNodeName(node) {
+		return node
+	}
+	return ""
+}
+
+// Check if a name is a valid Connect:Direct node name: 1 to 16 letters,
+// digits, national characters, '.', '_' or '-', starting with a letter or
+// national character.
+func isConnectDirectNodeName(name string) bool {
+	if len(name) == 0 || len(name) > maxConnectDirectNodeLength {
+		return false
+	}
+	for index, char := range strings.ToUpper(name) {
+		switch {
+		case char >= 'A' && char <= 'Z', char == '@', char == '#', char == '$':
+		case index > 0 && (char >= '0' && char <= '9' || char == '.' || char == '_' || char == '-'):
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// Return an item with the node-qualified paths of the Connect:Direct bridges
+// of the route.
+func (route Route) withConnectDirectPaths(item TransferItem) TransferItem {
+	if item.SourceType == "file" || item.SourceType == "directory" {
+		item.Source = route.SourceConnectDirect.path(item.Source)
+	}
+	if item.DestinationType == "file" || item.DestinationType == "directory" {
+		item.Destination = route.DestinationConnectDirect.path(item.Destination)
+	}
+	return item
+}
+
+/* Return the metadata of a route with the process parameters of its
+* Connect:Direct bridges added.
+* metadata - Metadata of the route, not modified
+ */
+func (route Route) withConnectDirectMetadata(metadata map[string]string) map[string]string {
+	if route.SourceConnectDirect == nil && route.DestinationConnectDirect == nil {
+		return metadata
+	}
+	merged := map[string]string{}
+	for key, value := range metadata {
+		merged[key] = value
+	}
+	for _, bridge := range []*ConnectDirectEndpoint{route.SourceConnectDirect, route.DestinationConnectDirect} {
+		if bridge != nil {
+			for key, value := range bridge.ProcessParameters {
+				merged[key] = value
+			}
+		}
+	}
+	return merged
+}
+
+/* Check the items of a route with a Connect:Direct bridge agent. Files and
+* directories are the only items of a node, and an end of a route cannot be
+* both a protocol bridge and a Connect:Direct bridge.
+* route    - Route of the transfer
+* item     - Item of the transfer
+* pointer  - Returns the JSON pointer of an attribute of the item
+* problems - Problems found
+ */
+func validateConnectDirectOptions(route Route, item TransferItem, pointer func(tokens ...interface{}) string, problems *requestProblems) {
+	if route.SourceConnectDirect != nil {
+		validateConnectDirectItem(route.SourceConnectDirect, item.Source, item.SourceType, pointer("source"), problems)
+		if route.SourceBridge != nil {
+			problems.add(pointer("source"), nil, "the source agent cannot be both a protocol bridge and a Connect:Direct bridge")
+		}
+	}
+	if route.DestinationConnectDirect != nil {
+		validateConnectDirectItem(route.DestinationConnectDirect, item.Destination, item.DestinationType, pointer("destination"), problems)
+		if route.DestinationBridge != nil {
+			problems.add(pointer("destination"), nil, "the destination agent cannot be both a protocol bridge and a Connect:Direct bridge")
+		}
+	}
+}
+
+/* Check an item of a Connect:Direct bridge.
+* bridge   - Bridge of the item
+* name     - Name of the item
+* itemType - Type of the item
+* pointer  - JSON pointer of the item
+* problems - Problems found
+ */
+func validateConnectDirectItem(bridge *ConnectDirectEndpoint, name string, itemType string, pointer string, problems *requestProblems) {
+	if len(bridge.Node) > 0 && !isConnectDirectNodeName(bridge.Node) {
+		problems.add(pointer+"/name", bridge.Node, "is not a Connect:Direct node name of 1 to %d characters", maxConnectDirectNodeLength)
+	}
+	if itemType != "file" && itemType != "directory" {
+		problems.add(pointer+"/type", itemType, "must be file or directory for a Connect:Direct bridge agent")
+	}
+	node := connectDirectNode(name)
+	if len(node) == 0 {
+		problems.add(pointer+"/name", name, "must be a node-qualified path, node:path")
+	} else if len(name) == len(node)+1 {
+		problems.add(pointer+"/name", name, "has no path on node %s", node)
+	}
+}
@@ -0,0 +1,266 @@
+/*
+© Copyright IBM Corporation 2022, 2022
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+/*
+* This file contains the support for retrieving the MQ Web Server password
+* from a credential provider instead of the configuration file. The password
+* attribute of the configuration selects the provider by URI scheme:
+*
+*   "password": "keyring:"                - OS keychain, default service name
+*   "password": "keyring://SERVICE"       - OS keychain, given service name
+*   "password": "awssm://SECRET#KEY"      - AWS Secrets Manager
+*   "password": "azkv://VAULT/SECRET"     - Azure Key Vault
+*   "password": "gcpsm://PROJECT/SECRET"  - GCP Secret Manager
+*   "password": "ibmsm://INSTANCE.REGION/SECRET_ID" - IBM Cloud Secrets Manager
+*   "password": "mftcred:///FILE"         - Encrypted credentials file
+*
+* Any other value is used as the password itself. The cloud providers are
+* described in secrets.go, the encrypted credentials file in credfile.go.
+*
+* The keychain is the macOS Keychain, Windows Credential Manager or the
+* Secret Service (libsecret) on Linux. Passwords are stored in it with the
+* "credentials set" command.
+ */
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/zalando/go-keyring"
+	"golang.org/x/term"
+)
+
+// Service name under which passwords are stored in the OS keychain.
+const keyringServiceName = "mft-rest-submit-transfer-go"
+
+// A source of the password used for basic authentication.
+type CredentialProvider interface {
+	// Return the password of a user for the given reference.
+	Password(reference *url.URL, userId string) (string, error)
+}
+
+// Credential providers by URI scheme.
+var credentialProviders = map[string]CredentialProvider{
+	"keyring": keyringProvider{},
+	"awssm":   awsSecretsManagerProvider{},
+	"azkv":    azureKeyVaultProvider{},
+	"gcpsm":   gcpSecretManagerProvider{},
+	"ibmsm":   ibmSecretsManagerProvider{},
+	"mftcred": credentialsFileProvider{},
+}
+
+// Password resolved by webPassword, cached for the life of the program.
+var resolvedPassword *string
+
+/* Return the password for basic authentication. If the configured password
+* is a reference to a credential provider, the password is retrieved from it.
+ */
+func webPassword() (string, error) {
+	if resolvedPassword != nil {
+		return *resolvedPassword, nil
+	}
+	password := config.Password
+	reference, provider, err := credentialProviderFor(password)
+	if err != nil {
+		return "", err
+	}
+	if provider != nil {
+		password, err = provider.Password(reference, config.UserId)
+		if err != nil {
+			return "", fmt.Errorf("unable to retrieve password from %s provider: %v", reference.Scheme, err)
+		}
+	}
+	registerSecret(password)
+	resolvedPassword = &password
+	return password, nil
+}
+
+/* Return the provider for a password reference or nil for a plain password.
+* A value starting with the scheme of a provider is a reference, and an error
+* is returned if it is malformed rather than using it as the password.
+* value - Password or reference to a credential provider
+ */
+func credentialProviderFor(value string) (*url.URL, CredentialProvider, error) {
+	colon := strings.Index(value, ":")
+	if colon < 0 {
+		return nil, nil, nil
+	}
+	scheme := strings.ToLower(value[:colon])
+	provider, found := credentialProviders[scheme]
+	if !found {
+		return nil, nil, nil
+	}
+	if scheme == "awssm" && strings.HasPrefix(value[colon+1:], "//") {
+		// The secret ID may be an ARN, whose colons are not a host and port
+		return awsSecretReference(value[colon+3:]), provider, nil
+	}
+	reference, err := url.Parse(value)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid %s credential reference: %v", scheme, err)
+	}
+	return reference, provider, nil
+}
+
+/* Return the reference to an AWS secret, taking the secret ID as it is.
+* value - Reference without its awssm:// scheme: SECRET_ID[?QUERY][#KEY]
+ */
+func awsSecretReference(value string) *url.URL {
+	reference := &url.URL{Scheme: "awssm"}
+	if index := strings.Index(value, "#"); index >= 0 {
+		reference.Fragment, value = value[index+1:], value[:index]
+	}
+	if index := strings.Index(value, "?"); index >= 0 {
+		reference.RawQuery, value = value[index+1:], value[:index]
+	}
+	reference.Path = value
+	return reference
+}
+
+// Credential provider backed by the OS keychain.
+type keyringProvider struct{}
+
+func (keyringProvider) Password(reference *url.URL, userId string) (string, error) {
+	return keyring.Get(keyringService(reference), userId)
+}
+
+// Return the keychain service name of a keyring reference.
+func keyringService(reference *url.URL) string {
+	if reference != nil && len(reference.Host) > 0 {
+		return reference.Host
+	}
+	return keyringServiceName
+}
+
+/* Command "credentials" - manage the password stored in the OS keychain, or
+* encrypt it into a credentials file.
+ */
+func credentialsCommand(args []string) int {
+	if len(args) == 0 {
+		printMessage("credentialsUsage")
+		printMessage("credentialsEncryptUsage")
+		return 2
+	}
+	action := args[0]
+	flags := newFlagSet("credentials " + action)
+	userId := flags.String("user", "", "User whose password is managed. Default is the configured user.")
+	service := flags.String("service", "", "Keychain service name. Default is taken from the configured password.")
+	reveal := flags.Bool("reveal", false, "Print the password retrieved by get")
+	credentialsFile := flags.String("file", "", "Credentials file for encrypt. Default is taken from the configured password.")
+	keyFile := flags.String("key", "", "Key file for encrypt. Default is taken from the configured password.")
+	if !parseCommandLine(flags, args[1:]) {
+		return 2
+	}
+	if len(*userId) == 0 {
+		*userId = config.UserId
+	}
+	if action == "encrypt" {
+		return encryptCredentials(*userId, *credentialsFile, *keyFile)
+	}
+	if len(*service) == 0 {
+		reference, _, _ := credentialProviderFor(config.Password)
+		*service = keyringService(reference)
+	}
+
+	switch action {
+	case "set":
+		password, err := readPassword(fmt.Sprintf("Password for %s: ", *userId))
+		if err != nil {
+			printMessage("passwordReadFailed", err)
+			return 1
+		}
+		if err := keyring.Set(*service, *userId, password); err != nil {
+			printMessage("keychainStoreFailed", err)
+			return 1
+		}
+		printMessage("keychainStored", *userId, *service)
+	case "get":
+		password, err := keyring.Get(*service, *userId)
+		if err != nil {
+			printMessage("keychainGetFailed", err)
+			return 1
+		}
+		if *reveal {
+			fmt.Printf("%s\n", password)
+		} else {
+			printMessage("keychainPresent", *userId, *service)
+		}
+	case "delete":
+		if err := keyring.Delete(*service, *userId); err != nil {
+			printMessage("keychainDeleteFailed", err)
+			return 1
+		}
+		printMessage("keychainDeleted", *userId)
+	default:
+		printMessage("credentialsUnknownAction", action)
+		return 2
+	}
+	return 0
+}
+
+/* Encrypt the password of a user into a credentials file.
+* userId          - User whose password is encrypted
+* credentialsFile - Credentials file, blank for the configured one
+* keyFile         - Key file, blank for the configured one
+ */
+func encryptCredentials(userId string, credentialsFile string, keyFile string) int {
+	if reference, provider, _ := credentialProviderFor(config.Password); provider != nil && reference.Scheme == "mftcred" {
+		configuredFile, configuredKeyFile := credentialsFileNames(reference)
+		if len(credentialsFile) == 0 {
+			credentialsFile = configuredFile
+		}
+		if len(keyFile) == 0 {
+			keyFile = configuredKeyFile
+		}
+	}
+	if len(credentialsFile) == 0 {
+		printMessage("credentialsFileRequired")
+		return 2
+	}
+	if len(keyFile) == 0 {
+		keyFile = defaultCredentialsKeyFile()
+	}
+	password, err := readPassword(fmt.Sprintf("Password for %s: ", userId))
+	if err != nil {
+		printMessage("passwordReadFailed", err)
+		return 1
+	}
+	if err := storeEncryptedPassword(credentialsFile, keyFile, userId, password); err != nil {
+		printMessage("credentialsEncryptFailed", credentialsFile, err)
+		return 1
+	}
+	printMessage("credentialsEncrypted", userId, credentialsFile, keyFile)
+	return 0
+}
+
+/* Read a password from the terminal without echo. If standard input is not
+* a terminal, the first line of it is read.
+* prompt - Text displayed before reading
+ */
+func readPassword(prompt string) (string, error) {
+	fd := int(os.Stdin.Fd())
+	if term.IsTerminal(fd) {
+		fmt.Print(prompt)
+		password, err := term.ReadPassword(fd)
+		fmt.Println()
+		return string(password), err
+	}
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && len(line) == 0 {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
@@ -0,0 +1,319 @@
+/*
+© Copyright IBM Corporation 2022, 2022
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+/*
+* This file contains the support for an encrypted credentials file. The file
+* is MFT-style, with the root element and namespace of the MQMFTCredentials.xml
+* file of MFT agents and one mqweb element per MQ Web Server user holding the
+* password encrypted in the passwordCipher attribute:
+*
+* <tns:mqmftCredentials xmlns:tns="http://wmqfte.ibm.com/MFTCredentials">
+*   <tns:mqweb user="mqmftadminusr" passwordCipher="..."/>
+* </tns:mqmftCredentials>
+*
+* The mqweb element and its cipher are specific to this program: fteObfuscate
+* and the agents do not read them. The other elements of an existing file,
+* such as the qmgr and file credentials of an agent, and the attributes of
+* the root element are kept as they are when "credentials encrypt" rewrites
+* it, after the mqweb elements; comments directly under the root element are
+* not kept.
+*
+* Passwords are encrypted with AES-256-GCM using a key read from a key file.
+* The key file is created with a random key by "credentials encrypt" if it
+* does not exist and must be readable only by its owner. The password is then
+* referenced in the configuration as
+*
+*   "password": "mftcred:///path/to/MQMFTCredentials.xml?key=/path/to/keyfile"
+*
+* The key file defaults to .mqmftcredentials.key in the home directory.
+ */
+package main
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Namespace of MFT credentials files.
+const mftCredentialsNamespace = "http://wmqfte.ibm.com/MFTCredentials"
+
+// Prefix identifying the cipher used for a password.
+const passwordCipherPrefix = "{AESGCM}"
+
+// Content of a credentials file.
+type mftCredentials struct {
+	XMLName xml.Name             `xml:"mqmftCredentials"`
+	Attrs   []xml.Attr           `xml:",any,attr"`
+	Web     []mftWebCredential   `xml:"mqweb"`
+	Others  []mftOtherCredential `xml:",any"`
+}
+
+// Credential of a MQ Web Server user.
+type mftWebCredential struct {
+	User           string `xml:"user,attr"`
+	Password       string `xml:"password,attr,omitempty"`
+	PasswordCipher string `xml:"passwordCipher,attr,omitempty"`
+}
+
+// Element of a credentials file other than mqweb, kept as read.
+type mftOtherCredential struct {
+	XMLName xml.Name
+	Attrs   []xml.Attr `xml:",any,attr"`
+	Inner   string     `xml:",innerxml"`
+}
+
+/* Return a name read from a credentials file with its namespace written as
+* a prefix, as encoding/xml would declare a new default namespace instead.
+* The raw content of the elements that are kept refers to these prefixes.
+* name     - Name of an element or attribute
+* prefixes - Prefixes of the namespaces declared by the root element
+ */
+func prefixedName(name xml.Name, prefixes map[string]string) xml.Name {
+	if name.Space == "xmlns" {
+		return xml.Name{Local: "xmlns:" + name.Local}
+	}
+	if prefix, found := prefixes[name.Space]; found {
+		return xml.Name{Local: prefix + ":" + name.Local}
+	}
+	return name
+}
+
+// Return the attributes read from a credentials file with their namespaces
+// written as prefixes.
+func prefixedAttrs(attrs []xml.Attr, prefixes map[string]string) []xml.Attr {
+	prefixed := []xml.Attr{}
+	for _, attr := range attrs {
+		prefixed = append(prefixed, xml.Attr{Name: prefixedName(attr.Name, prefixes), Value: attr.Value})
+	}
+	return prefixed
+}
+
+// Credential provider backed by an encrypted credentials file.
+type credentialsFileProvider struct{}
+
+func (credentialsFileProvider) Password(reference *url.URL, userId string) (string, error) {
+	fileName, keyFileName := credentialsFileNames(reference)
+	credentials, err := readCredentialsFile(fileName)
+	if err != nil {
+		return "", err
+	}
+	for _, credential := range credentials.Web {
+		if credential.User != userId {
+			continue
+		}
+		if len(credential.PasswordCipher) == 0 {
+			return credential.Password, nil
+		}
+		key, err := readCredentialsKey(keyFileName, false)
+		if err != nil {
+			return "", err
+		}
+		return decryptPassword(credential.PasswordCipher, key)
+	}
+	return "", fmt.Errorf("no credentials for user %s in %s", userId, fileName)
+}
+
+// Return the credentials file and key file of a mftcred reference.
+func credentialsFileNames(reference *url.URL) (string, string) {
+	fileName := reference.Path
+	if len(reference.Opaque) > 0 {
+		fileName = reference.Opaque
+	}
+	keyFileName := reference.Query().Get("key")
+	if len(keyFileName) == 0 {
+		keyFileName = defaultCredentialsKeyFile()
+	}
+	return fileName, keyFileName
+}
+
+// Return the default key file in the home directory of the user.
+func defaultCredentialsKeyFile() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		home = "."
+	}
+	return filepath.Join(home, ".mqmftcredentials.key")
+}
+
+// Read a credentials file. A missing file has no credentials.
+func readCredentialsFile(fileName string) (mftCredentials, error) {
+	var credentials mftCredentials
+	content, err := os.ReadFile(fileName)
+	if os.IsNotExist(err) {
+		return credentials, nil
+	} else if err != nil {
+		return credentials, err
+	}
+	err = xml.Unmarshal(content, &credentials)
+	return credentials, err
+}
+
+// Write a credentials file readable only by its owner, keeping the
+// attributes of the root element and the elements other than mqweb.
+func writeCredentialsFile(fileName string, credentials mftCredentials) error {
+	type element struct {
+		XMLName xml.Name             `xml:"tns:mqmftCredentials"`
+		Tns     string               `xml:"xmlns:tns,attr"`
+		Attrs   []xml.Attr           `xml:",any,attr"`
+		Web     []mftWebCredential   `xml:"tns:mqweb"`
+		Others  []mftOtherCredential `xml:",any"`
+	}
+	prefixes := map[string]string{mftCredentialsNamespace: "tns"}
+	rootAttrs := []xml.Attr{}
+	for _, attr := range credentials.Attrs {
+		if attr.Name.Space == "xmlns" && attr.Name.Local == "tns" {
+			continue
+		}
+		if attr.Name.Space == "xmlns" && attr.Value != mftCredentialsNamespace {
+			prefixes[attr.Value] = attr.Name.Local
+		}
+		rootAttrs = append(rootAttrs, attr)
+	}
+	others := []mftOtherCredential{}
+	for _, other := range credentials.Others {
+		others = append(others, mftOtherCredential{XMLName: prefixedName(other.XMLName, prefixes),
+			Attrs: prefixedAttrs(other.Attrs, prefixes), Inner: other.Inner})
+	}
+	root := element{Tns: mftCredentialsNamespace, Attrs: prefixedAttrs(rootAttrs, prefixes), Web: credentials.Web, Others: others}
+	content, err := xml.MarshalIndent(root, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(fileName, append([]byte(xml.Header), append(content, '\n')...), 0600)
+}
+
+/* Read the key used to encrypt passwords.
+* fileName - Key file
+* create   - Create the key file with a random key if it does not exist
+ */
+func readCredentialsKey(fileName string, create bool) ([]byte, error) {
+	content, err := os.ReadFile(fileName)
+	if os.IsNotExist(err) && create {
+		key := make([]byte, 32)
+		if _, err := io.ReadFull(rand.Reader, key); err != nil {
+			return nil, err
+		}
+		encoded := base64.StdEncoding.EncodeToString(key)
+		if err := os.WriteFile(fileName, []byte(encoded+"\n"), 0600); err != nil {
+			return nil, err
+		}
+		printMessage("credentialsKeyCreated", fileName)
+		return key, nil
+	} else if err != nil {
+		return nil, err
+	}
+	if info, err := os.Stat(fileName); err == nil && info.Mode().Perm()&0077 != 0 {
+		printMessage("credentialsKeyInsecure", fileName)
+	}
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(content)))
+	if err != nil || len(key) != 32 {
+		return nil, fmt.Errorf("%s does not contain a valid key", fileName)
+	}
+	return key, nil
+}
+
+// Encrypt a password with AES-256-GCM.
+func encryptPassword(password string, key []byte) (string, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return "", err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", err
+	}
+	sealed := gcm.Seal(nonce, nonce, []byte(password), nil)
+	return passwordCipherPrefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt a password encrypted by encryptPassword.
+func decryptPassword(passwordCipher string, key []byte) (string, error) {
+	if !strings.HasPrefix(passwordCipher, passwordCipherPrefix) {
+		return "", errors.New("unsupported password cipher")
+	}
+	sealed, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(passwordCipher, passwordCipherPrefix))
+	if err != nil {
+		return "", err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return "", err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return "", err
+	}
+	if len(sealed) < gcm.NonceSize() {
+		return "", errors.New("password cipher is too short")
+	}
+	password, err := gcm.Open(nil, sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():], nil)
+	if err != nil {
+		return "", errors.New("password cannot be decrypted with the key file")
+	}
+	return string(password), nil
+}
+
+/* Encrypt a password into a credentials file, replacing any existing
+* credential of the user. Plain text passwords of other users are encrypted
+* as well.
+* fileName    - Credentials file
+* keyFileName - Key file, created if it does not exist
+* userId      - User of the password
+* password    - Password to encrypt
+ */
+func storeEncryptedPassword(fileName string, keyFileName string, userId string, password string) error {
+	key, err := readCredentialsKey(keyFileName, true)
+	if err != nil {
+		return err
+	}
+	passwordCipher, err := encryptPassword(password, key)
+	if err != nil {
+		return err
+	}
+	credentials, err := readCredentialsFile(fileName)
+	if err != nil {
+		return err
+	}
+	credential := mftWebCredential{User: userId, PasswordCipher: passwordCipher}
+	replaced := false
+	for index := range credentials.Web {
+		if credentials.Web[index].User == userId {
+			credentials.Web[index] = credential
+			replaced = true
+		} else if len(credentials.Web[index].Password) > 0 {
+			otherCipher, err := encryptPassword(credentials.Web[index].Password, key)
+			if err != nil {
+				return err
+			}
+			credentials.Web[index] = mftWebCredential{User: credentials.Web[index].User, PasswordCipher: otherCipher}
+		}
+	}
+	if !replaced {
+		credentials.Web = append(credentials.Web, credential)
+	}
+	return writeCredentialsFile(fileName, credentials)
+}
@@ -0,0 +1,308 @@
+/*
+© Copyright IBM Corporation 2022, 2022
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+/*
+* This file contains the "dashboard" command, a terminal user interface
+* showing the agents and the recent transfers of a MQ Web Server, refreshed
+* every -interval. The items of the selected transfer are browsed with the
+* item browser of tui.go, and a transfer that has not ended can be cancelled.
+* The following keys are supported:
+*
+*   Up/Down, k/j        Select a transfer
+*   PgUp/PgDn, b/Space  Move one page
+*   Home/End, g/G       Select the first or last transfer
+*   Enter               Browse the items of the selected transfer
+*   c                   Cancel the selected transfer, after confirmation
+*   r                   Refresh now
+*   q, Ctrl-C           Quit
+ */
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/tidwall/gjson"
+)
+
+// Largest number of agent lines on the dashboard, the transfers using the
+// rest of the screen.
+const maxDashboardAgents = 8
+
+// State of the dashboard.
+type dashboard struct {
+	title        string
+	route        Route
+	transfersUrl string
+	limit        int
+	agents       []AgentInfo
+	transfers    []gjson.Result
+	refreshedAt  time.Time
+	status       string
+	selected     int
+	top          int
+	width        int
+	height       int
+}
+
+// Command "dashboard" - show the agents and transfers in a terminal user
+// interface.
+func dashboardCommand(args []string) int {
+	flags := newFlagSet("dashboard")
+	routeName := flags.String("route", "", "Route whose MQ Web Server is queried")
+	interval := flags.Duration("interval", 5*time.Second, "Interval between the refreshes")
+	limit := flags.Int("limit", 100, "Maximum number of transfers shown, 0 for no limit")
+	if !parseCommandLine(flags, args) || flags.NArg() != 0 || *interval <= 0 || *limit < 0 {
+		printMessage("dashboardUsage")
+		return 2
+	}
+	route, err := findRoute(*routeName)
+	if err != nil {
+		printError(err)
+		return 2
+	}
+	board := &dashboard{
+		title:        "MFT dashboard  " + route.transferUrl(),
+		route:        route,
+		transfersUrl: strings.TrimSuffix(route.transferUrl(), "/") + "?attributes=*",
+		limit:        *limit,
+	}
+	if err := board.run(*interval); err != nil {
+		printMessage("tuiFailed", err)
+		return 1
+	}
+	return 0
+}
+
+/* Run the dashboard until the user quits.
+* interval - Interval between the refreshes
+ */
+func (board *dashboard) run(interval time.Duration) error {
+	keys, restore, err := openTerminal()
+	if err != nil {
+		return err
+	}
+	defer restore()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	board.refresh()
+	for {
+		board.width, board.height = terminalSize()
+		board.move(0)
+		board.render()
+		select {
+		case <-ticker.C:
+			board.refresh()
+		case key, open := <-keys:
+			if !open {
+				return nil
+			}
+			switch key {
+			case "q", "\x03":
+				return nil
+			case "up", "k":
+				board.move(-1)
+			case "down", "j":
+				board.move(1)
+			case "pgup", "b":
+				board.move(-board.pageSize())
+			case "pgdn", " ":
+				board.move(board.pageSize())
+			case "home", "g":
+				board.move(-len(board.transfers))
+			case "end", "G":
+				board.move(len(board.transfers))
+			case "\r", "\n":
+				if err := board.browseSelected(keys); err != nil {
+					return err
+				}
+			case "c":
+				board.cancelSelected(keys)
+			case "r":
+				board.refresh()
+			}
+		}
+	}
+}
+
+// Query the agents and transfers again. Errors are shown on the status line.
+func (board *dashboard) refresh() {
+	board.status = ""
+	agentsUrl := agentCollectionUrl() + "?attributes=*"
+	// Cached responses would hide the changes
+	webResponses.invalidate(agentsUrl)
+	webResponses.invalidate(board.transfersUrl)
+	if response, body, err := callMQWeb("GET", agentsUrl, ""); err != nil {
+		board.status = "Agents: " + err.Error()
+	} else if response.StatusCode != http.StatusOK {
+		board.status = "Agents: " + mqWebError(response, body).Error()
+	} else {
+		board.agents = parseAgents(body, time.Now())
+	}
+	response, body, err := callMQWeb("GET", board.transfersUrl, "")
+	if err == nil && response.StatusCode != http.StatusOK {
+		err = mqWebError(response, body)
+	}
+	if err != nil {
+		board.status = "Transfers: " + err.Error()
+		return
+	}
+	transfers := gjson.Get(body, "transfer").Array()
+	if board.limit > 0 && len(transfers) > board.limit {
+		transfers = transfers[:board.limit]
+	}
+	// Keep the same transfer selected when the list changes
+	selectedId := ""
+	if board.selected < len(board.transfers) {
+		selectedId = board.transfers[board.selected].Get("id").String()
+	}
+	board.transfers = transfers
+	for index, transfer := range transfers {
+		if transfer.Get("id").String() == selectedId {
+			board.selected = index
+		}
+	}
+	board.refreshedAt = time.Now()
+}
+
+// Number of agent lines on the screen.
+func (board *dashboard) agentLines() int {
+	if len(board.agents) > maxDashboardAgents {
+		return maxDashboardAgents
+	}
+	return len(board.agents)
+}
+
+// Number of transfer lines on the screen.
+func (board *dashboard) pageSize() int {
+	// Title, agent header, agents, blank line, transfer header and status line
+	if lines := board.height - board.agentLines() - 5; lines > 0 {
+		return lines
+	}
+	return 1
+}
+
+// Move the selection by a number of transfers.
+func (board *dashboard) move(delta int) {
+	board.selected += delta
+	if board.selected >= len(board.transfers) {
+		board.selected = len(board.transfers) - 1
+	}
+	if board.selected < 0 {
+		board.selected = 0
+	}
+	if board.selected < board.top {
+		board.top = board.selected
+	}
+	if board.selected >= board.top+board.pageSize() {
+		board.top = board.selected - board.pageSize() + 1
+	}
+}
+
+// Return the selected transfer, if any.
+func (board *dashboard) selectedTransfer() (gjson.Result, bool) {
+	if board.selected < len(board.transfers) {
+		return board.transfers[board.selected], true
+	}
+	return gjson.Result{}, false
+}
+
+// Draw the screen.
+func (board *dashboard) render() {
+	var screen strings.Builder
+	screen.WriteString("\x1b[H\x1b[2J")
+	title := board.title
+	if !board.refreshedAt.IsZero() {
+		title += "  refreshed " + board.refreshedAt.Format("15:04:05")
+	}
+	screen.WriteString("\x1b[1m" + fitWidth(title, board.width) + "\x1b[0m\r\n")
+	screen.WriteString(fitWidth(fmt.Sprintf("%-28s %-10s %-14s %s", "AGENT", "TYPE", "STATE", "QUEUE MANAGER"), board.width) + "\r\n")
+	for _, agent := range board.agents[:board.agentLines()] {
+		screen.WriteString(fitWidth(fmt.Sprintf("%-28s %-10s %-14s %s", agent.Name, agent.Type, agent.State, agent.QueueManager), board.width) + "\r\n")
+	}
+	screen.WriteString("\r\n")
+	screen.WriteString(fitWidth(fmt.Sprintf("%-48s %-12s %-16s %-16s %s", "TRANSFER", "STATE", "SOURCE", "DESTINATION", "PROGRESS"), board.width) + "\r\n")
+	for row := 0; row < board.pageSize(); row++ {
+		index := board.top + row
+		if index < len(board.transfers) {
+			transfer := board.transfers[index]
+			line := fitWidth(fmt.Sprintf("%-48s %-12s %-16s %-16s %s", transfer.Get("id").String(), transfer.Get("status.state").String(),
+				transfer.Get("sourceAgent.name").String(), transfer.Get("destinationAgent.name").String(),
+				transferProgress(transfer).String()), board.width)
+			if index == board.selected {
+				line = "\x1b[7m" + line + "\x1b[0m"
+			}
+			screen.WriteString(line)
+		}
+		screen.WriteString("\r\n")
+	}
+	status := fmt.Sprintf("%d agents  %d transfers", len(board.agents), len(board.transfers))
+	if len(board.status) > 0 {
+		status += "  " + board.status
+	}
+	status += "  [Enter]items [c]ancel [r]efresh [q]uit"
+	screen.WriteString("\x1b[7m" + fitWidth(status, board.width) + "\x1b[0m")
+	fmt.Print(screen.String())
+}
+
+/* Browse the items of the selected transfer. The transfer is queried again
+* as the collection may not give the results of its items.
+* keys - Keys pressed
+ */
+func (board *dashboard) browseSelected(keys <-chan string) error {
+	selected, found := board.selectedTransfer()
+	if !found {
+		return nil
+	}
+	transferUrl := transferResourceUrl(board.route, selected.Get("id").String())
+	response, body, err := callMQWeb("GET", transferUrl+"?attributes=*", "")
+	if err == nil && response.StatusCode != http.StatusOK {
+		err = mqWebError(response, body)
+	}
+	if err != nil {
+		board.status = "Transfer: " + err.Error()
+		return nil
+	}
+	transfer := gjson.Get(body, "transfer.0")
+	title := fmt.Sprintf("Transfer %s  %s -> %s  %s", transfer.Get("id").String(),
+		transfer.Get("sourceAgent.name").String(), transfer.Get("destinationAgent.name").String(),
+		transfer.Get("status.state").String())
+	return browseItems(keys, title, transferItemResults(transfer))
+}
+
+/* Cancel the selected transfer after confirmation.
+* keys - Keys pressed
+ */
+func (board *dashboard) cancelSelected(keys <-chan string) {
+	selected, found := board.selectedTransfer()
+	if !found {
+		return
+	}
+	id, state := selected.Get("id").String(), selected.Get("status.state").String()
+	if isTerminalTransferState(state) {
+		board.status = "Transfer " + id + " has ended " + state
+		return
+	}
+	answer := promptLine(keys, board.height, "Cancel transfer "+id+"? [y/N] ")
+	if !strings.EqualFold(answer, "y") && !strings.EqualFold(answer, "yes") {
+		return
+	}
+	if err := postCancel(transferResourceUrl(board.route, id)); err != nil {
+		board.status = "Cancel: " + err.Error()
+		return
+	}
+	board.refresh()
+	board.status = "Cancel of " + id + " requested"
+}
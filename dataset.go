@@ -0,0 +1,211 @@
+/*
+© Copyright IBM Corporation 2022, 2022
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+/*
+* This file contains the z/OS dataset items of a transfer, for agents running
+* on z/OS. An item of type "dataset" is a sequential dataset or a member of a
+* partitioned dataset, and an item of type "pds" is a whole partitioned
+* dataset. Their names are given as //'HLQ.DATASET', fully qualified, or
+* //DATASET, prefixed with the high level qualifier of the agent user, with a
+* member in parentheses: //'HLQ.PDS(MEMBER)'. An item whose name starts with
+* // is a dataset when its type is not set.
+*
+* A destination dataset created by the transfer is allocated with the
+* attributes of the item:
+*
+*   { "source": "/u/data/orders.txt", "destination": "//'PROD.ORDERS.DATA'",
+*     "datasetAttributes": { "recfm": "FB", "lrecl": 80, "blksize": 27920, "space": "CYL,5,1" } }
+*
+* recfm is the record format, F, V or U followed by B for blocked, S for
+* spanned or standard and A or M for control characters. lrecl and blksize
+* are the record length and block size in bytes, blksize 0 letting the system
+* choose. space is the unit, CYL or TRK, the primary and the secondary
+* quantities. The attributes are sent in the BPXWDYN form used by MFT:
+* RECFM(F,B);LRECL(80);BLKSIZE(27920);SPACE(5,1);CYL.
+ */
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Limits of z/OS dataset names and attributes.
+const (
+	maxDatasetNameLength = 44
+	maxDatasetQualifier  = 8
+	maxDatasetRecordSize = 32760
+)
+
+// Record formats of a dataset: F, V or U, blocked, spanned or standard, and
+// ASA or machine control characters.
+var datasetRecordFormat = regexp.MustCompile(`^[FVU]B?S?[AM]?$`)
+
+// Units of the space allocated to a dataset, with their BPXWDYN keyword.
+var datasetSpaceUnits = map[string]string{"CYL": "CYL", "TRK": "TRACKS"}
+
+// Attributes of a dataset allocated by a transfer.
+type DatasetAttributes struct {
+	RecordFormat string `json:"recfm"`
+	RecordLength int    `json:"lrecl"`
+	BlockSize    int    `json:"blksize"`
+	Space        string `json:"space"`
+}
+
+// Return the attributes in the BPXWDYN form expected by MFT.
+func (attributes DatasetAttributes) String() string {
+	keys := []string{}
+	if len(attributes.RecordFormat) > 0 {
+		keys = append(keys, "RECFM("+strings.Join(strings.Split(strings.ToUpper(attributes.RecordFormat), ""), ",")+")")
+	}
+	if attributes.RecordLength > 0 {
+		keys = append(keys, fmt.Sprintf("LRECL(%d)", attributes.RecordLength))
+	}
+	if attributes.BlockSize > 0 {
+		keys = append(keys, fmt.Sprintf("BLKSIZE(%d)", attributes.BlockSize))
+	}
+	if unit, quantities, found := strings.Cut(attributes.Space, ","); found {
+		keys = append(keys, "SPACE("+quantities+")", datasetSpaceUnits[strings.ToUpper(unit)])
+	}
+	return strings.Join(keys, ";")
+}
+
+// Check if an item name is given in the form of a dataset name.
+func isDatasetPath(name string) bool {
+	return strings.HasPrefix(name, "//")
+}
+
+// Check if an item type is a dataset or partitioned dataset.
+func isDatasetType(itemType string) bool {
+	return itemType == "dataset" || itemType == "pds"
+}
+
+/* Check the dataset names and attributes of an item.
+* item     - Item of the transfer
+* pointer  - Returns the JSON pointer of an attribute of the item
+* problems - Problems found
+ */
+func validateDatasetOptions(item TransferItem, pointer func(tokens ...interface{}) string, problems *requestProblems) {
+	validateDatasetName(item.Source, item.SourceType, pointer("source", "name"), problems)
+	validateDatasetName(item.Destination, item.DestinationType, pointer("destination", "name"), problems)
+	attributes := item.DatasetAttributes
+	if attributes == nil {
+		return
+	}
+	if !isDatasetType(item.DestinationType) {
+		problems.add(pointer("destination", "attributes"), nil, "dataset attributes only apply to a dataset or pds destination")
+		return
+	}
+	if len(attributes.RecordFormat) > 0 && !datasetRecordFormat.MatchString(strings.ToUpper(attributes.RecordFormat)) {
+		problems.add(pointer("destination", "attributes"), attributes.RecordFormat, "is not a record format such as F, FB, V, VB or U")
+	}
+	if attributes.RecordLength < 0 || attributes.RecordLength > maxDatasetRecordSize {
+		problems.add(pointer("destination", "attributes"), attributes.RecordLength, "is not a record length between 1 and %d", maxDatasetRecordSize)
+	}
+	if attributes.BlockSize < 0 || attributes.BlockSize > maxDatasetRecordSize {
+		problems.add(pointer("destination", "attributes"), attributes.BlockSize, "is not a block size between 0 and %d", maxDatasetRecordSize)
+	}
+	fixedBlocked := strings.HasPrefix(strings.ToUpper(attributes.RecordFormat), "FB")
+	if fixedBlocked && attributes.RecordLength > 0 && attributes.BlockSize > 0 && attributes.BlockSize%attributes.RecordLength != 0 {
+		problems.add(pointer("destination", "attributes"), attributes.BlockSize, "must be a multiple of the record length %d", attributes.RecordLength)
+	}
+	if len(attributes.Space) > 0 && !isDatasetSpace(attributes.Space) {
+		problems.add(pointer("destination", "attributes"), attributes.Space, "must be CYL or TRK, the primary and the secondary quantities")
+	}
+}
+
+/* Check the name of a dataset item.
+* name     - Name of the item
+* itemType - Type of the item
+* pointer  - JSON pointer of the name
+* problems - Problems found
+ */
+func validateDatasetName(name string, itemType string, pointer string, problems *requestProblems) {
+	switch {
+	case !isDatasetType(itemType):
+		if isDatasetPath(name) {
+			problems.add(pointer, name, "is a dataset name, the type must be dataset or pds")
+		}
+	case !isDatasetName(name):
+		problems.add(pointer, name, "must be //'HLQ.DATASET' or //'HLQ.PDS(MEMBER)', with qualifiers of 1 to %d characters and at most %d characters",
+			maxDatasetQualifier, maxDatasetNameLength)
+	case itemType == "pds" && strings.Contains(name, "("):
+		problems.add(pointer, name, "names a member, the type must be dataset")
+	}
+}
+
+// Check if a name is a dataset name, //'HLQ.DATASET' or //DATASET with an
+// optional member.
+func isDatasetName(name string) bool {
+	if !isDatasetPath(name) {
+		return false
+	}
+	name = strings.TrimPrefix(name, "//")
+	if strings.HasPrefix(name, "'") {
+		if len(name) < 3 || !strings.HasSuffix(name, "'") {
+			return false
+		}
+		name = name[1 : len(name)-1]
+	}
+	dataset, member, hasMember := strings.Cut(name, "(")
+	if hasMember && (!strings.HasSuffix(member, ")") || !isDatasetQualifier(strings.TrimSuffix(member, ")"), false)) {
+		return false
+	}
+	if len(dataset) > maxDatasetNameLength {
+		return false
+	}
+	for _, qualifier := range strings.Split(dataset, ".") {
+		if !isDatasetQualifier(qualifier, true) {
+			return false
+		}
+	}
+	return true
+}
+
+/* Check if a name is a qualifier of a dataset name or a member name: 1 to 8
+* characters, a letter or national character followed by letters, digits,
+* national characters and, in a qualifier, hyphens.
+* name   - Qualifier or member name
+* hyphen - Whether hyphens are allowed
+ */
+func isDatasetQualifier(name string, hyphen bool) bool {
+	if len(name) == 0 || len(name) > maxDatasetQualifier {
+		return false
+	}
+	for index, char := range strings.ToUpper(name) {
+		switch {
+		case char >= 'A' && char <= 'Z', char == '@', char == '#', char == '$':
+		case index > 0 && char >= '0' && char <= '9':
+		case index > 0 && hyphen && char == '-':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// Check if a space allocation is UNIT,PRIMARY[,SECONDARY].
+func isDatasetSpace(space string) bool {
+	fields := strings.Split(space, ",")
+	if _, found := datasetSpaceUnits[strings.ToUpper(fields[0])]; !found || len(fields) < 2 || len(fields) > 3 {
+		return false
+	}
+	for _, quantity := range fields[1:] {
+		if value, err := strconv.Atoi(quantity); err != nil || value < 0 {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,154 @@
+/*
+© Copyright IBM Corporation 2022, 2022
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+/*
+* This file contains the export of transfer results to files for
+* reconciliation. -items-csv writes one row per transfer item with the
+* columns source, destination, state, bytes, checksum, description and
+* destination_checksum.
+*
+* "list -output csv" and "status -output csv" print one row per transfer with
+* the columns id, source_agent, destination_agent, source, destination,
+* state, bytes, start_time and end_time, for spreadsheets and audits. The
+* source and destination of a transfer of several items are those of its
+* items separated by semicolons. With -output csv-items they print one row
+* per item instead, with the same columns, the paths, state and bytes being
+* those of the item. "batch -csv" writes one row per transfer of the batch,
+* with the route as first column.
+ */
+package main
+
+import (
+	"encoding/csv"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/tidwall/gjson"
+)
+
+/* Write the item results of a transfer to a CSV file. Returns false if the
+* file could not be written.
+* fileName       - CSV file to write
+* transferStatus - Response of the transfer status query
+ */
+func writeItemsCsvFile(fileName string, transferStatus string) bool {
+	items := transferItemResults(gjson.Get(transferStatus, "transfer.0"))
+	if err := writeItemsCsv(fileName, items); err != nil {
+		printMessage("itemsCsvWriteFailed", fileName, err)
+		return false
+	}
+	printMessage("itemsCsvWritten", len(items), fileName)
+	return true
+}
+
+/* Write item results to a CSV file.
+* fileName - CSV file to write
+* items    - Item results of a transfer
+ */
+func writeItemsCsv(fileName string, items []ItemResult) error {
+	file, err := os.Create(fileName)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	writer := csv.NewWriter(file)
+	writer.Write([]string{"source", "destination", "state", "bytes", "checksum", "description", "destination_checksum"})
+	for _, item := range items {
+		writer.Write([]string{item.Source, item.Destination, item.State, strconv.FormatInt(item.Bytes, 10), item.Checksum,
+			item.Description, item.DestinationChecksum})
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return err
+	}
+	return file.Close()
+}
+
+// Columns of the CSV export of transfers.
+var transferCsvHeader = []string{"id", "source_agent", "destination_agent", "source", "destination", "state", "bytes",
+	"start_time", "end_time"}
+
+/* Return the CSV rows of a transfer.
+* transfer - Transfer as returned by the status query
+* perItem  - One row per item instead of one row for the transfer
+ */
+func transferCsvRecords(transfer gjson.Result, perItem bool) [][]string {
+	record := func(source string, destination string, state string, bytes int64) []string {
+		return []string{transfer.Get("id").String(), transfer.Get("sourceAgent.name").String(),
+			transfer.Get("destinationAgent.name").String(), source, destination, state, strconv.FormatInt(bytes, 10),
+			transfer.Get("statistics.startTime").String(), transfer.Get("statistics.endTime").String()}
+	}
+	items := transferItemResults(transfer)
+	if perItem {
+		records := [][]string{}
+		for _, item := range items {
+			records = append(records, record(item.Source, item.Destination, item.State, item.Bytes))
+		}
+		return records
+	}
+	sources, destinations := []string{}, []string{}
+	for _, item := range items {
+		sources = append(sources, item.Source)
+		destinations = append(destinations, item.Destination)
+	}
+	return [][]string{record(strings.Join(sources, ";"), strings.Join(destinations, ";"),
+		transfer.Get("status.state").String(), transferProgress(transfer).Bytes)}
+}
+
+/* Write transfers as CSV.
+* out       - Output of the CSV
+* transfers - Transfers as returned by the status query
+* perItem   - One row per item instead of one row per transfer
+ */
+func writeTransfersCsv(out io.Writer, transfers []gjson.Result, perItem bool) error {
+	writer := csv.NewWriter(out)
+	writer.Write(transferCsvHeader)
+	for _, transfer := range transfers {
+		writer.WriteAll(transferCsvRecords(transfer, perItem))
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+/* Write the transfers of a batch to a CSV file, one row per transfer. The
+* state and bytes are those recorded by the batch, which also cover the
+* transfers that could not be submitted.
+* fileName  - CSV file to write
+* transfers - Transfers of the batch, with their final state
+ */
+func writeBatchCsv(fileName string, transfers []*BatchTransfer) error {
+	file, err := os.Create(fileName)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	writer := csv.NewWriter(file)
+	writer.Write(append([]string{"route"}, transferCsvHeader...))
+	for _, transfer := range transfers {
+		record := make([]string, len(transferCsvHeader))
+		if status := gjson.Get(transfer.Body, "transfer.0"); status.Exists() {
+			record = transferCsvRecords(status, false)[0]
+		}
+		record[0], record[5], record[6] = transfer.TransferId, transfer.State, strconv.FormatInt(transfer.Bytes, 10)
+		writer.Write(append([]string{transfer.Route}, record...))
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return err
+	}
+	return file.Close()
+}
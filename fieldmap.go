@@ -0,0 +1,141 @@
+/*
+© Copyright IBM Corporation 2022, 2022
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+/*
+* This file contains the mapping of deprecated and renamed fields of transfer
+* requests written outside this program, such as held requests, manifests and
+* templates, to the names expected by the MQ Web Server. A request using an
+* old name keeps working and a warning names the field to update.
+*
+* Fields are named by their dotted path in the request. Arrays are traversed,
+* so "transferSet.item.source.file" applies to the source of every item. The
+* new name of a field is given by its full path, which must have the same
+* parent as the old one. Mappings can be added for the level of MQ in use with
+* the "requestFieldMappings" attribute of the configuration:
+*
+*   "requestFieldMappings": { "transferSet.item.source.file": "transferSet.item.source.name" }
+ */
+package main
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+)
+
+// Deprecated and alternative field names of transfer requests.
+var deprecatedRequestFields = map[string]string{
+	"transferSet.items":             "transferSet.item",
+	"sourceAgent.queueManager":      "sourceAgent.qmgrName",
+	"destinationAgent.queueManager": "destinationAgent.qmgrName",
+	"sourceAgent.agentName":         "sourceAgent.name",
+	"destinationAgent.agentName":    "destinationAgent.name",
+}
+
+/* Rename the deprecated fields of a transfer request, printing a warning for
+* each field renamed. The request is returned unchanged if it has none or is
+* not valid JSON.
+* request - Transfer request in JSON format
+ */
+func mapDeprecatedFields(request string) string {
+	var document map[string]interface{}
+	decoder := json.NewDecoder(strings.NewReader(request))
+	// Keep numbers as written
+	decoder.UseNumber()
+	if err := decoder.Decode(&document); err != nil {
+		return request
+	}
+	mappings := map[string]string{}
+	for from, to := range deprecatedRequestFields {
+		mappings[from] = to
+	}
+	for from, to := range config.RequestFieldMappings {
+		mappings[from] = to
+	}
+	// Rename parents before their children
+	paths := make([]string, 0, len(mappings))
+	for from := range mappings {
+		paths = append(paths, from)
+	}
+	sort.Slice(paths, func(i, k int) bool {
+		depthI, depthK := strings.Count(paths[i], "."), strings.Count(paths[k], ".")
+		if depthI != depthK {
+			return depthI < depthK
+		}
+		return paths[i] < paths[k]
+	})
+
+	renamed := false
+	for _, from := range paths {
+		to := mappings[from]
+		parent, oldName := splitFieldPath(from)
+		newParent, newName := splitFieldPath(to)
+		if parent != newParent || oldName == newName {
+			printMessage("requestFieldMappingInvalid", from, to)
+			continue
+		}
+		if renameField(document, parent, oldName, newName) {
+			printMessage("requestFieldDeprecated", from, to)
+			renamed = true
+		}
+	}
+	if !renamed {
+		return request
+	}
+	content, err := json.Marshal(document)
+	if err != nil {
+		return request
+	}
+	return string(content)
+}
+
+// Split a field path into the path of its parent and its name.
+func splitFieldPath(path string) (string, string) {
+	if index := strings.LastIndex(path, "."); index >= 0 {
+		return path[:index], path[index+1:]
+	}
+	return "", path
+}
+
+/* Rename a field in every object found at a path. Returns true if a field
+* was renamed. A field already present under its new name is left alone.
+* value   - JSON value decoded by encoding/json
+* parent  - Dotted path of the objects holding the field, blank for value
+* oldName - Deprecated name of the field
+* newName - Current name of the field
+ */
+func renameField(value interface{}, parent string, oldName string, newName string) bool {
+	switch value := value.(type) {
+	case []interface{}:
+		renamed := false
+		for _, element := range value {
+			renamed = renameField(element, parent, oldName, newName) || renamed
+		}
+		return renamed
+	case map[string]interface{}:
+		if len(parent) > 0 {
+			name, rest, _ := strings.Cut(parent, ".")
+			return renameField(value[name], rest, oldName, newName)
+		}
+		field, found := value[oldName]
+		if !found {
+			return false
+		}
+		if _, exists := value[newName]; !exists {
+			value[newName] = field
+		}
+		delete(value, oldName)
+		return true
+	}
+	return false
+}
@@ -0,0 +1,54 @@
+/*
+© Copyright IBM Corporation 2022, 2022
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+/*
+* This file contains the file space destinations of a transfer. A file space
+* is an area of the queue manager of the destination agent that users of the
+* MFT web gateway download files from. An item of destination type
+* "fileSpace" is named by the file space, and the files of the source keep
+* their names in it:
+*
+*   { "source": "/usr/srcdir/report.pdf", "sourceType": "file",
+*     "destination": "partnerA", "destinationType": "fileSpace" }
+*
+* The source of a file space destination is a file, the files matching a
+* pattern or a directory.
+ */
+package main
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Maximum length of the name of a file space.
+const maxFileSpaceNameLength = 256
+
+/* Check the name and source of an item whose destination is a file space.
+* item     - Item of the transfer
+* pointer  - Returns the JSON pointer of an attribute of the item
+* problems - Problems found
+ */
+func validateFileSpaceOptions(item TransferItem, pointer func(tokens ...interface{}) string, problems *requestProblems) {
+	if item.DestinationType != "fileSpace" {
+		return
+	}
+	if item.SourceType != "file" && item.SourceType != "directory" {
+		problems.add(pointer("source", "type"), item.SourceType, "must be file or directory for a file space destination")
+	}
+	if len(item.Destination) > maxFileSpaceNameLength ||
+		strings.IndexFunc(item.Destination, func(r rune) bool { return unicode.IsSpace(r) || r == '/' || r == '\\' }) >= 0 {
+		problems.add(pointer("destination", "name"), item.Destination, "must be the name of a file space, without white space or path separators, of at most %d characters",
+			maxFileSpaceNameLength)
+	}
+}
@@ -0,0 +1,199 @@
+/*
+© Copyright IBM Corporation 2022, 2022
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+/*
+* This file contains the "fixup" command, which resubmits the items of a
+* failed or partially successful transfer that did not succeed. The transfer
+* is queried with its item results, and a corrective transfer is proposed
+* between the same agents with the failed items only, keeping their mode,
+* encodings and other attributes as well as the job name, priority and user
+* defined metadata of the transfer. The ID of the original transfer is added
+* to the metadata under the key fixupOf.
+*
+* The failed items and the reason of each failure are printed and the user is
+* asked to confirm before the corrective transfer is submitted, unless -yes is
+* given. With -if-exists overwrite, destination files left by the failed
+* attempt are replaced. With -dry-run the request is printed without asking.
+ */
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/tidwall/gjson"
+)
+
+// Metadata key holding the ID of the transfer corrected by a fixup transfer.
+const fixupMetadataKey = "fixupOf"
+
+// Command "fixup" - resubmit the failed items of a transfer.
+func fixupCommand(args []string) int {
+	flags := newFlagSet("fixup")
+	yes := flags.Bool("yes", false, "Submit the corrective transfer without asking for confirmation")
+	actionIfExists := flags.String("if-exists", "", "Action when a destination file exists, error or overwrite")
+	dryRun := flags.Bool("dry-run", false, "Print the corrective transfer request without submitting it")
+	resultFile := flags.String("result", "", "Write the result of the submission to a JSON file")
+	routeName := flags.String("route", "", "Route whose MQ Web Server is queried")
+	if !parseCommandLine(flags, args) || flags.NArg() != 1 {
+		printMessage("fixupUsage")
+		return 2
+	}
+	transferId, err := normalizeTransferId(flags.Arg(0))
+	if err != nil {
+		printError(err)
+		return 2
+	}
+	server, err := findRoute(*routeName)
+	if err != nil {
+		printError(err)
+		return 2
+	}
+	transferUrl := transferResourceUrl(server, transferId)
+	respGET, respBody, err := callMQWeb("GET", transferUrl+"?attributes=*", "")
+	if err != nil {
+		logMessage("statusQueryFailed", []any{logUrl, transferUrl}, transferUrl, err)
+		return 1
+	}
+	if respGET.StatusCode != http.StatusOK {
+		logMessage("responseCodeReceived", []any{logUrl, transferUrl, logStatusCode, respGET.StatusCode}, respGET.Status)
+		return 1
+	}
+	transfer := gjson.Get(respBody, "transfer.0")
+	if !transfer.Exists() {
+		logMessage("transferIdNotFound", []any{logTransferId, transferId}, transferId)
+		return 1
+	}
+	state := transfer.Get("status.state").String()
+	if !isTerminalTransferState(state) {
+		printMessage("fixupNotEnded", transferId, state)
+		return 1
+	}
+
+	route, failed := fixupRoute(transfer)
+	route.TransferUrl = server.TransferUrl
+	if len(route.Items) == 0 {
+		printMessage("fixupNothingToDo", transferId, state)
+		return 0
+	}
+	if len(*actionIfExists) > 0 {
+		for index := range route.Items {
+			route.Items[index].ActionIfExists = *actionIfExists
+		}
+	}
+	if err := validateTransferItems(route); err != nil {
+		printError(err)
+		return 2
+	}
+	printMessage("fixupProposal", transferId, state, len(route.Items), len(transferItemResults(transfer)))
+	for _, item := range failed {
+		printMessage("fixupItem", item.Source, item.Destination, item.State, item.Description)
+	}
+	if *dryRun {
+		fmt.Println(indentRequest(buildTransferJsonRequest(withDryRunId(route))))
+		return 0
+	}
+	transferRequest := buildTransferJsonRequest(route)
+	if !*yes && !askConfirmation(message("fixupConfirm", len(route.Items))) {
+		printMessage("previewCancelled")
+		writeSubmissionResult(*resultFile, SubmissionResult{Route: route.Name, Outcome: "cancelled"})
+		return 1
+	}
+
+	printMessage("runId", runId)
+	respCode, transferStatus := submitTransfer(route.transferUrl(), transferRequest)
+	fixup := gjson.Get(transferStatus, "transfer.0")
+	outcome := submissionOutcome(respCode)
+	auditSubmission("fixup", route.Name, route.transferUrl(), transferRequest, outcome, transferStatus)
+	writeSubmissionResult(*resultFile, SubmissionResult{Route: route.Name, Outcome: outcome,
+		TransferId: fixup.Get("id").String(), State: fixup.Get("status.state").String()})
+	if respCode != http.StatusOK {
+		return exitFailed
+	}
+	return transferExitCode(fixup.Get("status.state").String())
+}
+
+/* Build the route of the corrective transfer of a transfer, with the items
+* that did not succeed. Returns the route and the results of those items.
+* transfer - Transfer as returned by the status query
+ */
+func fixupRoute(transfer gjson.Result) (Route, []ItemResult) {
+	route := Route{
+		Name:             "fixup-" + transfer.Get("id").String(),
+		SourceAgent:      transfer.Get("sourceAgent.name").String(),
+		SourceQM:         transfer.Get("sourceAgent.qmgrName").String(),
+		DestinationAgent: transfer.Get("destinationAgent.name").String(),
+		DestinationQM:    transfer.Get("destinationAgent.qmgrName").String(),
+		JobName:          transfer.Get("job.name").String(),
+		Metadata:         map[string]string{},
+	}
+	if priority := transfer.Get("transferSet.priority"); priority.Exists() {
+		value := int(priority.Int())
+		route.Priority = &value
+	}
+	for key, value := range transfer.Get("transferSet.userDefinedMetadata").Map() {
+		route.Metadata[key] = value.String()
+	}
+	route.Metadata[fixupMetadataKey] = transfer.Get("id").String()
+	route.Metadata = withRunIdMetadata(route.Metadata)
+
+	results := transferItemResults(transfer)
+	failed := []ItemResult{}
+	for index, item := range transfer.Get("transferSet.item").Array() {
+		if strings.EqualFold(results[index].State, "successful") {
+			continue
+		}
+		failed = append(failed, results[index])
+		route.Items = append(route.Items, fixupItem(item))
+		if method := item.Get("checksumMethod").String(); len(method) > 0 {
+			route.ChecksumMethod = method
+		}
+	}
+	return route, failed
+}
+
+/* Return a transfer item with the attributes of an item of a transfer.
+* item - Item as returned by the status query
+ */
+func fixupItem(item gjson.Result) TransferItem {
+	transferItem := TransferItem{
+		Source:              item.Get("source.name").String(),
+		SourceType:          item.Get("source.type").String(),
+		Destination:         item.Get("destination.name").String(),
+		DestinationType:     item.Get("destination.type").String(),
+		Mode:                item.Get("mode").String(),
+		SourceEncoding:      item.Get("source.encoding").String(),
+		DestinationEncoding: item.Get("destination.encoding").String(),
+		DestinationEOL:      item.Get("destination.endOfLine").String(),
+		SourceDisposition:   item.Get("source.disposition").String(),
+		ActionIfExists:      item.Get("destination.actionIfExists").String(),
+		MessagePersistence:  item.Get("destination.messagePersistence").String(),
+		SetMqProps:          item.Get("destination.setMqProps").Bool(),
+	}
+	if recursive := item.Get("source.recursive"); recursive.Exists() {
+		value := recursive.Bool()
+		transferItem.Recursive = &value
+	}
+	for _, pattern := range item.Get("source.exclude").Array() {
+		transferItem.Exclude = append(transferItem.Exclude, pattern.String())
+	}
+	// The status of older levels of MQ does not give the item types
+	if len(transferItem.SourceType) == 0 {
+		transferItem.SourceType = "file"
+	}
+	if len(transferItem.DestinationType) == 0 {
+		transferItem.DestinationType = "file"
+	}
+	return transferItem
+}
@@ -0,0 +1,163 @@
+/*
+© Copyright IBM Corporation 2022, 2022
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+/*
+* This file contains the failover between the MQ Web Servers of a highly
+* available MFT deployment. The MQ Web Servers are listed in the
+* configuration, the first being the primary:
+*
+*   "gateways": ["https://mqweb1.example.com:9443", "https://mqweb2.example.com:9443"]
+*
+* A request to the scheme, host and port of any of them is sent to the active
+* one instead, so that transferUrl and the URLs returned by the MQ Web Server
+* keep working after a failover. Before the first request the gateways are
+* pinged in order, with a GET of the installation resource, and the first that
+* answers becomes active. When a connection to the active gateway cannot be
+* established, the request was not sent, so it is sent again to the next
+* gateway that answers its ping, whatever its method. Any HTTP response to
+* a ping, even 401 Unauthorized, shows that the gateway is up.
+ */
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Longest time to wait for the answer to a ping of a gateway.
+const gatewayPingTimeout = 5 * time.Second
+
+// Gateways of the configuration and the one in use.
+type GatewayPool struct {
+	mutex   sync.Mutex
+	origins []string
+	active  int
+	checked bool
+}
+
+// Gateways in use, initialized from the configuration on first use.
+var gateways GatewayPool
+
+// Return the scheme, host and port of a URL, empty if it is not a URL.
+func urlOrigin(rawUrl string) string {
+	parsed, err := url.Parse(rawUrl)
+	if err != nil || len(parsed.Host) == 0 {
+		return ""
+	}
+	return strings.ToLower(parsed.Scheme + "://" + parsed.Host)
+}
+
+/* Ping a gateway. Returns nil if it answers with any HTTP response.
+* origin - Scheme, host and port of the gateway
+ */
+func pingGateway(origin string) error {
+	client, err := webClient()
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), gatewayPingTimeout)
+	defer cancel()
+	request, err := http.NewRequestWithContext(ctx, "GET", origin+"/ibmmq/rest/v2/admin/installation", nil)
+	if err != nil {
+		return err
+	}
+	response, err := client.Do(request)
+	if err != nil {
+		return err
+	}
+	response.Body.Close()
+	if response.StatusCode >= http.StatusInternalServerError {
+		return fmt.Errorf("%s", response.Status)
+	}
+	return nil
+}
+
+/* Make the first gateway answering its ping active, starting after the
+* active one. Returns false if none answers. Called with the mutex held.
+* skip - Number of gateways skipped from the active one, 0 to ping it first
+ */
+func (pool *GatewayPool) activateNext(skip int) bool {
+	for offset := skip; offset < len(pool.origins); offset++ {
+		candidate := (pool.active + offset) % len(pool.origins)
+		if err := pingGateway(pool.origins[candidate]); err != nil {
+			printMessage("gatewayDown", pool.origins[candidate], err)
+			continue
+		}
+		pool.active = candidate
+		return true
+	}
+	return false
+}
+
+/* Return the URL to which a request is sent, the URL of the active gateway
+* when the URL is that of any gateway.
+* rawUrl - URL of the request
+ */
+func (pool *GatewayPool) resolve(rawUrl string) string {
+	pool.mutex.Lock()
+	defer pool.mutex.Unlock()
+	if !pool.checked {
+		pool.checked = true
+		for _, gateway := range config.Gateways {
+			pool.origins = append(pool.origins, urlOrigin(gateway))
+		}
+		if len(pool.origins) > 1 && pool.activateNext(0) && pool.active != 0 {
+			printMessage("gatewayFailover", pool.origins[0], pool.origins[pool.active])
+		}
+	}
+	origin := urlOrigin(rawUrl)
+	if len(pool.origins) == 0 || len(origin) == 0 {
+		return rawUrl
+	}
+	for _, gateway := range pool.origins {
+		if gateway == origin {
+			parsed, _ := url.Parse(rawUrl)
+			active, _ := url.Parse(pool.origins[pool.active])
+			parsed.Scheme, parsed.Host = active.Scheme, active.Host
+			return parsed.String()
+		}
+	}
+	return rawUrl
+}
+
+/* Fail over after a connection to a gateway could not be established.
+* Returns true if the request can be sent again: another gateway answers, or
+* another request has already failed over.
+* rawUrl - URL of the request that failed, as returned by resolve
+ */
+func (pool *GatewayPool) failover(rawUrl string) bool {
+	pool.mutex.Lock()
+	defer pool.mutex.Unlock()
+	if len(pool.origins) < 2 {
+		return false
+	}
+	failed := urlOrigin(rawUrl)
+	if failed != pool.origins[pool.active] {
+		for _, gateway := range pool.origins {
+			if gateway == failed {
+				return true
+			}
+		}
+		return false
+	}
+	if !pool.activateNext(1) {
+		return false
+	}
+	printMessage("gatewayFailover", failed, pool.origins[pool.active])
+	return true
+}
@@ -1,13 +1,22 @@
 module mft-rest-submit-transfer-go
 
-go 1.18
+go 1.21
 
 require (
 	github.com/ricardolonga/jsongo v0.0.0-20161215110933-459112a8028d
 	github.com/tidwall/gjson v1.14.1
+	github.com/zalando/go-keyring v0.2.3
+	golang.org/x/net v0.17.0
+	golang.org/x/term v0.13.0
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
+	github.com/alessio/shellescape v1.4.1 // indirect
+	github.com/danieljoos/wincred v1.2.0 // indirect
+	github.com/godbus/dbus/v5 v5.1.0 // indirect
 	github.com/tidwall/match v1.1.1 // indirect
 	github.com/tidwall/pretty v1.2.0 // indirect
+	golang.org/x/sys v0.13.0 // indirect
+	golang.org/x/text v0.13.0 // indirect
 )
@@ -0,0 +1,462 @@
+/*
+© Copyright IBM Corporation 2022, 2022
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+/*
+* This file contains the HTTP client used to call the MQ Web Server. The
+* client is built once from the configuration. Proxy settings are described
+* in proxy.go.
+*
+* The TLS policy is set by the "tls" attribute of the configuration:
+*
+*   "tls": {
+*     "minVersion": "1.2",
+*     "cipherSuites": ["TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384"],
+*     "fips": false
+*   }
+*
+* minVersion is one of 1.0, 1.1, 1.2 or 1.3 and defaults to 1.2. cipherSuites
+* uses the IANA names of the suites and applies to TLS 1.2 and earlier, as
+* TLS 1.3 suites are not configurable. With fips set, only TLS 1.2 and 1.3 and
+* the FIPS 140-2 approved AES-GCM suites and NIST curves are allowed.
+*
+* The server certificate can be pinned in addition to the normal verification,
+* so that a certificate issued by a compromised CA is refused:
+*
+*   "tls": {
+*     "pinnedPublicKeys": ["base64 SHA-256 of the SubjectPublicKeyInfo"],
+*     "pinnedCertificates": ["hex SHA-256 fingerprint of the certificate"]
+*   }
+*
+* A connection is accepted when any certificate of the server chain matches
+* any pin. The public key pin is printed by
+*   openssl x509 -pubkey -noout -in cert.pem | openssl pkey -pubin -outform der |
+*   openssl dgst -sha256 -binary | base64
+* and the certificate fingerprint by
+*   openssl x509 -noout -fingerprint -sha256 -in cert.pem
+*
+* Timeouts of the requests are set by the "timeouts" attribute, as durations
+* such as "30s" or numbers of seconds. 0 disables a timeout:
+*
+*   "timeouts": {
+*     "dial": "30s",            - establishing the TCP connection
+*     "tlsHandshake": "10s",    - TLS handshake
+*     "responseHeader": "60s",  - waiting for the response after the request is sent
+*     "request": "120s"         - whole request, including reading the response
+*   }
+*
+* Connections are kept open and reused between requests. The pool is tuned by
+* the "connectionPool" attribute for programs submitting many transfers:
+*
+*   "connectionPool": {
+*     "maxIdleConns": 100,          - idle connections kept, 0 for no limit
+*     "maxIdleConnsPerHost": 10,    - idle connections kept per server
+*     "idleConnTimeout": "90s",     - time an idle connection is kept
+*     "keepAlive": "30s",           - interval of TCP keep-alive probes, -1 to disable
+*     "disableKeepAlives": false,   - use a new connection for every request
+*     "maxConnsPerHost": 0,         - connections open per server, 0 for no limit
+*     "maxInFlight": 0              - requests in progress at once, 0 for no limit
+*   }
+*
+* Some MQ Web Server deployments throttle or drop clients opening too many
+* sockets. maxConnsPerHost limits the connections of the pool, and
+* maxInFlight the requests sent at once by all the parts of the program, such
+* as the status queries of a large batch. Requests over the limit wait for
+* another to complete.
+*
+* HTTP/2 is used when the server offers it during the TLS handshake, which
+* lets the status queries of many transfers share a single connection, and
+* HTTP/1.1 otherwise. Set "forceHttp1": true or use the -http1.1 flag where a
+* proxy or load balancer breaks HTTP/2.
+*
+* Redirects are only followed for GET requests, to the same host and at most
+* maxRedirects times. An upgrade from http to https is followed, a downgrade
+* is refused. A POST is never redirected, as the Go client would otherwise
+* resend it as a GET, and redirects to other hosts, often a login page of a
+* gateway, are refused. Each redirect followed is reported with the chain of
+* URLs so that transferUrl can be corrected.
+*
+* The -insecure-skip-verify flag disables verification of the server
+* certificate for development and test systems using self-signed certificates.
+* It must never be used in production: credentials are sent to whichever server
+* answers.
+ */
+package main
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TLS settings of the connection to the MQ Web Server.
+type TLSPolicy struct {
+	MinVersion         string   `json:"minVersion"`
+	CipherSuites       []string `json:"cipherSuites"`
+	FIPS               bool     `json:"fips"`
+	PinnedPublicKeys   []string `json:"pinnedPublicKeys"`
+	PinnedCertificates []string `json:"pinnedCertificates"`
+}
+
+// Timeouts of requests to the MQ Web Server.
+type HTTPTimeouts struct {
+	Dial           Duration `json:"dial"`
+	TLSHandshake   Duration `json:"tlsHandshake"`
+	ResponseHeader Duration `json:"responseHeader"`
+	Request        Duration `json:"request"`
+}
+
+// Returns the default timeouts of requests to the MQ Web Server.
+func defaultHTTPTimeouts() HTTPTimeouts {
+	return HTTPTimeouts{
+		Dial:           Duration(30 * time.Second),
+		TLSHandshake:   Duration(10 * time.Second),
+		ResponseHeader: Duration(60 * time.Second),
+		Request:        Duration(120 * time.Second),
+	}
+}
+
+// Settings of the pool of connections to the MQ Web Server.
+type ConnectionPool struct {
+	MaxIdleConns        int      `json:"maxIdleConns"`
+	MaxIdleConnsPerHost int      `json:"maxIdleConnsPerHost"`
+	IdleConnTimeout     Duration `json:"idleConnTimeout"`
+	KeepAlive           Duration `json:"keepAlive"`
+	DisableKeepAlives   bool     `json:"disableKeepAlives"`
+	MaxConnsPerHost     int      `json:"maxConnsPerHost"`
+	MaxInFlight         int      `json:"maxInFlight"`
+}
+
+// Returns the default settings of the connection pool.
+func defaultConnectionPool() ConnectionPool {
+	return ConnectionPool{
+		MaxIdleConns:        100,
+		MaxIdleConnsPerHost: 10,
+		IdleConnTimeout:     Duration(90 * time.Second),
+		KeepAlive:           Duration(30 * time.Second),
+	}
+}
+
+// TLS versions by name.
+var tlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// Cipher suites approved for FIPS 140-2.
+var fipsCipherSuites = []uint16{
+	tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
+	tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,
+	tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+	tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
+	tls.TLS_RSA_WITH_AES_128_GCM_SHA256,
+	tls.TLS_RSA_WITH_AES_256_GCM_SHA384,
+}
+
+// Maximum number of redirects followed for a request.
+const maxRedirects = 5
+
+// Client shared by all requests to the MQ Web Server. Built by webClient.
+var httpClient *http.Client
+
+// Return the client used for requests to the MQ Web Server.
+func webClient() (*http.Client, error) {
+	if httpClient != nil {
+		return httpClient, nil
+	}
+	tlsConfig, err := buildTLSConfig(config.TLS)
+	if err != nil {
+		return nil, err
+	}
+	if insecureSkipVerify {
+		banner := strings.Repeat("*", 78)
+		fmt.Fprintf(os.Stderr, "%s\n%s\n%s\n", banner, message("insecureSkipVerifyWarning"), banner)
+		tlsConfig.InsecureSkipVerify = true
+	}
+	proxy, err := proxySelector(config.Proxy)
+	if err != nil {
+		return nil, err
+	}
+	timeouts, pool := config.Timeouts, config.ConnectionPool
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+	transport.Proxy = proxy
+	transport.DialContext = (&net.Dialer{
+		Timeout:   time.Duration(timeouts.Dial),
+		KeepAlive: time.Duration(pool.KeepAlive),
+	}).DialContext
+	transport.MaxIdleConns = pool.MaxIdleConns
+	transport.MaxIdleConnsPerHost = pool.MaxIdleConnsPerHost
+	transport.IdleConnTimeout = time.Duration(pool.IdleConnTimeout)
+	transport.DisableKeepAlives = pool.DisableKeepAlives
+	transport.MaxConnsPerHost = pool.MaxConnsPerHost
+	transport.TLSHandshakeTimeout = time.Duration(timeouts.TLSHandshake)
+	transport.ResponseHeaderTimeout = time.Duration(timeouts.ResponseHeader)
+	if forceHTTP1 || config.ForceHTTP1 {
+		// A non-nil empty map disables the HTTP/2 upgrade of TLS connections
+		transport.ForceAttemptHTTP2 = false
+		transport.TLSNextProto = map[string]func(string, *tls.Conn) http.RoundTripper{}
+	} else {
+		transport.ForceAttemptHTTP2 = true
+	}
+	roundTripper, err := traceTransport(transport)
+	if err != nil {
+		return nil, err
+	}
+	httpClient = &http.Client{
+		Transport:     roundTripper,
+		Timeout:       time.Duration(timeouts.Request),
+		CheckRedirect: checkRedirect,
+	}
+	return httpClient, nil
+}
+
+/* Issue a HTTP request to the MQ Web Server and read the response body.
+* Requests failing with a transient error are retried, see retry.go. The
+* responses to GET requests are cached for a short time, see cache.go.
+* httpVerb - Value can be GET, POST or DELETE
+* url      - Url to which request will be submitted
+* body     - Body of the request to be sent
+ */
+func callMQWeb(httpVerb string, url string, body string) (*http.Response, string, error) {
+	if httpVerb != "GET" {
+		webResponses.invalidate(url)
+	} else if response, respBody, found := webResponses.get(url); found {
+		return response, respBody, nil
+	}
+	response, respBody, err := callMQWebUncached(httpVerb, url, body)
+	if httpVerb == "GET" && err == nil {
+		webResponses.put(url, response, respBody)
+	}
+	return response, respBody, err
+}
+
+// Issue a HTTP request to the MQ Web Server, retrying transient errors.
+func callMQWebUncached(httpVerb string, url string, body string) (*http.Response, string, error) {
+	password, err := webPassword()
+	if err != nil {
+		return nil, "", err
+	}
+	client, err := webClient()
+	if err != nil {
+		return nil, "", err
+	}
+	policy := config.Retry
+	failovers := 0
+	for attempt := 1; ; attempt++ {
+		target := gateways.resolve(url)
+		release := acquireInFlight()
+		response, respBody, err := sendMQWebRequest(client, httpVerb, target, body, password)
+		release()
+		// The request was not sent, send it to another gateway, see gateway.go
+		if err != nil && isConnectError(err) && failovers < len(config.Gateways) && gateways.failover(target) {
+			failovers++
+			attempt--
+			continue
+		}
+		if attempt >= policy.MaxAttempts || !isRetryable(httpVerb, response, err) {
+			return response, respBody, err
+		}
+		reason := ""
+		if err != nil {
+			reason = err.Error()
+		} else {
+			reason = response.Status
+		}
+		delay, retry := policy.nextDelay(attempt, response)
+		if !retry {
+			logMessage("retryAfterTooLong", []any{logUrl, url}, httpVerb, url, delay, time.Duration(policy.MaxRetryAfter))
+			return response, respBody, err
+		}
+		logMessage("requestRetry", []any{logUrl, url}, httpVerb, url, reason, delay.Round(time.Millisecond), attempt+1, policy.MaxAttempts)
+		time.Sleep(delay)
+	}
+}
+
+// Slots of the requests in progress, nil for no limit.
+var inFlightRequests chan struct{}
+var inFlightOnce sync.Once
+
+// Wait until a request can be sent without exceeding maxInFlight. Returns the
+// function releasing the slot of the request.
+func acquireInFlight() func() {
+	inFlightOnce.Do(func() {
+		if config.ConnectionPool.MaxInFlight > 0 {
+			inFlightRequests = make(chan struct{}, config.ConnectionPool.MaxInFlight)
+		}
+	})
+	if inFlightRequests == nil {
+		return func() {}
+	}
+	inFlightRequests <- struct{}{}
+	return func() { <-inFlightRequests }
+}
+
+// Send a single HTTP request to the MQ Web Server and read the response body.
+func sendMQWebRequest(client *http.Client, httpVerb string, url string, body string, password string) (*http.Response, string, error) {
+	httpRequest, err := buildHTTPRequestHeader(httpVerb, url, body, config.UserId, password)
+	if err != nil {
+		return nil, "", err
+	}
+	logMessage("httpRequest", []any{logUrl, url}, httpVerb, url)
+	logHttpDetails("httpRequestDetails", url, httpRequest.Header, body)
+	response, err := client.Do(httpRequest)
+	if err != nil {
+		return nil, "", err
+	}
+	defer response.Body.Close()
+	logMessage("httpResponse", []any{logUrl, url, logStatusCode, response.StatusCode}, httpVerb, url, response.Status)
+	respBody, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return nil, "", fmt.Errorf("unable to read response: %v", err)
+	}
+	logHttpDetails("httpResponseDetails", url, response.Header, string(respBody))
+	return response, string(respBody), nil
+}
+
+/* Decide whether a redirect is followed. Returns an error explaining why a
+* redirect is refused.
+* request - Request to the location of the redirect
+* via     - Requests already made, oldest first
+ */
+func checkRedirect(request *http.Request, via []*http.Request) error {
+	original := via[0]
+	chain := []string{}
+	for _, previous := range via {
+		chain = append(chain, previous.URL.String())
+	}
+	chain = append(chain, request.URL.String())
+	redirect := strings.Join(chain, " -> ")
+	status := ""
+	if request.Response != nil {
+		status = request.Response.Status
+	}
+
+	switch {
+	case original.Method != "GET" && original.Method != "HEAD":
+		return fmt.Errorf("%s redirect of %s refused: %s. The request was not sent to the new location, set the URL to it", status, original.Method, redirect)
+	case len(via) > maxRedirects:
+		return fmt.Errorf("more than %d redirects: %s", maxRedirects, redirect)
+	case request.URL.Scheme == "http" && original.URL.Scheme == "https":
+		return fmt.Errorf("%s redirect from https to http refused: %s", status, redirect)
+	case request.URL.Hostname() != original.URL.Hostname():
+		return fmt.Errorf("%s redirect to another host refused, it may be the login page of a gateway: %s", status, redirect)
+	}
+	logMessage("redirectFollowed", []any{logUrl, redirect}, status, redirect)
+	return nil
+}
+
+/* Build the TLS configuration for a policy.
+* policy - TLS policy from the configuration
+ */
+func buildTLSConfig(policy TLSPolicy) (*tls.Config, error) {
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+	if len(policy.MinVersion) > 0 {
+		version, found := tlsVersions[policy.MinVersion]
+		if !found {
+			return nil, fmt.Errorf("unsupported TLS version %s", policy.MinVersion)
+		}
+		tlsConfig.MinVersion = version
+	}
+
+	if len(policy.CipherSuites) > 0 {
+		suites := map[string]uint16{}
+		for _, suite := range append(tls.CipherSuites(), tls.InsecureCipherSuites()...) {
+			suites[suite.Name] = suite.ID
+		}
+		for _, name := range policy.CipherSuites {
+			id, found := suites[strings.TrimSpace(name)]
+			if !found {
+				return nil, fmt.Errorf("unsupported cipher suite %s", name)
+			}
+			tlsConfig.CipherSuites = append(tlsConfig.CipherSuites, id)
+		}
+	}
+
+	if policy.FIPS {
+		if tlsConfig.MinVersion < tls.VersionTLS12 {
+			return nil, fmt.Errorf("TLS %s is not allowed in FIPS mode", policy.MinVersion)
+		}
+		if len(tlsConfig.CipherSuites) == 0 {
+			tlsConfig.CipherSuites = fipsCipherSuites
+		}
+		for _, id := range tlsConfig.CipherSuites {
+			if !isFipsCipherSuite(id) {
+				return nil, fmt.Errorf("cipher suite %s is not allowed in FIPS mode", tls.CipherSuiteName(id))
+			}
+		}
+		tlsConfig.CurvePreferences = []tls.CurveID{tls.CurveP256, tls.CurveP384, tls.CurveP521}
+	}
+
+	if len(policy.PinnedPublicKeys) > 0 || len(policy.PinnedCertificates) > 0 {
+		verifyPins, err := certificatePinVerifier(policy)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.VerifyConnection = verifyPins
+	}
+	return tlsConfig, nil
+}
+
+/* Return a function checking that the server chain matches a pin.
+* policy - TLS policy with the pins
+ */
+func certificatePinVerifier(policy TLSPolicy) (func(tls.ConnectionState) error, error) {
+	publicKeyPins := map[string]bool{}
+	for _, pin := range policy.PinnedPublicKeys {
+		hash, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(strings.TrimSpace(pin), "sha256/"))
+		if err != nil || len(hash) != sha256.Size {
+			return nil, fmt.Errorf("invalid public key pin %s", pin)
+		}
+		publicKeyPins[string(hash)] = true
+	}
+	certificatePins := map[string]bool{}
+	for _, pin := range policy.PinnedCertificates {
+		hash, err := hex.DecodeString(strings.ReplaceAll(strings.TrimSpace(pin), ":", ""))
+		if err != nil || len(hash) != sha256.Size {
+			return nil, fmt.Errorf("invalid certificate pin %s", pin)
+		}
+		certificatePins[string(hash)] = true
+	}
+
+	return func(state tls.ConnectionState) error {
+		for _, certificate := range state.PeerCertificates {
+			publicKeyHash := sha256.Sum256(certificate.RawSubjectPublicKeyInfo)
+			certificateHash := sha256.Sum256(certificate.Raw)
+			if publicKeyPins[string(publicKeyHash[:])] || certificatePins[string(certificateHash[:])] {
+				return nil
+			}
+		}
+		return fmt.Errorf("certificate of %s does not match any pinned public key or certificate", state.ServerName)
+	}, nil
+}
+
+// Check if a cipher suite is approved for FIPS 140-2.
+func isFipsCipherSuite(id uint16) bool {
+	for _, fipsId := range fipsCipherSuites {
+		if id == fipsId {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,184 @@
+/*
+© Copyright IBM Corporation 2022, 2022
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+/*
+* This file contains the integrity checks made by this program, in addition
+* to the checksums computed by the agents. The checks use the algorithm set by
+* "integrityAlgorithm" in the configuration, SHA-256 by default, or SHA-512 or
+* MD5:
+*   - before submission, a source file whose expected checksum is given by
+*     the "checksum" of its item, or the checksum column of a manifest, is
+*     checked and the transfer is not submitted if it differs.
+*   - after a successful transfer, with the -verify flag of the submit
+*     command, the checksums of the source and destination files of each file
+*     item are compared.
+*
+* Only files accessible from the host running this program can be checked;
+* other items are reported as not verified. Algorithms are implementations of
+* IntegrityAlgorithm registered in integrityAlgorithms.
+ */
+package main
+
+import (
+	"crypto/md5"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"sort"
+	"strings"
+)
+
+// Default algorithm of the integrity checks.
+const defaultIntegrityAlgorithm = "SHA-256"
+
+// An algorithm computing the checksum of the content of a file.
+type IntegrityAlgorithm interface {
+	// Name of the algorithm, as set in the configuration
+	Name() string
+	// Checksum of the content read from reader, in lower case hexadecimal
+	Sum(reader io.Reader) (string, error)
+}
+
+// Integrity algorithm based on a hash function of the standard library.
+type hashAlgorithm struct {
+	name    string
+	newHash func() hash.Hash
+}
+
+func (algorithm hashAlgorithm) Name() string {
+	return algorithm.name
+}
+
+func (algorithm hashAlgorithm) Sum(reader io.Reader) (string, error) {
+	digest := algorithm.newHash()
+	if _, err := io.Copy(digest, reader); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(digest.Sum(nil)), nil
+}
+
+// Integrity algorithms, by upper case name.
+var integrityAlgorithms = map[string]IntegrityAlgorithm{
+	"MD5":     hashAlgorithm{"MD5", md5.New},
+	"SHA-256": hashAlgorithm{"SHA-256", sha256.New},
+	"SHA-512": hashAlgorithm{"SHA-512", sha512.New},
+}
+
+// Return the integrity algorithm set in the configuration.
+func integrityAlgorithm() (IntegrityAlgorithm, error) {
+	name := config.IntegrityAlgorithm
+	if len(name) == 0 {
+		name = defaultIntegrityAlgorithm
+	}
+	if algorithm, found := integrityAlgorithms[strings.ToUpper(name)]; found {
+		return algorithm, nil
+	}
+	names := []string{}
+	for name := range integrityAlgorithms {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return nil, fmt.Errorf("integrity algorithm %s is not supported, use one of %s", name, strings.Join(names, ", "))
+}
+
+/* Compute the checksum of a file.
+* algorithm - Integrity algorithm
+* fileName  - File to read
+ */
+func fileChecksum(algorithm IntegrityAlgorithm, fileName string) (string, error) {
+	file, err := os.Open(fileName)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	return algorithm.Sum(file)
+}
+
+/* Check the sources of a route against the expected checksums of its items.
+* Returns an error naming every source that differs or cannot be read.
+* route - Route of the transfer
+ */
+func verifySourceChecksums(route Route) error {
+	var algorithm IntegrityAlgorithm
+	problems := []string{}
+	for _, item := range route.transferItems() {
+		if len(item.Checksum) == 0 {
+			continue
+		}
+		if algorithm == nil {
+			var err error
+			if algorithm, err = integrityAlgorithm(); err != nil {
+				return err
+			}
+		}
+		if route.hasRemoteSources() || len(localSourcePaths(item.Source)) == 0 || hasWildcard(item.Source) {
+			printMessage("integrityNotVerified", item.Source)
+			continue
+		}
+		checksum, err := fileChecksum(algorithm, item.Source)
+		if err != nil {
+			problems = append(problems, err.Error())
+		} else if !strings.EqualFold(checksum, item.Checksum) {
+			problems = append(problems, fmt.Sprintf("%s checksum of %s is %s, expected %s", algorithm.Name(), item.Source, checksum, item.Checksum))
+		}
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("%s", strings.Join(problems, "\n"))
+	}
+	return nil
+}
+
+/* Compare the checksums of the source and destination files of the file items
+* of a transfer. Returns false if a file differs or cannot be read.
+* route - Route of the transfer
+ */
+func verifyTransferredFiles(route Route) bool {
+	algorithm, err := integrityAlgorithm()
+	if err != nil {
+		printMessage("integrityCheckFailed", err)
+		return false
+	}
+	verified := true
+	for _, item := range route.transferItems() {
+		if item.SourceType != "file" || item.DestinationType != "file" || hasWildcard(item.Source) {
+			printMessage("integrityNotFileItem", item.Source)
+			continue
+		}
+		if _, err := os.Stat(item.Source); err != nil || route.hasRemoteSources() {
+			printMessage("integrityNotVerified", item.Source)
+			continue
+		}
+		if _, err := os.Stat(item.Destination); err != nil || route.DestinationBridge != nil || route.DestinationConnectDirect != nil {
+			printMessage("integrityNotVerified", item.Source)
+			continue
+		}
+		sourceChecksum, err := fileChecksum(algorithm, item.Source)
+		if err == nil {
+			var destinationChecksum string
+			if destinationChecksum, err = fileChecksum(algorithm, item.Destination); err == nil && sourceChecksum != destinationChecksum {
+				err = fmt.Errorf("%s checksums differ: %s, %s", algorithm.Name(), sourceChecksum, destinationChecksum)
+			}
+		}
+		if err != nil {
+			printMessage("integrityMismatch", item.Source, item.Destination, err)
+			verified = false
+			continue
+		}
+		printMessage("integrityVerified", item.Source, item.Destination, algorithm.Name(), sourceChecksum)
+	}
+	return verified
+}
@@ -0,0 +1,166 @@
+/*
+© Copyright IBM Corporation 2022, 2022
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+/*
+* This file contains the JUnit XML reports of -junit on "submit" and "batch",
+* so that CI pipelines orchestrating transfers show their results natively.
+* Each transfer is a test suite named after its route, and each of its items a
+* test case named "SOURCE -> DESTINATION" that fails, with the state of the
+* item as type and its description as message, unless the item is successful:
+*
+*   <testsuites tests="2" failures="1">
+*     <testsuite name="nightly" tests="2" failures="1" time="5.000" id="414D...0708">
+*       <testcase classname="nightly" name="/in/a -> /out/a"></testcase>
+*       <testcase classname="nightly" name="/in/b -> /out/b">
+*         <failure message="BFGIO0001E: File not found" type="failed"></failure>
+*       </testcase>
+*     </testsuite>
+*   </testsuites>
+*
+* A transfer whose items are not known, for instance because it could not be
+* submitted, is a single test case named after the transfer ID or the route.
+* A scheduled transfer, not run yet, is skipped.
+ */
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/tidwall/gjson"
+)
+
+// Root element of a JUnit XML report.
+type JUnitReport struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Skipped  int              `xml:"skipped,attr"`
+	Suites   []JUnitTestSuite `xml:"testsuite"`
+}
+
+// Test suite of a JUnit XML report, one per transfer.
+type JUnitTestSuite struct {
+	Name      string          `xml:"name,attr"`
+	Id        string          `xml:"id,attr,omitempty"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	Skipped   int             `xml:"skipped,attr"`
+	Time      string          `xml:"time,attr"`
+	Timestamp string          `xml:"timestamp,attr,omitempty"`
+	Cases     []JUnitTestCase `xml:"testcase"`
+}
+
+// Test case of a JUnit XML report, one per transfer item.
+type JUnitTestCase struct {
+	ClassName string        `xml:"classname,attr"`
+	Name      string        `xml:"name,attr"`
+	Failure   *JUnitFailure `xml:"failure,omitempty"`
+	Skipped   *JUnitFailure `xml:"skipped,omitempty"`
+}
+
+// Failure or reason for skipping of a test case.
+type JUnitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr,omitempty"`
+}
+
+/* Return the test suite of a transfer.
+* route          - Name of the route of the transfer
+* state          - Final state of the transfer, empty or notSubmitted if it
+*                  was not submitted
+* transferStatus - Last response of the transfer status query, if any
+* duration       - Duration measured by the caller, used when the statistics
+*                  of the transfer do not give it
+ */
+func transferTestSuite(route string, state string, transferStatus string, duration time.Duration) JUnitTestSuite {
+	transfer := gjson.Get(transferStatus, "transfer.0")
+	suite := JUnitTestSuite{Name: route, Id: transfer.Get("id").String()}
+	if startTime, err := time.Parse(time.RFC3339, transfer.Get("statistics.startTime").String()); err == nil {
+		suite.Timestamp = startTime.UTC().Format("2006-01-02T15:04:05")
+		endTime, err := time.Parse(time.RFC3339, transfer.Get("statistics.endTime").String())
+		if err == nil && endTime.After(startTime) {
+			duration = endTime.Sub(startTime)
+		}
+	}
+	suite.Time = fmt.Sprintf("%.3f", duration.Seconds())
+	for _, item := range transferItemResults(transfer) {
+		testCase := JUnitTestCase{ClassName: route, Name: item.Source + " -> " + item.Destination}
+		if !strings.EqualFold(item.State, "successful") {
+			description := item.Description
+			if len(description) == 0 {
+				description = message("junitItemNotSuccessful", item.State)
+			}
+			testCase.Failure = &JUnitFailure{Message: description, Type: item.State}
+		}
+		suite.add(testCase)
+	}
+	if len(suite.Cases) > 0 {
+		return suite
+	}
+	testCase := JUnitTestCase{ClassName: route, Name: route}
+	if len(suite.Id) > 0 {
+		testCase.Name = suite.Id
+	}
+	switch {
+	case state == "scheduled":
+		testCase.Skipped = &JUnitFailure{Message: message("junitScheduled")}
+	case len(state) == 0 || state == "notSubmitted":
+		testCase.Failure = &JUnitFailure{Message: message("junitNotSubmitted"), Type: "notSubmitted"}
+	case !strings.EqualFold(state, "successful"):
+		testCase.Failure = &JUnitFailure{Message: message("junitTransferNotSuccessful", state), Type: state}
+	}
+	suite.add(testCase)
+	return suite
+}
+
+// Add a test case to a test suite and count it.
+func (suite *JUnitTestSuite) add(testCase JUnitTestCase) {
+	suite.Cases = append(suite.Cases, testCase)
+	suite.Tests++
+	if testCase.Failure != nil {
+		suite.Failures++
+	}
+	if testCase.Skipped != nil {
+		suite.Skipped++
+	}
+}
+
+/* Write a JUnit XML report. Errors are printed and do not change the outcome
+* of the command.
+* fileName - XML file to write, nothing is written when empty
+* suites   - Test suites of the report, one per transfer
+ */
+func writeJUnitReport(fileName string, suites []JUnitTestSuite) {
+	if len(fileName) == 0 {
+		return
+	}
+	report := JUnitReport{Suites: suites}
+	for _, suite := range suites {
+		report.Tests += suite.Tests
+		report.Failures += suite.Failures
+		report.Skipped += suite.Skipped
+	}
+	content, err := xml.MarshalIndent(report, "", "  ")
+	if err == nil {
+		err = os.WriteFile(fileName, append([]byte(xml.Header), append(content, '\n')...), 0644)
+	}
+	if err != nil {
+		printMessage("junitWriteFailed", fileName, err)
+		return
+	}
+	printMessage("junitWritten", report.Tests, fileName)
+}
@@ -0,0 +1,267 @@
+/*
+© Copyright IBM Corporation 2022, 2022
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+/*
+* This file contains the logging of the program with log/slog. Every message
+* of the catalog is logged at a level, information unless messageLevels
+* says otherwise, with the key of the message and, where known, the fields
+* transferID, url and statusCode. The level and format are set in the
+* configuration or with -log-level and -log-format:
+*
+*   "logging": { "level": "debug", "format": "json", "file": "mft.log" }
+*
+* The levels are debug, info (the default), warn and error. The formats are:
+*   - plain, the default: the text of the message on standard output, as
+*     printed by the program before it used slog
+*   - text: key=value records of slog.TextHandler
+*   - json: JSON records of slog.JSONHandler, for log platforms
+*
+* The text and json records are written to the "file" of the configuration,
+* or to standard error so that the tables, CSV and JSON that commands print
+* on standard output stay separate. At debug level each request to the MQ
+* Web Server and its response are logged too.
+*
+* -q, -v and -vv set the verbosity for scripting and debugging, overriding
+* the level. -q logs only the errors, and "submit" and "batch" then print
+* just the ID and final state of each transfer. -v logs at debug level, and
+* -vv also logs the headers and bodies of the requests and responses. Secrets
+* are masked in every record, see redact.go.
+ */
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+)
+
+// Names of the fields of the log records.
+const (
+	logKey        = "key"
+	logTransferId = "transferID"
+	logUrl        = "url"
+	logStatusCode = "statusCode"
+)
+
+// Logging settings of the configuration.
+type LoggingSettings struct {
+	Level  string `json:"level"`
+	Format string `json:"format"`
+	File   string `json:"file"`
+}
+
+// Values of -log-level and -log-format, overriding the configuration.
+var logLevelFlag, logFormatFlag string
+
+// Values of -q, -v and -vv.
+var quietFlag, verboseFlag, veryVerboseFlag bool
+
+// Logger of the program, printing plain messages until the configuration is
+// loaded.
+var logger = slog.New(redactingHandler{next: &plainHandler{out: os.Stdout, level: slog.LevelInfo}})
+
+// Levels of the messages that are not information. Messages whose key ends
+// with Failed or Usage are errors.
+var messageLevels = map[string]slog.Level{
+	"agentHealthWarning":           slog.LevelWarn,
+	"agentMenuInvalid":             slog.LevelWarn,
+	"agentNotFound":                slog.LevelError,
+	"baselineAnomaly":              slog.LevelWarn,
+	"canaryCritical":               slog.LevelError,
+	"canaryNotRemoved":             slog.LevelWarn,
+	"canaryUnknown":                slog.LevelWarn,
+	"cancelTimedOut":               slog.LevelWarn,
+	"credentialsKeyInsecure":       slog.LevelWarn,
+	"credentialsUnknownAction":     slog.LevelError,
+	"gatewayDown":                  slog.LevelWarn,
+	"gatewayFailover":              slog.LevelWarn,
+	"heldNotFound":                 slog.LevelError,
+	"httpRequest":                  slog.LevelDebug,
+	"httpRequestDetails":           slog.LevelDebug,
+	"httpResponse":                 slog.LevelDebug,
+	"httpResponseDetails":          slog.LevelDebug,
+	"integrityMismatch":            slog.LevelError,
+	"integrityNotVerified":         slog.LevelWarn,
+	"integrityTransferNotComplete": slog.LevelError,
+	"itemChecksumMismatch":         slog.LevelError,
+	"onboardInvalid":               slog.LevelError,
+	"pollTimedOut":                 slog.LevelWarn,
+	"pollTimedOutPending":          slog.LevelWarn,
+	"requestFieldDeprecated":       slog.LevelWarn,
+	"requestFieldMappingInvalid":   slog.LevelError,
+	"requestRetry":                 slog.LevelWarn,
+	"responseCodeReceived":         slog.LevelError,
+	"retryAfterTooLong":            slog.LevelWarn,
+	"syslogFailed":                 slog.LevelWarn,
+	"templateExists":               slog.LevelError,
+	"templateNameInvalid":          slog.LevelError,
+	"templateNotFound":             slog.LevelError,
+	"transferErrors":               slog.LevelError,
+	"transferIdNotFound":           slog.LevelError,
+	"transferNotFound":             slog.LevelError,
+	"unknownCommand":               slog.LevelError,
+	"webhookFailed":                slog.LevelWarn,
+	"webhookNotified":              slog.LevelDebug,
+	"webhookRetry":                 slog.LevelWarn,
+}
+
+// Handler printing the text of the records, without their level and fields.
+type plainHandler struct {
+	out   io.Writer
+	level slog.Level
+}
+
+func (handler *plainHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return level >= handler.level
+}
+
+func (handler *plainHandler) Handle(ctx context.Context, record slog.Record) error {
+	_, err := fmt.Fprintln(handler.out, record.Message)
+	return err
+}
+
+func (handler *plainHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return handler
+}
+
+func (handler *plainHandler) WithGroup(name string) slog.Handler {
+	return handler
+}
+
+// Return the level of a message of the catalog.
+func messageLevel(key string) slog.Level {
+	if level, found := messageLevels[key]; found {
+		return level
+	}
+	if strings.HasSuffix(key, "Failed") || strings.HasSuffix(key, "Usage") {
+		return slog.LevelError
+	}
+	return slog.LevelInfo
+}
+
+/* Parse a log level.
+* value - debug, info, warn or error
+ */
+func parseLogLevel(value string) (slog.Level, error) {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(value)); err != nil {
+		return level, fmt.Errorf("%s", message("logLevelInvalid", value))
+	}
+	return level, nil
+}
+
+/* Set up the logger from the configuration and the command line. Returns an
+* error if a setting is not valid or the log file cannot be opened.
+* settings - Logging settings of the configuration
+ */
+func configureLogging(settings LoggingSettings) error {
+	if len(logLevelFlag) > 0 {
+		settings.Level = logLevelFlag
+	}
+	if len(logFormatFlag) > 0 {
+		settings.Format = logFormatFlag
+	}
+	level := slog.LevelInfo
+	if len(settings.Level) > 0 {
+		var err error
+		if level, err = parseLogLevel(settings.Level); err != nil {
+			return err
+		}
+	}
+	switch {
+	case quietFlag && (verboseFlag || veryVerboseFlag):
+		return fmt.Errorf("%s", message("quietVerboseConflict"))
+	case quietFlag:
+		level = slog.LevelError
+	case verboseFlag || veryVerboseFlag:
+		level = slog.LevelDebug
+	}
+	var out io.Writer = os.Stderr
+	if len(settings.File) > 0 && settings.Format != "" && settings.Format != "plain" {
+		file, err := os.OpenFile(settings.File, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+		if err != nil {
+			return err
+		}
+		out = file
+	}
+	options := &slog.HandlerOptions{Level: level}
+	var handler slog.Handler
+	switch settings.Format {
+	case "", "plain":
+		handler = &plainHandler{out: os.Stdout, level: level}
+	case "text":
+		handler = slog.NewTextHandler(out, options)
+	case "json":
+		handler = slog.NewJSONHandler(out, options)
+	default:
+		return fmt.Errorf("%s", message("logFormatInvalid", settings.Format))
+	}
+	logger = slog.New(redactingHandler{next: handler})
+	return nil
+}
+
+/* Log a message of the catalog at its level, with fields.
+* key    - Key of the message
+* fields - Alternating names and values of fields such as logUrl
+* args   - Arguments of the format verbs in the message
+ */
+func logMessage(key string, fields []any, args ...interface{}) {
+	level := messageLevel(key)
+	if !logger.Enabled(context.Background(), level) {
+		return
+	}
+	logger.Log(context.Background(), level, message(key, args...), append([]any{logKey, key}, fields...)...)
+}
+
+// Log an error that is not a message of the catalog.
+func printError(err error) {
+	logger.Error(err.Error())
+}
+
+/* Log the headers and body of a request or response with -vv.
+* key    - Key of the message, httpRequestDetails or httpResponseDetails
+* url    - URL of the request
+* header - Headers of the request or response
+* body   - Body of the request or response
+ */
+func logHttpDetails(key string, url string, header http.Header, body string) {
+	if !veryVerboseFlag {
+		return
+	}
+	names := []string{}
+	for name := range header {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	lines := []string{}
+	for _, name := range names {
+		lines = append(lines, name+": "+strings.Join(header.Values(name), ", "))
+	}
+	logMessage(key, []any{logUrl, url}, url, strings.Join(lines, "\n"), body)
+}
+
+/* Print the ID and final state of a transfer with -q, the only output of
+* a successful transfer.
+* transferId - ID of the transfer
+* state      - Final state of the transfer
+ */
+func printQuietResult(transferId string, state string) {
+	if quietFlag {
+		fmt.Println(transferId, state)
+	}
+}
@@ -0,0 +1,361 @@
+/*
+© Copyright IBM Corporation 2022, 2022
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+/*
+* This file contains the support for maintenance windows. A maintenance
+* window applies to a route or to an agent. Transfer requests submitted
+* while a window is active are not sent to the MQ Web Server. They are held
+* in the "held" directory under the state directory and are released by the
+* next run of the program after the window has ended, or explicitly with the
+* "release" command. The "held" command lists the held transfer requests.
+*
+* The same local queue holds transfers whose source looks anomalous, see
+* baseline.go. Those are only released by the "release" command after review.
+*
+* Window start and end are either a time of day (HH:MM, local time) which
+* applies on the listed days, or a date and time in RFC 3339 format for a
+* one-off window. A daily window whose end is before its start runs past
+* midnight.
+ */
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tidwall/gjson"
+)
+
+// A period during which transfers of a route or agent are held.
+type MaintenanceWindow struct {
+	Route       string   `json:"route"`
+	Agent       string   `json:"agent"`
+	Days        []string `json:"days"`
+	Start       string   `json:"start"`
+	End         string   `json:"end"`
+	Description string   `json:"description"`
+}
+
+// A transfer request held locally instead of being submitted.
+type HeldTransfer struct {
+	Id             string    `json:"id"`
+	Route          string    `json:"route"`
+	Reason         string    `json:"reason"`
+	HeldAt         time.Time `json:"heldAt"`
+	ReleaseAfter   time.Time `json:"releaseAfter"`
+	ReviewRequired bool      `json:"reviewRequired"`
+	Request        string    `json:"request"`
+	TransferUrl    string    `json:"transferUrl,omitempty"`
+	RunId          string    `json:"runId,omitempty"`
+	Snapshot       *Snapshot `json:"snapshot,omitempty"`
+
+	// Set when the transfer is released and archived, see retention.go
+	ReleasedAt    *time.Time `json:"releasedAt,omitempty"`
+	ReleaseResult string     `json:"releaseResult,omitempty"`
+}
+
+// Layout of the time of day in a daily maintenance window.
+const timeOfDayLayout = "15:04"
+
+/* Return the end of the maintenance window active for a route at the
+* given time. The boolean return value is false if no window is active.
+* route - Route of the transfer
+* now   - Time to check
+ */
+func activeMaintenanceWindow(route Route, now time.Time) (MaintenanceWindow, time.Time, bool) {
+	for _, window := range config.MaintenanceWindows {
+		if !window.appliesTo(route) {
+			continue
+		}
+		if end, active := window.activeAt(now); active {
+			return window, end, true
+		}
+	}
+	return MaintenanceWindow{}, time.Time{}, false
+}
+
+// Check if the window applies to the route or one of its agents.
+func (window MaintenanceWindow) appliesTo(route Route) bool {
+	if len(window.Route) > 0 && window.Route != route.Name {
+		return false
+	}
+	if len(window.Agent) > 0 && !strings.EqualFold(window.Agent, route.SourceAgent) &&
+		!strings.EqualFold(window.Agent, route.DestinationAgent) {
+		return false
+	}
+	return true
+}
+
+// Check if the window is active at the given time and return its end.
+func (window MaintenanceWindow) activeAt(now time.Time) (time.Time, bool) {
+	// One-off window
+	if start, err := time.Parse(time.RFC3339, window.Start); err == nil {
+		end, err := time.Parse(time.RFC3339, window.End)
+		if err != nil {
+			return time.Time{}, false
+		}
+		return end, !now.Before(start) && now.Before(end)
+	}
+
+	// Daily window, which may have started the day before
+	start, errStart := time.Parse(timeOfDayLayout, window.Start)
+	end, errEnd := time.Parse(timeOfDayLayout, window.End)
+	if errStart != nil || errEnd != nil {
+		return time.Time{}, false
+	}
+	for _, dayOffset := range []int{0, -1} {
+		day := now.AddDate(0, 0, dayOffset)
+		if !window.appliesOn(day.Weekday()) {
+			continue
+		}
+		windowStart := time.Date(day.Year(), day.Month(), day.Day(), start.Hour(), start.Minute(), 0, 0, now.Location())
+		windowEnd := time.Date(day.Year(), day.Month(), day.Day(), end.Hour(), end.Minute(), 0, 0, now.Location())
+		if !windowEnd.After(windowStart) {
+			windowEnd = windowEnd.AddDate(0, 0, 1)
+		}
+		if !now.Before(windowStart) && now.Before(windowEnd) {
+			return windowEnd, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// Check if a daily window applies on the given day of week.
+func (window MaintenanceWindow) appliesOn(day time.Weekday) bool {
+	if len(window.Days) == 0 {
+		return true
+	}
+	for _, name := range window.Days {
+		if strings.EqualFold(name, day.String()[:3]) || strings.EqualFold(name, day.String()) {
+			return true
+		}
+	}
+	return false
+}
+
+// Returns the directory in which held transfers are stored.
+func heldDirectory() string {
+	return filepath.Join(config.StateDirectory, "held")
+}
+
+/* Hold a transfer request locally. The id and time of the held transfer
+* are set by this function.
+* held - Transfer to hold, with route, reason, release time and request set
+ */
+func holdTransfer(held HeldTransfer) (HeldTransfer, error) {
+	now := time.Now()
+	held.Id = strconv.FormatInt(now.UnixNano(), 36)
+	held.HeldAt = now
+	held.RunId = runId
+	if err := os.MkdirAll(heldDirectory(), 0700); err != nil {
+		return held, err
+	}
+	content, err := json.MarshalIndent(held, "", "  ")
+	if err != nil {
+		return held, err
+	}
+	return held, os.WriteFile(filepath.Join(heldDirectory(), held.Id+".json"), content, 0600)
+}
+
+// Read all held transfers, oldest first.
+func readHeldTransfers() ([]HeldTransfer, error) {
+	return readHeldTransfersIn(heldDirectory())
+}
+
+// Read all held transfers of a directory, oldest first.
+func readHeldTransfersIn(directory string) ([]HeldTransfer, error) {
+	files, err := filepath.Glob(filepath.Join(directory, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	heldTransfers := []HeldTransfer{}
+	for _, file := range files {
+		content, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		var held HeldTransfer
+		if err := json.Unmarshal(content, &held); err != nil {
+			return nil, fmt.Errorf("%s: %v", file, err)
+		}
+		heldTransfers = append(heldTransfers, held)
+	}
+	sort.Slice(heldTransfers, func(i, j int) bool {
+		return heldTransfers[i].HeldAt.Before(heldTransfers[j].HeldAt)
+	})
+	return heldTransfers, nil
+}
+
+// Remove a held transfer from the local queue.
+func removeHeldTransfer(held HeldTransfer) error {
+	return os.Remove(filepath.Join(heldDirectory(), held.Id+".json"))
+}
+
+/* Submit the held transfers whose maintenance window has ended. A transfer
+* whose route is in a new maintenance window stays held, as do transfers
+* waiting for review.
+ */
+func releaseDueTransfers() {
+	now := time.Now()
+	purgeArchivedTransfers(now)
+	heldTransfers, err := readHeldTransfers()
+	if err != nil {
+		printMessage("heldReadFailed", err)
+		return
+	}
+	for _, held := range heldTransfers {
+		if held.ReviewRequired || now.Before(held.ReleaseAfter) {
+			continue
+		}
+		if route, err := findRoute(held.Route); err == nil {
+			if _, end, active := activeMaintenanceWindow(route, now); active {
+				printMessage("heldStaysHeld", held.Id, held.Route, end.Format(time.RFC1123))
+				continue
+			}
+		}
+		releaseHeldTransfer(held)
+	}
+}
+
+/* Remove a transfer from the local queue and submit it. Returns the HTTP
+* status code of the status query, -1 or pollTimedOutStatus.
+* held - Transfer to release
+ */
+func releaseHeldTransfer(held HeldTransfer) int {
+	printMessage("heldReleasing", held.Id, held.Route)
+	// Remove first so that a failing submission is not repeated by every run.
+	held, err := archiveHeldTransfer(held)
+	if err != nil {
+		printMessage("heldRemoveFailed", held.Id, err)
+		return -1
+	}
+	transferUrl := held.TransferUrl
+	if len(transferUrl) == 0 {
+		transferUrl = config.TransferUrl
+	}
+	// The request may have been held before an upgrade of MQ renamed fields
+	request := mapDeprecatedFields(held.Request)
+	respCode, transferStatus := submitTransfer(transferUrl, request)
+	auditSubmission("release", held.Route, transferUrl, request, submissionOutcome(respCode), transferStatus)
+	result := "failed"
+	if transfer := gjson.Get(transferStatus, "transfer.0"); transfer.Exists() {
+		result = transfer.Get("id").String() + " " + transfer.Get("status.state").String()
+	}
+	recordReleaseResult(held, result)
+	if respCode == http.StatusOK && held.Snapshot != nil {
+		// The reviewed source becomes the baseline for the next run
+		if err := saveBaseline(*held.Snapshot); err != nil {
+			printMessage("baselineSaveFailed", held.Route, err)
+		}
+	}
+	return respCode
+}
+
+// Command "held" - list the transfers held locally.
+func heldCommand(args []string) int {
+	flags := newFlagSet("held")
+	archived := flags.Bool("archived", false, "List the released transfers kept in the archive")
+	if !parseCommandLine(flags, args) {
+		return 2
+	}
+	if *archived {
+		return listArchivedTransfers()
+	}
+	heldTransfers, err := readHeldTransfers()
+	if err != nil {
+		printMessage("heldReadFailed", err)
+		return 1
+	}
+	if len(heldTransfers) == 0 {
+		printMessage("heldNone")
+		return 0
+	}
+	now := time.Now()
+	fmt.Printf("%-14s %-16s %-20s %-31s %s\n", "ID", "ROUTE", "HELD AT", "STATUS", "REASON")
+	for _, held := range heldTransfers {
+		status := message("heldStatusUntil", held.ReleaseAfter.Format("2006-01-02 15:04"))
+		if held.ReviewRequired {
+			status = message("heldStatusReview")
+		} else if !now.Before(held.ReleaseAfter) {
+			status = message("heldStatusDue")
+		}
+		fmt.Printf("%-14s %-16s %-20s %-31s %s\n", held.Id, held.Route, held.HeldAt.Format("2006-01-02 15:04:05"), status, held.Reason)
+	}
+	return 0
+}
+
+// List the archived held transfers.
+func listArchivedTransfers() int {
+	archived, err := readHeldTransfersIn(archiveDirectory())
+	if err != nil {
+		printMessage("heldReadFailed", err)
+		return 1
+	}
+	if len(archived) == 0 {
+		printMessage("archiveNone")
+		return 0
+	}
+	fmt.Printf("%-14s %-16s %-20s %-20s %s\n", "ID", "ROUTE", "HELD AT", "RELEASED AT", "RESULT")
+	for _, held := range archived {
+		releasedAt := ""
+		if held.ReleasedAt != nil {
+			releasedAt = held.ReleasedAt.Format("2006-01-02 15:04:05")
+		}
+		fmt.Printf("%-14s %-16s %-20s %-20s %s\n", held.Id, held.Route, held.HeldAt.Format("2006-01-02 15:04:05"), releasedAt, held.ReleaseResult)
+	}
+	return 0
+}
+
+/* Command "release" - submit held transfers. Without arguments the transfers
+* whose window has ended are submitted. Transfers given by id are submitted
+* whatever the reason they are held for.
+ */
+func releaseCommand(args []string) int {
+	flags := newFlagSet("release")
+	if !parseCommandLine(flags, args) {
+		return 2
+	}
+	if flags.NArg() == 0 {
+		releaseDueTransfers()
+		return 0
+	}
+	heldTransfers, err := readHeldTransfers()
+	if err != nil {
+		printMessage("heldReadFailed", err)
+		return 1
+	}
+	exitCode := 0
+	for _, id := range flags.Args() {
+		found := false
+		for _, held := range heldTransfers {
+			if held.Id == id {
+				found = true
+				if releaseHeldTransfer(held) != http.StatusOK {
+					exitCode = 1
+				}
+			}
+		}
+		if !found {
+			printMessage("heldNotFound", id)
+			exitCode = 1
+		}
+	}
+	return exitCode
+}
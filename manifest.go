@@ -0,0 +1,206 @@
+/*
+© Copyright IBM Corporation 2022, 2022
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+/*
+* This file contains the reading of transfer manifests, files listing the
+* items of a transfer too many to be written in the configuration. A manifest
+* is given with the -manifest flag of the submit command or the "manifest"
+* attribute of a route, and replaces the items of the route.
+*
+* A CSV manifest has a header row naming its columns. The source and
+* destination columns are required; sourceType, destinationType, mode (text or
+* binary), sourceEncoding, destinationEncoding, destinationEOL, checksum, the
+* expected checksum of the source, sourceDisposition (leave or delete),
+* actionIfExists (error or overwrite), recursive (true or false), exclude,
+* glob patterns separated by semicolons, messagePersistence, setMqProps
+* (true or false), messageDelimiter, messageDelimiterType,
+* messageDelimiterPosition, groupMessages (true or false) and waitTime, in
+* seconds, are optional:
+*
+*   source,destination,sourceType,destinationType,mode,destinationEOL
+*   /usr/srcdir/a.txt,/usr/destdir/a.txt,file,file,text,CRLF
+*   /usr/srcdir/b.bin,/usr/destdir/b.bin,,,binary,
+*
+* A manifest whose name ends with .jsonl or .ndjson has one item per line in
+* JSON, with the attributes of the "items" of a route. Blank lines are ignored:
+*
+*   {"source": "/usr/srcdir/a.txt", "destination": "/usr/destdir/a.txt", "mode": "text"}
+ */
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Columns of a CSV manifest.
+var manifestColumns = []string{"source", "destination", "sourceType", "destinationType", "mode",
+	"sourceEncoding", "destinationEncoding", "destinationEOL", "checksum", "sourceDisposition",
+	"actionIfExists", "recursive", "exclude", "messagePersistence", "setMqProps",
+	"messageDelimiter", "messageDelimiterType", "messageDelimiterPosition", "groupMessages", "waitTime"}
+
+/* Read the items of a transfer from a manifest file.
+* fileName - CSV or JSON Lines manifest
+ */
+func readManifest(fileName string) ([]TransferItem, error) {
+	file, err := os.Open(fileName)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var items []TransferItem
+	switch strings.ToLower(filepath.Ext(fileName)) {
+	case ".jsonl", ".ndjson":
+		items, err = readJsonLinesManifest(file)
+	default:
+		items, err = readCsvManifest(file)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("manifest %s: %v", fileName, err)
+	}
+	if len(items) == 0 {
+		return nil, fmt.Errorf("manifest %s has no items", fileName)
+	}
+	return items, nil
+}
+
+// Read the items of a CSV manifest.
+func readCsvManifest(reader io.Reader) ([]TransferItem, error) {
+	csvReader := csv.NewReader(reader)
+	csvReader.TrimLeadingSpace = true
+	header, err := csvReader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("header row: %v", err)
+	}
+	// Index of each column of the manifest
+	columns := map[string]int{}
+	for index, name := range header {
+		name = strings.TrimSpace(name)
+		known := false
+		for _, column := range manifestColumns {
+			if strings.EqualFold(name, column) {
+				columns[column], known = index, true
+			}
+		}
+		if !known {
+			return nil, fmt.Errorf("unknown column %q, the columns are %s", name, strings.Join(manifestColumns, ", "))
+		}
+	}
+	for _, column := range manifestColumns[:2] {
+		if _, found := columns[column]; !found {
+			return nil, fmt.Errorf("column %s is missing", column)
+		}
+	}
+
+	items := []TransferItem{}
+	for {
+		record, err := csvReader.Read()
+		if err == io.EOF {
+			return items, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		field := func(column string) string {
+			if index, found := columns[column]; found {
+				return strings.TrimSpace(record[index])
+			}
+			return ""
+		}
+		line, _ := csvReader.FieldPos(0)
+		exclude := []string(nil)
+		if len(field("exclude")) > 0 {
+			exclude = strings.Split(field("exclude"), ";")
+		}
+		var recursive *bool
+		if value := field("recursive"); len(value) > 0 {
+			parsed, err := strconv.ParseBool(value)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: recursive must be true or false, not %s", line, value)
+			}
+			recursive = &parsed
+		}
+		setMqProps := false
+		if value := field("setMqProps"); len(value) > 0 {
+			if setMqProps, err = strconv.ParseBool(value); err != nil {
+				return nil, fmt.Errorf("line %d: setMqProps must be true or false, not %s", line, value)
+			}
+		}
+		groupMessages := false
+		if value := field("groupMessages"); len(value) > 0 {
+			if groupMessages, err = strconv.ParseBool(value); err != nil {
+				return nil, fmt.Errorf("line %d: groupMessages must be true or false, not %s", line, value)
+			}
+		}
+		var waitTime *int
+		if value := field("waitTime"); len(value) > 0 {
+			parsed, err := strconv.Atoi(value)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: waitTime must be a number of seconds, not %s", line, value)
+			}
+			waitTime = &parsed
+		}
+		item := TransferItem{Source: field("source"), SourceType: field("sourceType"),
+			Destination: field("destination"), DestinationType: field("destinationType"), Mode: field("mode"),
+			SourceEncoding: field("sourceEncoding"), DestinationEncoding: field("destinationEncoding"),
+			DestinationEOL: field("destinationEOL"), Checksum: field("checksum"),
+			SourceDisposition: field("sourceDisposition"), ActionIfExists: field("actionIfExists"),
+			Recursive: recursive, Exclude: exclude, MessagePersistence: field("messagePersistence"), SetMqProps: setMqProps,
+			MessageDelimiter: field("messageDelimiter"), MessageDelimiterType: field("messageDelimiterType"),
+			MessageDelimiterPosition: field("messageDelimiterPosition"), GroupMessages: groupMessages, WaitTime: waitTime}
+		if err := checkManifestItem(item); err != nil {
+			return nil, fmt.Errorf("line %d: %v", line, err)
+		}
+		items = append(items, item)
+	}
+}
+
+// Read the items of a JSON Lines manifest.
+func readJsonLinesManifest(reader io.Reader) ([]TransferItem, error) {
+	items := []TransferItem{}
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if len(text) == 0 {
+			continue
+		}
+		var item TransferItem
+		decoder := json.NewDecoder(strings.NewReader(text))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&item); err != nil {
+			return nil, fmt.Errorf("line %d: %v", line, err)
+		}
+		if err := checkManifestItem(item); err != nil {
+			return nil, fmt.Errorf("line %d: %v", line, err)
+		}
+		items = append(items, item)
+	}
+	return items, scanner.Err()
+}
+
+// Check that an item of a manifest has a source and a destination.
+func checkManifestItem(item TransferItem) error {
+	if len(item.Source) == 0 || len(item.Destination) == 0 {
+		return fmt.Errorf("source and destination are required")
+	}
+	return nil
+}
@@ -0,0 +1,133 @@
+/*
+© Copyright IBM Corporation 2022, 2022
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+/*
+* This file contains the message catalog of the program. User-facing messages
+* are identified by a key and their text is taken from a catalog, a JSON file
+* mapping keys to fmt format strings. English messages are built into the
+* program from messages/en.json and are used for any key missing from the
+* catalog of the locale.
+*
+* The locale is taken from the "locale" attribute of the configuration, or from
+* the MFT_LOCALE, LC_ALL, LC_MESSAGES or LANG environment variables. For a locale
+* such as fr_FR.UTF-8 the catalogs fr_FR.json and then fr.json are looked for,
+* first in the "messageDirectory" of the configuration and then among the
+* catalogs built into the program. To translate the program, copy
+* messages/en.json, translate the texts keeping the format verbs (use %[n]v to
+* reorder arguments) and save it under the name of the locale.
+ */
+package main
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Catalogs built into the program.
+//
+//go:embed messages/*.json
+var builtinCatalogs embed.FS
+
+// Messages of the current locale, loaded by loadMessages.
+var messages map[string]string
+
+/* Return a message of the catalog formatted with its arguments.
+* key  - Key of the message
+* args - Arguments of the format verbs in the message
+ */
+func message(key string, args ...interface{}) string {
+	if messages == nil {
+		loadMessages("", "")
+	}
+	text, found := messages[key]
+	if !found {
+		text = key
+	}
+	return fmt.Sprintf(text, args...)
+}
+
+/* Log a message of the catalog at its level, see logging.go. With the
+* default plain format, it is printed followed by a new line.
+* key  - Key of the message
+* args - Arguments of the format verbs in the message
+ */
+func printMessage(key string, args ...interface{}) {
+	logMessage(key, nil, args...)
+}
+
+/* Load the English messages overlaid with the messages of a locale.
+* locale    - Locale, blank to take it from the environment
+* directory - Directory of additional catalogs, may be blank
+ */
+func loadMessages(locale string, directory string) {
+	messages = map[string]string{}
+	if content, err := builtinCatalogs.ReadFile("messages/en.json"); err == nil {
+		json.Unmarshal(content, &messages)
+	}
+	if len(locale) == 0 {
+		locale = environmentLocale()
+	}
+	for _, name := range localeCatalogNames(locale) {
+		var content []byte
+		var err error
+		if len(directory) > 0 {
+			content, err = os.ReadFile(filepath.Join(directory, name+".json"))
+		}
+		if len(directory) == 0 || err != nil {
+			content, err = builtinCatalogs.ReadFile("messages/" + name + ".json")
+		}
+		if err != nil {
+			continue
+		}
+		localized := map[string]string{}
+		if err := json.Unmarshal(content, &localized); err != nil {
+			fmt.Fprintf(os.Stderr, "Message catalog %s is not valid: %v\n", name, err)
+			continue
+		}
+		for key, text := range localized {
+			messages[key] = text
+		}
+		return
+	}
+}
+
+// Return the locale set in the environment.
+func environmentLocale() string {
+	for _, variable := range []string{"MFT_LOCALE", "LC_ALL", "LC_MESSAGES", "LANG"} {
+		if value := os.Getenv(variable); len(value) > 0 {
+			return value
+		}
+	}
+	return ""
+}
+
+// Return the catalog names of a locale, most specific first.
+func localeCatalogNames(locale string) []string {
+	// Drop the encoding and modifier, as in fr_FR.UTF-8@euro
+	if index := strings.IndexAny(locale, ".@"); index >= 0 {
+		locale = locale[:index]
+	}
+	locale = strings.ReplaceAll(locale, "-", "_")
+	if len(locale) == 0 || locale == "C" || locale == "POSIX" {
+		return nil
+	}
+	names := []string{locale}
+	if index := strings.Index(locale, "_"); index > 0 {
+		names = append(names, locale[:index])
+	}
+	return names
+}
@@ -0,0 +1,171 @@
+/*
+© Copyright IBM Corporation 2022, 2022
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+/*
+* This file contains the user defined metadata of transfers. The "metadata"
+* attribute of a route holds key/value pairs that are sent with the transfer
+* request and passed by the agents to exits and the transfer log.
+*
+* Metadata is checked before the request is built, so that a key or value
+* beyond the limits below is reported precisely instead of being rejected by
+* the MQ Web Server or agent with a generic error:
+*   - at most maxMetadataEntries pairs
+*   - keys of 1 to maxMetadataKeyLength characters, without white space or
+*     '=', and not starting with the prefix reserved by MFT, com.ibm.wmqfte.
+*   - values of at most maxMetadataValueLength characters
+*
+* With "originator" enabled in the configuration, the OS user, host name and
+* version of this program are added to the metadata of every submitted
+* transfer, under keys starting with a configurable prefix:
+*
+*   "originator": { "enabled": true, "prefix": "originator." }
+*
+* gives the keys originator.user, originator.host and originator.tool. These
+* keys replace any metadata of the route with the same key.
+*
+* Metadata given with the -metadata flag of the submit command is added to the
+* metadata of the route, replacing any pair with the same key. The metadata
+* and job name of a transfer are printed with its status, for the monitoring
+* and charge-back processes that rely on them.
+ */
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/user"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/tidwall/gjson"
+)
+
+// Limits of user defined metadata.
+const (
+	maxMetadataEntries     = 100
+	maxMetadataKeyLength   = 255
+	maxMetadataValueLength = 4096
+)
+
+// Prefix of the metadata keys set by MFT itself.
+const reservedMetadataPrefix = "com.ibm.wmqfte."
+
+// Default prefix of the originator metadata keys.
+const defaultOriginatorPrefix = "originator."
+
+// Settings of the originator metadata.
+type OriginatorSettings struct {
+	Enabled bool   `json:"enabled"`
+	Prefix  string `json:"prefix"`
+}
+
+/* Return the metadata of a route with the originator metadata added, if
+* enabled in the configuration.
+* metadata - Metadata of the route, not modified
+ */
+func withOriginatorMetadata(metadata map[string]string) map[string]string {
+	if !config.Originator.Enabled {
+		return metadata
+	}
+	prefix := config.Originator.Prefix
+	if len(prefix) == 0 {
+		prefix = defaultOriginatorPrefix
+	}
+	merged := map[string]string{}
+	for key, value := range metadata {
+		merged[key] = value
+	}
+	merged[prefix+"user"] = originatorUser()
+	merged[prefix+"host"], _ = os.Hostname()
+	merged[prefix+"tool"] = programName + " " + programVersion
+	return merged
+}
+
+// Return the name of the OS user running the program.
+func originatorUser() string {
+	if current, err := user.Current(); err == nil {
+		return current.Username
+	}
+	if name := os.Getenv("USER"); len(name) > 0 {
+		return name
+	}
+	return os.Getenv("USERNAME")
+}
+
+/* Add a metadata pair given on the command line as KEY=VALUE.
+* value    - Value of the -metadata flag
+* metadata - Metadata to which the pair is added
+ */
+func parseMetadataFlag(value string, metadata map[string]string) error {
+	key, data, found := strings.Cut(value, "=")
+	if !found {
+		return fmt.Errorf("metadata %q must be given as KEY=VALUE", value)
+	}
+	metadata[key] = data
+	return nil
+}
+
+/* Print the job name and user defined metadata of a transfer, if any.
+* transfer - Transfer as returned by the status query
+ */
+func printTransferMetadata(transfer gjson.Result) {
+	if jobName := transfer.Get("job.name").String(); len(jobName) > 0 {
+		printMessage("transferJobName", jobName)
+	}
+	metadata := transfer.Get("transferSet.userDefinedMetadata").Map()
+	keys := make([]string, 0, len(metadata))
+	for key := range metadata {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		printMessage("transferMetadata", key, metadata[key].String())
+	}
+}
+
+/* Check user defined metadata against the limits. All problems found are
+* reported, one per line.
+* metadata - Metadata key/value pairs
+ */
+func validateMetadata(metadata map[string]string) error {
+	var problems requestProblems
+	if len(metadata) > maxMetadataEntries {
+		problems.add(jsonPointer("transferSet", "userDefinedMetadata"), len(metadata), "entries, the maximum is %d", maxMetadataEntries)
+	}
+	keys := make([]string, 0, len(metadata))
+	for key := range metadata {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		value := metadata[key]
+		pointer := jsonPointer("transferSet", "userDefinedMetadata", key)
+		switch {
+		case len(key) == 0:
+			problems.add(pointer, nil, "metadata key is empty")
+		case utf8.RuneCountInString(key) > maxMetadataKeyLength:
+			problems.add(pointer, nil, "metadata key is %d characters long, the maximum is %d",
+				utf8.RuneCountInString(key), maxMetadataKeyLength)
+		case strings.IndexFunc(key, func(r rune) bool { return unicode.IsSpace(r) || r == '=' }) >= 0:
+			problems.add(pointer, nil, "metadata key contains white space or '='")
+		case strings.HasPrefix(strings.ToLower(key), reservedMetadataPrefix):
+			problems.add(pointer, nil, "metadata key uses the prefix %s reserved by MFT", reservedMetadataPrefix)
+		}
+		if length := utf8.RuneCountInString(value); length > maxMetadataValueLength {
+			problems.add(pointer, nil, "value is %d characters long, the maximum is %d", length, maxMetadataValueLength)
+		}
+	}
+	return problems.err()
+}
@@ -0,0 +1,358 @@
+/*
+© Copyright IBM Corporation 2022, 2022
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+/*
+* This file contains the "monitor" command, which manages the resource
+* monitors of agents through the monitor resource of the MQ Web Server, next
+* to the transfer resource. A resource monitor polls a directory or a queue of
+* the source agent of a route and submits the transfer of the route when its
+* trigger condition is met.
+*
+* "monitor create" creates a monitor of the -resource directory, or queue with
+* -resource-type queue, polled every -poll-interval. A directory monitor is
+* triggered by the files matching the -include pattern and not the -exclude
+* pattern, wildcards or, with -match regex, regular expressions. Up to
+* -batch-size matching files are transferred by each transfer. A queue monitor
+* is triggered when the queue is not empty. Other trigger conditions are set
+* with -trigger, see trigger.go. The transfer task of the monitor
+* is the transfer of the route, or of the -item flags, whose names may use the
+* variables set by the monitor such as ${FilePath} and ${FileName}, see
+* variables.go.
+*
+* Transfers started by a monitor are not part of the run that created it, so
+* its transfer task has no runId metadata.
+*
+* "monitor list" lists the monitors with their state, resource, poll interval
+* and trigger condition, optionally only those of an -agent or whose name
+* matches the -name wildcard pattern.
+*
+* "monitor delete AGENT NAME" deletes a monitor after asking the user to
+* confirm, unless -force is given. Transfers already started by the monitor
+* are not affected.
+ */
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	j "github.com/ricardolonga/jsongo"
+	"github.com/tidwall/gjson"
+)
+
+// Subcommands of the "monitor" command.
+var monitorCommands = map[string]func(args []string) int{
+	"create": monitorCreateCommand,
+	"list":   monitorListCommand,
+	"delete": monitorDeleteCommand,
+}
+
+// Types of the resources of a monitor.
+var monitorResourceTypes = map[string]bool{"directory": true, "queue": true}
+
+// Syntaxes of the patterns of a directory monitor.
+var monitorMatchPatterns = map[string]bool{"wildcard": true, "regex": true}
+
+// Resource monitor of an agent.
+type MonitorDefinition struct {
+	Name         string
+	Agent        string
+	ResourceType string
+	Resource     string
+	PollInterval time.Duration
+	BatchSize    int
+	Trigger      MonitorTrigger
+	Route        Route
+}
+
+// Return the URL of the monitor resource of the MQ Web Server of a route,
+// next to its transfer resource.
+func monitorCollectionUrl(route Route) string {
+	transferUrl := strings.TrimSuffix(route.transferUrl(), "/")
+	return strings.TrimSuffix(transferUrl, "/transfer") + "/monitor"
+}
+
+// Command "monitor" - manage the resource monitors of agents.
+func monitorCommand(args []string) int {
+	if len(args) == 0 {
+		printMessage("monitorUsage")
+		return 2
+	}
+	run, found := monitorCommands[args[0]]
+	if !found {
+		printMessage("monitorUsage")
+		return 2
+	}
+	return run(args[1:])
+}
+
+// Command "monitor create" - create a resource monitor for a route.
+func monitorCreateCommand(args []string) int {
+	flags := newFlagSet("monitor create")
+	name := flags.String("name", "", "Name of the monitor")
+	routeName := flags.String("route", "", "Route whose transfer the monitor submits")
+	resource := flags.String("resource", "", "Directory or queue monitored by the source agent of the route")
+	resourceType := flags.String("resource-type", "directory", "Type of the resource, directory or queue")
+	pollInterval := flags.Duration("poll-interval", time.Minute, "Interval between polls of the resource")
+	batchSize := flags.Int("batch-size", 0, "Maximum number of files per transfer, the agent default if 0")
+	include := flags.String("include", "*", "Pattern of the files triggering a directory monitor")
+	exclude := flags.String("exclude", "", "Pattern of the files ignored by a directory monitor")
+	match := flags.String("match", "wildcard", "Syntax of the patterns, wildcard or regex")
+	triggerType := flags.String("trigger", "", "Trigger condition, matchAll, noneMatch, fileSize, noSizeChange, queueNotEmpty or completeGroups")
+	var fileSize int64
+	flags.Func("file-size", "Size of a file triggering a fileSize monitor, such as 10MB", func(value string) (err error) {
+		fileSize, err = parseByteSize(value)
+		return err
+	})
+	noChangePolls := flags.Int("no-change-polls", 0, "Polls without change of size triggering a noSizeChange monitor")
+	customVariables := []string{}
+	flags.Func("var", "Declare a custom variable of the transfer task, such as a message property. May be repeated", func(value string) error {
+		if !isVariableName(value) {
+			return fmt.Errorf("variable name %q must be letters, digits, '_' or '.'", value)
+		}
+		customVariables = append(customVariables, value)
+		return nil
+	})
+	dryRun := flags.Bool("dry-run", false, "Print the monitor request without creating the monitor")
+	items := []TransferItem{}
+	flags.Func("item", "Transfer SOURCE=DESTINATION instead of the items of the route. May be repeated", func(value string) error {
+		item, err := parseTransferItem(value)
+		items = append(items, item)
+		return err
+	})
+	if !parseCommandLine(flags, args) || flags.NArg() != 0 {
+		printMessage("monitorUsage")
+		return 2
+	}
+	route, err := findSubmissionRoute(*routeName, items)
+	if err != nil {
+		printError(err)
+		return 2
+	}
+	delete(route.Metadata, runIdMetadataKey)
+	trigger := MonitorTrigger{Type: *triggerType, IncludePattern: *include, ExcludePattern: *exclude,
+		MatchPattern: *match, FileSize: fileSize, NoChangePolls: *noChangePolls}
+	monitor := MonitorDefinition{
+		Name:         *name,
+		Agent:        route.SourceAgent,
+		ResourceType: *resourceType,
+		Resource:     *resource,
+		PollInterval: *pollInterval,
+		BatchSize:    *batchSize,
+		Trigger:      trigger.withDefaults(*resourceType),
+		Route:        route,
+	}
+	if err := validateMonitor(monitor); err != nil {
+		printError(err)
+		return 2
+	}
+	if err := validateMonitorVariables(route, monitor.ResourceType, customVariables); err != nil {
+		printError(err)
+		return 2
+	}
+	monitorRequest := buildMonitorJsonRequest(monitor)
+	if *dryRun {
+		fmt.Println(indentRequest(monitorRequest))
+		return 0
+	}
+	if err := requireRemoteFeature(route, "resource monitors"); err != nil {
+		printError(err)
+		return 1
+	}
+	monitorUrl := monitorCollectionUrl(route)
+	response, body, err := callMQWeb("POST", monitorUrl, monitorRequest)
+	if err != nil {
+		printMessage("monitorRequestFailed", monitorUrl, err)
+		return 1
+	}
+	if response.StatusCode != http.StatusCreated {
+		printMessage("monitorRequestFailed", monitorUrl, mqWebError(response, body))
+		return 1
+	}
+	printMessage("monitorCreated", monitor.Name, monitor.Agent, monitor.Resource)
+	return 0
+}
+
+/* Return the error of a request rejected by the MQ Web Server, with the
+* message of the response body when it has one.
+* response - Response of the request
+* body     - Body of the response
+ */
+func mqWebError(response *http.Response, body string) error {
+	if message := gjson.Get(body, "error.0.message").String(); len(message) > 0 {
+		return fmt.Errorf("%s: %s", response.Status, message)
+	}
+	return fmt.Errorf("%s", response.Status)
+}
+
+/* Check a monitor definition. Its transfer has been checked with its route.
+* monitor - Monitor to create
+ */
+func validateMonitor(monitor MonitorDefinition) error {
+	var problems requestProblems
+	if len(monitor.Name) == 0 || strings.ContainsAny(monitor.Name, "*?% \t") {
+		problems.add(jsonPointer("name"), monitor.Name, "must be a monitor name without white space, '*', '?' or '%%'")
+	}
+	if len(monitor.Agent) == 0 {
+		problems.add(jsonPointer("agentName"), nil, "the route has no source agent to run the monitor")
+	}
+	if !monitorResourceTypes[monitor.ResourceType] {
+		problems.add(jsonPointer("resource", "type"), monitor.ResourceType, "must be directory or queue")
+	}
+	switch {
+	case len(monitor.Resource) == 0:
+		problems.add(jsonPointer("resource", "name"), nil, "the monitored resource is missing")
+	case monitor.ResourceType == "queue" && !isQueueName(monitor.Resource):
+		problems.add(jsonPointer("resource", "name"), monitor.Resource, "must be QUEUE or QUEUE@QMGR")
+	}
+	if monitor.PollInterval < time.Second {
+		problems.add(jsonPointer("pollInterval"), monitor.PollInterval.String(), "must be at least 1s")
+	}
+	if monitor.BatchSize < 0 {
+		problems.add(jsonPointer("batchSize"), monitor.BatchSize, "must not be negative")
+	}
+	if monitorResourceTypes[monitor.ResourceType] {
+		monitor.Trigger.validate(monitor.ResourceType, &problems)
+	}
+	return problems.err()
+}
+
+/* Return the poll interval of a monitor as a number of the largest unit that
+* divides it exactly.
+* interval - Poll interval
+ */
+func pollIntervalUnit(interval time.Duration) (int64, string) {
+	units := []struct {
+		name     string
+		duration time.Duration
+	}{{"days", 24 * time.Hour}, {"hours", time.Hour}, {"minutes", time.Minute}}
+	for _, unit := range units {
+		if interval%unit.duration == 0 {
+			return int64(interval / unit.duration), unit.name
+		}
+	}
+	return int64(interval / time.Second), "seconds"
+}
+
+/* Build the JSON request creating a monitor, with the transfer request of its
+* route as its transfer task.
+* monitor - Monitor to create
+ */
+func buildMonitorJsonRequest(monitor MonitorDefinition) string {
+	request := j.Object().Put("name", monitor.Name)
+	request.Put("type", monitor.ResourceType)
+	request.Put("agentName", monitor.Agent)
+	request.Put("resource", j.Object().Put("name", monitor.Resource).Put("type", monitor.ResourceType))
+
+	request.Put("triggerCondition", monitor.Trigger.jsonObject())
+
+	interval, unit := pollIntervalUnit(monitor.PollInterval)
+	request.Put("pollInterval", interval)
+	request.Put("pollIntervalUnit", unit)
+	if monitor.BatchSize > 0 {
+		request.Put("batchSize", monitor.BatchSize)
+	}
+	request.Put("transferDefinition", json.RawMessage(buildTransferJsonRequest(monitor.Route)))
+	return request.String()
+}
+
+// Command "monitor list" - list the resource monitors.
+func monitorListCommand(args []string) int {
+	flags := newFlagSet("monitor list")
+	agent := flags.String("agent", "", "List the monitors of an agent only")
+	name := flags.String("name", "*", "List the monitors whose name matches a wildcard pattern")
+	routeName := flags.String("route", "", "Route whose MQ Web Server is queried")
+	if !parseCommandLine(flags, args) || flags.NArg() != 0 {
+		printMessage("monitorUsage")
+		return 2
+	}
+	if _, err := path.Match(*name, ""); err != nil {
+		printMessage("monitorUsage")
+		return 2
+	}
+	route, err := findRoute(*routeName)
+	if err != nil {
+		printError(err)
+		return 2
+	}
+	monitorUrl := monitorCollectionUrl(route) + "?attributes=*"
+	if len(*agent) > 0 {
+		monitorUrl += "&agentName=" + url.QueryEscape(*agent)
+	}
+	response, body, err := callMQWeb("GET", monitorUrl, "")
+	if err != nil {
+		printMessage("monitorRequestFailed", monitorUrl, err)
+		return 1
+	}
+	if response.StatusCode != http.StatusOK {
+		printMessage("monitorRequestFailed", monitorUrl, mqWebError(response, body))
+		return 1
+	}
+	// The filters are also applied here for MQ Web Servers that ignore them
+	monitors := []gjson.Result{}
+	for _, monitor := range gjson.Get(body, "monitor").Array() {
+		matched, _ := path.Match(*name, monitor.Get("name").String())
+		if matched && (len(*agent) == 0 || strings.EqualFold(monitor.Get("agentName").String(), *agent)) {
+			monitors = append(monitors, monitor)
+		}
+	}
+	printMessage("monitorsListed", len(monitors))
+	for _, monitor := range monitors {
+		fmt.Printf("  %-24s %-16s %-8s %-9s %s\n", monitor.Get("name").String(), monitor.Get("agentName").String(),
+			monitor.Get("state").String(), monitor.Get("type").String(), monitor.Get("resource.name").String())
+		fmt.Printf("    %s\n", message("monitorPollInterval", monitor.Get("pollInterval").Int(), monitor.Get("pollIntervalUnit").String()))
+		if batchSize := monitor.Get("batchSize").Int(); batchSize > 0 {
+			fmt.Printf("    %s\n", message("monitorBatchSize", batchSize))
+		}
+		fmt.Printf("    %s\n", message("monitorTrigger", describeTrigger(monitor.Get("triggerCondition"))))
+	}
+	return 0
+}
+
+// Command "monitor delete" - delete a resource monitor.
+func monitorDeleteCommand(args []string) int {
+	flags := newFlagSet("monitor delete")
+	force := flags.Bool("force", false, "Delete the monitor without asking for confirmation")
+	routeName := flags.String("route", "", "Route whose MQ Web Server is queried")
+	if !parseCommandLine(flags, args) || flags.NArg() != 2 {
+		printMessage("monitorUsage")
+		return 2
+	}
+	agent, name := flags.Arg(0), flags.Arg(1)
+	route, err := findRoute(*routeName)
+	if err != nil {
+		printError(err)
+		return 2
+	}
+	if !*force && !askConfirmation(message("monitorDeleteConfirm", name, agent)) {
+		printMessage("monitorNotDeleted", name)
+		return 1
+	}
+	monitorUrl := monitorCollectionUrl(route) + "/" + url.PathEscape(name) + "?agentName=" + url.QueryEscape(agent)
+	response, body, err := callMQWeb("DELETE", monitorUrl, "")
+	if err != nil {
+		printMessage("monitorRequestFailed", monitorUrl, err)
+		return 1
+	}
+	if response.StatusCode != http.StatusNoContent && response.StatusCode != http.StatusOK {
+		printMessage("monitorRequestFailed", monitorUrl, mqWebError(response, body))
+		return 1
+	}
+	printMessage("monitorDeleted", name, agent)
+	return 0
+}
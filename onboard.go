@@ -0,0 +1,251 @@
+/*
+© Copyright IBM Corporation 2022, 2022
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+/*
+* This file contains the "onboard" command, which generates the onboarding
+* pack of a new partner from a few parameters: the route to add to the
+* configuration, the transfer request, a resource monitor definition, a
+* crontab entry for the schedule and a README.
+*
+* Each file of the pack is generated from a text/template. The templates
+* built into the program are in the onboarding directory; a directory of
+* templates given with -templates replaces them, so that an estate can adapt
+* the pack to its own conventions. A template named X.tmpl generates the file
+* X, and is skipped when it generates nothing. Templates are given the fields
+* of onboardingPartner and the functions json, to quote a value as JSON, and
+* indent, to indent a JSON document.
+ */
+package main
+
+import (
+	"bytes"
+	"embed"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"text/template"
+)
+
+// Onboarding pack templates built into the program.
+//
+//go:embed onboarding/*.tmpl
+var builtinOnboardingTemplates embed.FS
+
+// Valid partner names, also used as route and file names.
+var partnerNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]*$`)
+
+// Parameters of a partner given to the onboarding templates.
+type onboardingPartner struct {
+	Name                 string
+	SourceAgent          string
+	SourceQM             string
+	DestinationAgent     string
+	DestinationQM        string
+	SourceDirectory      string
+	DestinationDirectory string
+	Schedule             string
+	NotificationEmail    string
+	BusinessDaysOnly     bool
+	MonitorName          string
+	TriggerCondition     string
+	TransferRequest      string
+	Program              string
+	ConfigFile           string
+}
+
+// Command "onboard" - generate the onboarding pack of a partner.
+func onboardCommand(args []string) int {
+	flags := newFlagSet("onboard")
+	partner := onboardingPartner{}
+	flags.StringVar(&partner.Name, "partner", "", "Name of the partner, used as route name")
+	flags.StringVar(&partner.SourceDirectory, "source-dir", "", "Directory of the source agent holding files for the partner")
+	flags.StringVar(&partner.DestinationDirectory, "destination-dir", "", "Directory of the destination agent receiving the files")
+	flags.StringVar(&partner.Schedule, "schedule", "", "Cron schedule of the transfer, such as \"0 6 * * 1-5\"")
+	flags.StringVar(&partner.NotificationEmail, "email", "", "Notification email address of the partner")
+	flags.BoolVar(&partner.BusinessDaysOnly, "business-days", false, "Transfer on business days only")
+	baseRoute := flags.String("base-route", "", "Route from which agents and queue managers are copied")
+	sourceAgent := flags.String("source-agent", "", "Source agent, AGENT@QMGR")
+	destinationAgent := flags.String("destination-agent", "", "Destination agent, AGENT@QMGR")
+	templateDir := flags.String("templates", "", "Directory of templates replacing the built in templates")
+	outputDir := flags.String("output", "", "Directory of the pack, onboarding-PARTNER by default")
+	force := flags.Bool("force", false, "Overwrite existing files")
+	if !parseCommandLine(flags, args) || flags.NArg() != 0 {
+		printMessage("onboardUsage")
+		return 2
+	}
+	if err := completePartner(&partner, *baseRoute, *sourceAgent, *destinationAgent); err != nil {
+		printMessage("onboardInvalid", err)
+		return 2
+	}
+	if len(*outputDir) == 0 {
+		*outputDir = "onboarding-" + partner.Name
+	}
+
+	templates, err := readOnboardingTemplates(*templateDir)
+	if err != nil {
+		printMessage("onboardTemplatesFailed", err)
+		return 1
+	}
+	files, err := renderOnboardingPack(templates, partner)
+	if err != nil {
+		printMessage("onboardTemplatesFailed", err)
+		return 1
+	}
+	if err := writeOnboardingPack(*outputDir, files, *force); err != nil {
+		printMessage("onboardWriteFailed", *outputDir, err)
+		return 1
+	}
+	printMessage("onboardWritten", partner.Name, len(files), *outputDir)
+	return 0
+}
+
+/* Validate the partner parameters and fill in the derived fields.
+* partner          - Parameters given on the command line
+* baseRoute        - Route from which agents are copied
+* sourceAgent      - Source agent as AGENT@QMGR, overriding the base route
+* destinationAgent - Destination agent as AGENT@QMGR, overriding the base route
+ */
+func completePartner(partner *onboardingPartner, baseRoute string, sourceAgent string, destinationAgent string) error {
+	if !partnerNamePattern.MatchString(partner.Name) {
+		return fmt.Errorf("partner name %q must be letters, digits, '_', '-' or '.'", partner.Name)
+	}
+	if len(partner.SourceDirectory) == 0 || len(partner.DestinationDirectory) == 0 {
+		return fmt.Errorf("-source-dir and -destination-dir are required")
+	}
+	if len(partner.Schedule) > 0 && len(strings.Fields(partner.Schedule)) != 5 {
+		return fmt.Errorf("schedule %q must have the 5 fields of a cron schedule", partner.Schedule)
+	}
+	if len(partner.NotificationEmail) > 0 && !strings.Contains(partner.NotificationEmail, "@") {
+		return fmt.Errorf("invalid email address %q", partner.NotificationEmail)
+	}
+	base, err := findRoute(baseRoute)
+	if err != nil {
+		return err
+	}
+	partner.SourceAgent, partner.SourceQM = base.SourceAgent, base.SourceQM
+	partner.DestinationAgent, partner.DestinationQM = base.DestinationAgent, base.DestinationQM
+	if len(sourceAgent) > 0 {
+		if partner.SourceAgent, partner.SourceQM, err = splitAgent(sourceAgent); err != nil {
+			return err
+		}
+	}
+	if len(destinationAgent) > 0 {
+		if partner.DestinationAgent, partner.DestinationQM, err = splitAgent(destinationAgent); err != nil {
+			return err
+		}
+	}
+
+	route := Route{
+		Name:                partner.Name,
+		SourceAgent:         partner.SourceAgent,
+		SourceQM:            partner.SourceQM,
+		DestinationAgent:    partner.DestinationAgent,
+		DestinationQM:       partner.DestinationQM,
+		SourceItem:          partner.SourceDirectory,
+		SourceItemType:      "directory",
+		DestinationItem:     partner.DestinationDirectory,
+		DestinationItemType: "directory",
+	}
+	partner.TransferRequest = buildTransferJsonRequest(route)
+	// The same trigger condition as "monitor create" without trigger flags
+	partner.TriggerCondition = MonitorTrigger{Type: "matchAll", IncludePattern: "*", MatchPattern: "wildcard"}.jsonObject().String()
+	partner.MonitorName = strings.ToUpper("PARTNER_" + strings.NewReplacer("-", "_", ".", "_").Replace(partner.Name))
+	partner.Program, _ = os.Executable()
+	partner.ConfigFile = configFile
+	if len(partner.ConfigFile) > 0 {
+		partner.ConfigFile, _ = filepath.Abs(partner.ConfigFile)
+	}
+	return nil
+}
+
+// Split an agent given as AGENT@QMGR.
+func splitAgent(value string) (string, string, error) {
+	agent, qmgr, found := strings.Cut(value, "@")
+	if !found || len(agent) == 0 || len(qmgr) == 0 {
+		return "", "", fmt.Errorf("agent %q must be given as AGENT@QMGR", value)
+	}
+	return agent, qmgr, nil
+}
+
+/* Parse the onboarding templates, from a directory or built into the program.
+* directory - Directory of templates, blank for the built in templates
+ */
+func readOnboardingTemplates(directory string) (*template.Template, error) {
+	var templateFS fs.FS = builtinOnboardingTemplates
+	pattern := "onboarding/*.tmpl"
+	if len(directory) > 0 {
+		templateFS, pattern = os.DirFS(directory), "*.tmpl"
+	}
+	functions := template.FuncMap{
+		"json": func(value interface{}) (string, error) {
+			content, err := json.Marshal(value)
+			return string(content), err
+		},
+		"indent": func(document string, prefix string) (string, error) {
+			var indented bytes.Buffer
+			err := json.Indent(&indented, []byte(document), prefix, "  ")
+			return indented.String(), err
+		},
+	}
+	return template.New("onboarding").Funcs(functions).ParseFS(templateFS, pattern)
+}
+
+/* Render the files of an onboarding pack. Returns the content of the files
+* by name.
+* templates - Parsed onboarding templates
+* partner   - Parameters of the partner
+ */
+func renderOnboardingPack(templates *template.Template, partner onboardingPartner) (map[string][]byte, error) {
+	files := map[string][]byte{}
+	for _, tmpl := range templates.Templates() {
+		if !strings.HasSuffix(tmpl.Name(), ".tmpl") {
+			continue
+		}
+		var content bytes.Buffer
+		if err := tmpl.Execute(&content, partner); err != nil {
+			return nil, err
+		}
+		if len(bytes.TrimSpace(content.Bytes())) > 0 {
+			files[strings.TrimSuffix(tmpl.Name(), ".tmpl")] = content.Bytes()
+		}
+	}
+	return files, nil
+}
+
+/* Write the files of an onboarding pack to a directory.
+* directory - Directory of the pack, created if needed
+* files     - Content of the files by name
+* force     - Overwrite existing files
+ */
+func writeOnboardingPack(directory string, files map[string][]byte, force bool) error {
+	if !force {
+		for name := range files {
+			if _, err := os.Stat(filepath.Join(directory, name)); err == nil {
+				return fmt.Errorf("%s already exists, use -force to overwrite", filepath.Join(directory, name))
+			}
+		}
+	}
+	if err := os.MkdirAll(directory, 0755); err != nil {
+		return err
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(directory, name), content, 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,204 @@
+/*
+© Copyright IBM Corporation 2022, 2022
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+/*
+* This file contains the polling of the status of a submitted transfer until
+* it ends. The status is queried after interval, then after intervals growing
+* by the factor backoff up to maxInterval, until the transfer is successful,
+* partially successful, failed or cancelled, or timeout expires:
+*
+*   "polling": { "interval": "5s", "maxInterval": "1m", "backoff": 1.5, "timeout": "30m" }
+*
+* A timeout of 0 waits until the transfer ends. The -poll-interval and
+* -wait-timeout flags of "submit" replace interval and timeout.
+*
+* A transfer goes through three states while it is polled: pending while the
+* MQ Web Server answers 404 Not Found, as the agent has not yet published the
+* transfer, running while its state is not final, with its progress, rate and
+* estimated completion time printed when they change, see progress.go, and
+* ended, with its average rate printed. An error of the
+* MQ Web Server ends the polling, as does the timeout, in which case the last
+* status received is returned.
+ */
+package main
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tidwall/gjson"
+)
+
+// Polling of the status of submitted transfers.
+type PollPolicy struct {
+	Interval    Duration `json:"interval"`
+	MaxInterval Duration `json:"maxInterval"`
+	Backoff     float64  `json:"backoff"`
+	Timeout     Duration `json:"timeout"`
+}
+
+// States of a transfer while it is polled.
+const (
+	pollPending = "pending"
+	pollRunning = "running"
+	pollEnded   = "ended"
+)
+
+// Status returned by pollTransfer when the polling times out while the
+// transfer is running, with the last status received.
+const pollTimedOutStatus = -2
+
+// Returns the default polling policy.
+func defaultPollPolicy() PollPolicy {
+	return PollPolicy{
+		Interval:    Duration(5 * time.Second),
+		MaxInterval: Duration(time.Minute),
+		Backoff:     1.5,
+		Timeout:     Duration(30 * time.Minute),
+	}
+}
+
+// Return the interval before the poll following an interval.
+func (policy PollPolicy) next(interval time.Duration) time.Duration {
+	if policy.Backoff > 1 {
+		interval = time.Duration(float64(interval) * policy.Backoff)
+	}
+	if maxInterval := time.Duration(policy.MaxInterval); maxInterval > 0 && interval > maxInterval {
+		interval = maxInterval
+	}
+	return interval
+}
+
+/* Query the status of a transfer until it ends. Returns the HTTP status code
+* of the last status query, -1 or pollTimedOutStatus, and the last transfer
+* status received.
+* transferUrl - URL of the transfer resource
+* policy      - Intervals and timeout of the polling
+ */
+func pollTransferStatus(transferUrl string, policy PollPolicy) (int, string) {
+	printMessage("queryingStatus")
+	return pollTransfer(transferUrl, policy, true)
+}
+
+/* Query the status of a transfer until it ends, printing only the errors and
+* the changes of state of the transfer unless verbose.
+* transferUrl - URL of the transfer resource
+* policy      - Intervals and timeout of the polling
+* verbose     - Whether to print the progress and the final status
+ */
+func pollTransfer(transferUrl string, policy PollPolicy, verbose bool) (int, string) {
+	interval := time.Duration(policy.Interval)
+	if interval <= 0 {
+		interval = time.Duration(defaultPollPolicy().Interval)
+	}
+	started := time.Now()
+	state, transferState, lastBody := pollPending, "", ""
+	var lastProgress TransferProgress
+	var meter ThroughputMeter
+	for state != pollEnded {
+		// A cached status would hide the progress of the transfer
+		webResponses.invalidate(transferUrl)
+		response, body, err := callMQWeb("GET", transferUrl+"?attributes=*", "")
+		switch {
+		case err != nil:
+			logMessage("statusQueryFailed", []any{logUrl, transferUrl}, transferUrl, err)
+			return -1, lastBody
+		case response.StatusCode == http.StatusNotFound && state == pollPending:
+			// The agent has not published the transfer yet
+		case response.StatusCode != http.StatusOK:
+			logMessage("responseCodeReceived", []any{logUrl, transferUrl, logStatusCode, response.StatusCode}, response.Status)
+			return response.StatusCode, body
+		default:
+			lastBody = body
+			progress := transferProgress(gjson.Get(body, "transfer.0"))
+			meter.update(progress, time.Now())
+			newState := gjson.Get(body, "transfer.0.status.state").String()
+			if isTerminalTransferState(newState) {
+				transferId := gjson.Get(body, "transfer.0.id").String()
+				syslogEvent(syslogSeverity(newState), "completed", []string{"transferID", transferId, "state", newState,
+					"bytes", strconv.FormatInt(progress.Bytes, 10), "url", transferUrl}, message("syslogCompleted", transferId, newState))
+				notifyWebhooks(transferUrl, body)
+				state = pollEnded
+				continue
+			}
+			if newState != transferState {
+				transferId := gjson.Get(body, "transfer.0.id").String()
+				logMessage("transferState", []any{logTransferId, transferId}, transferId, newState)
+			}
+			if verbose && progress != lastProgress {
+				text := progress.String()
+				if estimate := meter.estimate(progress); len(estimate) > 0 {
+					text += ", " + estimate
+				}
+				if len(text) > 0 {
+					printMessage("transferProgress", text)
+				}
+				lastProgress = progress
+			}
+			state, transferState = pollRunning, newState
+		}
+		if timeout := time.Duration(policy.Timeout); timeout > 0 && time.Since(started)+interval > timeout {
+			if state == pollPending {
+				if verbose {
+					logMessage("pollTimedOutPending", []any{logUrl, transferUrl}, timeout.String())
+				}
+				return http.StatusNotFound, ""
+			}
+			if verbose {
+				logMessage("pollTimedOut", []any{logUrl, transferUrl}, timeout.String(), transferState)
+			}
+			return pollTimedOutStatus, lastBody
+		}
+		time.Sleep(interval)
+		interval = policy.next(interval)
+	}
+	if verbose {
+		printTransferStatus(lastBody)
+		if summary := meter.summary(gjson.Get(lastBody, "transfer.0")); len(summary) > 0 {
+			logger.Info(summary, logTransferId, gjson.Get(lastBody, "transfer.0.id").String())
+		}
+	}
+	return http.StatusOK, lastBody
+}
+
+/* Return the outcome of a submitted transfer for the audit log and -result:
+* submitted once it has ended, notEnded when the polling timed out and failed
+* otherwise.
+* status - Status returned by pollTransfer
+ */
+func submissionOutcome(status int) string {
+	switch status {
+	case http.StatusOK:
+		return "submitted"
+	case pollTimedOutStatus:
+		return "notEnded"
+	}
+	return "failed"
+}
+
+/* Return the exit code of a command for the final state of the transfer it
+* submitted: 0 when successful, 3 when partially successful and 1 otherwise,
+* as for a batch.
+* state - Final state of the transfer, or its state when the polling timed out
+ */
+func transferExitCode(state string) int {
+	switch strings.ToLower(state) {
+	case "successful":
+		return exitSuccessful
+	case "partiallysuccessful":
+		return exitPartial
+	}
+	return exitFailed
+}
@@ -0,0 +1,141 @@
+/*
+© Copyright IBM Corporation 2022, 2022
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+/*
+* This file contains the preview of a transfer printed before submission:
+* the number of items, the size of the request body and, for sources
+* accessible from this host, the number of files and bytes to transfer.
+*
+* With -confirm the user is asked to confirm a transfer larger than the
+* "confirmThresholds" of the configuration, so that a mistake in a route or
+* manifest does not submit a massive transfer:
+*
+*   "confirmThresholds": { "items": 100, "requestBytes": 1048576, "bytes": 10737418240 }
+*
+* A threshold of 0 is not checked.
+ */
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Sizes of a transfer above which -confirm asks for confirmation.
+type ConfirmThresholds struct {
+	Items        int   `json:"items"`
+	RequestBytes int   `json:"requestBytes"`
+	Bytes        int64 `json:"bytes"`
+}
+
+// Returns the default confirmation thresholds.
+func defaultConfirmThresholds() ConfirmThresholds {
+	return ConfirmThresholds{Items: 100, RequestBytes: 1 << 20, Bytes: 10 << 30}
+}
+
+// Estimated size of a transfer.
+type TransferEstimate struct {
+	Items        int
+	RequestBytes int
+	LocalSources int
+	LocalFiles   int
+	LocalBytes   int64
+}
+
+/* Estimate the size of the transfer of a route. Sources that are not
+* accessible from this host are only counted as items.
+* route   - Route of the transfer
+* request - Transfer request in JSON format
+ */
+func estimateTransfer(route Route, request string) TransferEstimate {
+	estimate := TransferEstimate{RequestBytes: len(request)}
+	for _, item := range route.transferItems() {
+		estimate.Items++
+		if route.hasRemoteSources() {
+			continue
+		}
+		paths := localSourcePaths(item.Source)
+		if len(paths) == 0 {
+			continue
+		}
+		estimate.LocalSources++
+		for _, source := range paths {
+			walkSourceFiles(item, source, func(path string, info os.FileInfo, err error) error {
+				if err == nil {
+					estimate.LocalFiles++
+					estimate.LocalBytes += info.Size()
+				}
+				return nil
+			})
+		}
+	}
+	return estimate
+}
+
+// Print the estimate of a transfer.
+func (estimate TransferEstimate) print() {
+	printMessage("previewRequest", estimate.Items, formatBytes(int64(estimate.RequestBytes)))
+	if estimate.LocalSources > 0 {
+		printMessage("previewLocal", estimate.LocalSources, estimate.Items, estimate.LocalFiles, formatBytes(estimate.LocalBytes))
+	}
+}
+
+/* Return the thresholds exceeded by a transfer, as descriptions.
+* thresholds - Thresholds from the configuration
+ */
+func (estimate TransferEstimate) exceeded(thresholds ConfirmThresholds) []string {
+	reasons := []string{}
+	if thresholds.Items > 0 && estimate.Items > thresholds.Items {
+		reasons = append(reasons, fmt.Sprintf("%d items > %d", estimate.Items, thresholds.Items))
+	}
+	if thresholds.RequestBytes > 0 && estimate.RequestBytes > thresholds.RequestBytes {
+		reasons = append(reasons, fmt.Sprintf("request of %s > %s",
+			formatBytes(int64(estimate.RequestBytes)), formatBytes(int64(thresholds.RequestBytes))))
+	}
+	if thresholds.Bytes > 0 && estimate.LocalBytes > thresholds.Bytes {
+		reasons = append(reasons, fmt.Sprintf("%s > %s", formatBytes(estimate.LocalBytes), formatBytes(thresholds.Bytes)))
+	}
+	return reasons
+}
+
+/* Ask the user to confirm a large transfer. Returns false unless the user
+* answers yes.
+* reasons - Thresholds exceeded by the transfer
+ */
+func confirmSubmission(reasons []string) bool {
+	return askConfirmation(message("previewConfirm", strings.Join(reasons, ", ")))
+}
+
+// Ask the user a yes or no question. Returns false unless the user answers yes.
+func askConfirmation(question string) bool {
+	fmt.Print(question)
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
+
+// Format a number of bytes with a binary unit.
+func formatBytes(bytes int64) string {
+	const unit = 1024
+	if bytes < unit {
+		return fmt.Sprintf("%d B", bytes)
+	}
+	value, prefix := float64(bytes)/unit, 0
+	for value >= unit && prefix < 4 {
+		value /= unit
+		prefix++
+	}
+	return fmt.Sprintf("%.1f %ciB", value, "KMGTP"[prefix])
+}
@@ -0,0 +1,152 @@
+/*
+© Copyright IBM Corporation 2022, 2022
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+/*
+* This file contains the progress of a transfer, read from its status while it
+* is polled: the bytes transferred out of the total, from
+* status.currentBytesTransferred and status.totalBytes, and the files done out
+* of the files of the transfer, from the statistics. Levels of MQ that do not
+* report the current bytes give the bytes sent of the transfer set instead.
+* A line of progress is printed each time it changes between two polls:
+*
+*   Progress: 3.0 MiB of 10.0 MiB (30%), 1 of 4 files, 1.2 MiB/s, done at 10:42:17
+*
+* The rate is measured between successive polls and smoothed, half of it
+* coming from the last interval, so that a single slow or fast interval does
+* not swing the estimated completion time. When the transfer ends its average
+* rate is printed, over the start and end times of its statistics or, when the
+* status has none, over the time it was polled.
+ */
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/tidwall/gjson"
+)
+
+// Progress of a transfer.
+type TransferProgress struct {
+	Bytes      int64
+	TotalBytes int64
+	Files      int64
+	FilesDone  int64
+}
+
+/* Return the progress of a transfer.
+* transfer - Transfer as returned by the status query
+ */
+func transferProgress(transfer gjson.Result) TransferProgress {
+	progress := TransferProgress{
+		Bytes:      transfer.Get("status.currentBytesTransferred").Int(),
+		TotalBytes: transfer.Get("status.totalBytes").Int(),
+		Files:      transfer.Get("statistics.numberOfFiles").Int(),
+		FilesDone: transfer.Get("statistics.numberOfFileSuccesses").Int() + transfer.Get("statistics.numberOfFileFailures").Int() +
+			transfer.Get("statistics.numberOfFileWarnings").Int(),
+	}
+	if !transfer.Get("status.currentBytesTransferred").Exists() {
+		progress.Bytes = transfer.Get("transferSet.bytesSent").Int()
+	}
+	return progress
+}
+
+// Return the percentage of the bytes transferred, or -1 if the total is not
+// known.
+func (progress TransferProgress) percent() int {
+	if progress.TotalBytes <= 0 {
+		return -1
+	}
+	return int(progress.Bytes * 100 / progress.TotalBytes)
+}
+
+// Return the progress as text, empty if the status gives none.
+func (progress TransferProgress) String() string {
+	parts := []string{}
+	switch {
+	case progress.percent() >= 0:
+		parts = append(parts, fmt.Sprintf("%s of %s (%d%%)", formatBytes(progress.Bytes), formatBytes(progress.TotalBytes), progress.percent()))
+	case progress.Bytes > 0:
+		parts = append(parts, formatBytes(progress.Bytes))
+	}
+	if progress.Files > 0 {
+		parts = append(parts, fmt.Sprintf("%d of %d files", progress.FilesDone, progress.Files))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// Rate of a transfer measured over successive polls.
+type ThroughputMeter struct {
+	firstBytes int64
+	firstTime  time.Time
+	lastBytes  int64
+	lastTime   time.Time
+	rate       float64
+}
+
+/* Record the bytes transferred at a poll.
+* progress - Progress of the transfer
+* now      - Time of the poll
+ */
+func (meter *ThroughputMeter) update(progress TransferProgress, now time.Time) {
+	if meter.firstTime.IsZero() {
+		meter.firstBytes, meter.firstTime = progress.Bytes, now
+	} else if elapsed := now.Sub(meter.lastTime).Seconds(); elapsed > 0 && progress.Bytes >= meter.lastBytes {
+		rate := float64(progress.Bytes-meter.lastBytes) / elapsed
+		if meter.rate > 0 {
+			rate = (meter.rate + rate) / 2
+		}
+		meter.rate = rate
+	}
+	meter.lastBytes, meter.lastTime = progress.Bytes, now
+}
+
+// Return the rate and the estimated completion time of a transfer as text,
+// empty until the rate is known.
+func (meter ThroughputMeter) estimate(progress TransferProgress) string {
+	if meter.rate <= 0 {
+		return ""
+	}
+	text := formatBytes(int64(meter.rate)) + "/s"
+	if progress.TotalBytes > progress.Bytes {
+		remaining := time.Duration(float64(progress.TotalBytes-progress.Bytes) / meter.rate * float64(time.Second))
+		text += ", " + message("transferEta", meter.lastTime.Add(remaining).Format("15:04:05"))
+	}
+	return text
+}
+
+/* Return the average rate of an ended transfer as text, empty if it cannot
+* be computed.
+* transfer - Transfer as returned by the status query
+* meter    - Rate measured while the transfer was polled
+ */
+func (meter ThroughputMeter) summary(transfer gjson.Result) string {
+	progress := transferProgress(transfer)
+	bytes := progress.Bytes
+	if bytes == 0 {
+		bytes = progress.TotalBytes
+	}
+	start, startErr := time.Parse(time.RFC3339, transfer.Get("statistics.startTime").String())
+	end, endErr := time.Parse(time.RFC3339, transfer.Get("statistics.endTime").String())
+	if startErr != nil || endErr != nil {
+		// Measure over the polls instead
+		start, end, bytes = meter.firstTime, meter.lastTime, meter.lastBytes-meter.firstBytes
+	}
+	elapsed := end.Sub(start)
+	if bytes <= 0 || elapsed <= 0 {
+		return ""
+	}
+	return message("transferThroughput", formatBytes(bytes), elapsed.Round(time.Millisecond).String(),
+		formatBytes(int64(float64(bytes)/elapsed.Seconds())))
+}
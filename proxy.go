@@ -0,0 +1,155 @@
+/*
+© Copyright IBM Corporation 2022, 2022
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+/*
+* This file contains the support for reaching the MQ Web Server through a
+* HTTP(S) proxy. By default the HTTP_PROXY, HTTPS_PROXY and NO_PROXY
+* environment variables are honored. The proxy can also be set by the "proxy"
+* attribute of the configuration:
+*
+*   "proxy": {
+*     "url": "http://proxy.example.com:3128",
+*     "user": "proxyuser",
+*     "password": "keyring://proxy",
+*     "noProxy": "localhost,.internal.example.com"
+*   }
+*
+* or by the -proxy URL and -proxy-user USER[:PASSWORD] flags, which take
+* precedence over the configuration. The password may be a reference to a
+* credential provider as for the MQ Web Server password, see credentials.go.
+* When no password is given it is taken from the MFT_PROXY_PASSWORD
+* environment variable, or prompted for on a terminal.
+*
+* Where the MQ Web Server is only reachable through a SOCKS tunnel, such as
+* "ssh -D 1080 bastion", set the proxy URL to socks5://HOST:PORT or use the
+* -socks5 HOST:PORT flag. Host names are resolved by the SOCKS server. The
+* proxy user and password are used for SOCKS username/password
+* authentication.
+ */
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"golang.org/x/net/http/httpproxy"
+	"golang.org/x/term"
+)
+
+// Environment variable holding the password of the proxy user.
+const proxyPasswordVariable = "MFT_PROXY_PASSWORD"
+
+// Proxy settings of the connection to the MQ Web Server.
+type ProxySettings struct {
+	URL      string `json:"url"`
+	User     string `json:"user"`
+	Password string `json:"password"`
+	NoProxy  string `json:"noProxy"`
+}
+
+// Proxy URL and user set by the -proxy, -socks5 and -proxy-user flags.
+var proxyFlag, socks5Flag, proxyUserFlag string
+
+// Apply the -proxy, -socks5 and -proxy-user flags to the configuration.
+func applyProxyFlags() error {
+	if len(proxyFlag) > 0 && len(socks5Flag) > 0 {
+		return fmt.Errorf("-proxy and -socks5 cannot be used together")
+	}
+	if len(proxyFlag) > 0 {
+		config.Proxy.URL = proxyFlag
+	}
+	if len(socks5Flag) > 0 {
+		config.Proxy.URL = "socks5://" + strings.TrimPrefix(socks5Flag, "socks5://")
+	}
+	if len(proxyUserFlag) > 0 {
+		user, password, hasPassword := strings.Cut(proxyUserFlag, ":")
+		config.Proxy.User = user
+		if hasPassword {
+			config.Proxy.Password = password
+		}
+	}
+	return nil
+}
+
+/* Return the function selecting the proxy of a request, or nil when no
+* proxy is configured in the environment or the configuration.
+* settings - Proxy settings from the configuration
+ */
+func proxySelector(settings ProxySettings) (func(*http.Request) (*url.URL, error), error) {
+	proxyConfig := httpproxy.FromEnvironment()
+	if len(settings.URL) > 0 {
+		if _, err := url.Parse(settings.URL); err != nil {
+			return nil, fmt.Errorf("invalid proxy URL %s: %v", settings.URL, err)
+		}
+		proxyConfig.HTTPProxy = settings.URL
+		proxyConfig.HTTPSProxy = settings.URL
+	}
+	if len(settings.NoProxy) > 0 {
+		proxyConfig.NoProxy = settings.NoProxy
+	}
+	if len(proxyConfig.HTTPProxy) == 0 && len(proxyConfig.HTTPSProxy) == 0 {
+		return nil, nil
+	}
+
+	var credentials *url.Userinfo
+	if len(settings.User) > 0 {
+		password, err := proxyPassword(settings)
+		if err != nil {
+			return nil, err
+		}
+		credentials = url.UserPassword(settings.User, password)
+	}
+	selectProxy := proxyConfig.ProxyFunc()
+	return func(request *http.Request) (*url.URL, error) {
+		proxyUrl, err := selectProxy(request.URL)
+		if proxyUrl != nil && credentials != nil && proxyUrl.User == nil {
+			withCredentials := *proxyUrl
+			withCredentials.User = credentials
+			proxyUrl = &withCredentials
+		}
+		return proxyUrl, err
+	}, nil
+}
+
+// Return the password of the proxy user.
+func proxyPassword(settings ProxySettings) (string, error) {
+	password := settings.Password
+	if len(password) == 0 {
+		password = os.Getenv(proxyPasswordVariable)
+	}
+	if len(password) == 0 {
+		if !term.IsTerminal(int(os.Stdin.Fd())) {
+			return "", fmt.Errorf("no password for proxy user %s, set %s", settings.User, proxyPasswordVariable)
+		}
+		password, err := readPassword(message("proxyPasswordPrompt", settings.User))
+		registerSecret(password)
+		return password, err
+	}
+	reference, provider, err := credentialProviderFor(password)
+	if err != nil {
+		return "", err
+	}
+	if provider != nil {
+		resolved, err := provider.Password(reference, settings.User)
+		if err != nil {
+			return "", fmt.Errorf("unable to retrieve proxy password from %s provider: %v", reference.Scheme, err)
+		}
+		registerSecret(resolved)
+		return resolved, nil
+	}
+	registerSecret(password)
+	return password, nil
+}
@@ -0,0 +1,85 @@
+/*
+© Copyright IBM Corporation 2022, 2022
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+/*
+* This file contains the pre-flight check of the coordination and command
+* queue managers of the MQ Web Server. Their names are not given by the MFT
+* REST API, which routes every request through the queue managers set in
+* mqwebuser.xml, so they are repeated in the configuration:
+*
+*   "coordinationQM": "COORDQM", "commandQM": "CMDQM"
+*
+* When they are set, the qmgr resource of the MQ Web Server is queried before
+* a transfer is submitted,
+*
+*   GET /ibmmq/rest/v2/admin/qmgr/COORDQM
+*
+* and the submission fails with "queue manager COORDQM is not running" when
+* the queue manager is not in the running state, rather than with a failure
+* of the transfer request that does not give the cause. The qmgr resource
+* only knows the queue managers local to the MQ Web Server, which is where
+* the coordination and command queue managers of the MFT REST API are.
+ */
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/tidwall/gjson"
+)
+
+/* Return the URL of a queue manager of the MQ Web Server of a transfer
+* resource.
+* transferUrl - URL of the transfer resource
+* name        - Name of the queue manager
+ */
+func qmgrUrlOf(transferUrl string, name string) string {
+	adminUrl := strings.TrimSuffix(strings.TrimSuffix(transferUrl, "/"), "/mft/transfer")
+	return adminUrl + "/qmgr/" + url.PathEscape(name)
+}
+
+/* Check that the coordination and command queue managers of the
+* configuration are running.
+* route - Route of the transfer, whose MQ Web Server is queried
+ */
+func checkQueueManagers(route Route) error {
+	roles := []struct{ role, name string }{
+		{"coordination", config.CoordinationQM},
+		{"command", config.CommandQM},
+	}
+	checked := map[string]bool{}
+	for _, qmgr := range roles {
+		if len(qmgr.name) == 0 || checked[qmgr.name] {
+			continue
+		}
+		checked[qmgr.name] = true
+		qmgrUrl := qmgrUrlOf(route.transferUrl(), qmgr.name)
+		response, body, err := callMQWeb("GET", qmgrUrl, "")
+		if err != nil {
+			return fmt.Errorf("%s", message("qmgrQueryFailed", qmgr.role, qmgr.name, err))
+		}
+		if response.StatusCode == http.StatusNotFound {
+			return fmt.Errorf("%s", message("qmgrNotFound", qmgr.role, qmgr.name))
+		}
+		if response.StatusCode != http.StatusOK {
+			return fmt.Errorf("%s", message("qmgrQueryFailed", qmgr.role, qmgr.name, mqWebError(response, body)))
+		}
+		if state := gjson.Get(body, "qmgr.0.state").String(); !strings.EqualFold(state, "running") {
+			return fmt.Errorf("%s", message("qmgrNotRunning", qmgr.role, qmgr.name, state))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,138 @@
+/*
+© Copyright IBM Corporation 2022, 2022
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+/*
+* This file contains the support for transfers between files and MQ queues.
+* A destination of type "queue" delivers each source file as a message to the
+* queue named QUEUE or QUEUE@QMGR, the queue manager of the destination agent
+* when no queue manager is given:
+*
+*   { "source": "/usr/srcdir/order.xml", "sourceType": "file",
+*     "destination": "ORDERS.IN@HUBQM", "destinationType": "queue",
+*     "messagePersistence": "persistent", "setMqProps": true }
+*
+* messagePersistence is persistent, nonPersistent or queueDefault, the
+* default persistence of the queue. With setMqProps the agent sets MQ message
+* properties describing the transfer on the first message of each file.
+*
+* A source of type "queue" lands the messages of the queue QUEUE or QUEUE@QMGR
+* in a file or a directory:
+*
+*   { "source": "ORDERS.OUT", "sourceType": "queue",
+*     "destination": "/usr/destdir/orders.txt", "destinationType": "file",
+*     "messageDelimiter": "\n", "messageDelimiterPosition": "postfix",
+*     "groupMessages": false, "waitTime": 30 }
+*
+* messageDelimiter is written between messages, as text or, with
+* "messageDelimiterType": "binary", as hexadecimal bytes such as "0D0A".
+* messageDelimiterPosition is prefix or postfix. With groupMessages the
+* messages of a complete message group are transferred. waitTime is the number
+* of seconds to wait for a message or a complete group when the queue is empty.
+ */
+package main
+
+import (
+	"encoding/hex"
+	"strings"
+)
+
+// Maximum length of the name of an MQ queue or queue manager.
+const maxMQNameLength = 48
+
+// Persistence of the messages written to a destination queue.
+var messagePersistences = map[string]bool{
+	"persistent":    true,
+	"nonPersistent": true,
+	"queueDefault":  true,
+}
+
+/* Check the queue options of an item.
+* item     - Item of the transfer
+* pointer  - Returns the JSON pointer of an attribute of the item
+* problems - Problems found
+ */
+func validateQueueOptions(item TransferItem, pointer func(tokens ...interface{}) string, problems *requestProblems) {
+	if item.DestinationType != "queue" {
+		if len(item.MessagePersistence) > 0 {
+			problems.add(pointer("destination", "messagePersistence"), item.MessagePersistence, "only applies to a queue destination")
+		}
+		if item.SetMqProps {
+			problems.add(pointer("destination", "setMqProps"), true, "only applies to a queue destination")
+		}
+		return
+	}
+	if item.SourceType != "file" {
+		problems.add(pointer("source", "type"), item.SourceType, "must be file for a queue destination")
+	}
+	if !isQueueName(item.Destination) {
+		problems.add(pointer("destination", "name"), item.Destination, "must be QUEUE or QUEUE@QMGR, with names of 1 to %d characters A-Z, a-z, 0-9, '.', '_', '/' or '%%'", maxMQNameLength)
+	}
+	if len(item.MessagePersistence) > 0 && !messagePersistences[item.MessagePersistence] {
+		problems.add(pointer("destination", "messagePersistence"), item.MessagePersistence, "must be persistent, nonPersistent or queueDefault")
+	}
+}
+
+/* Check the options of an item whose source is a queue.
+* item     - Item of the transfer
+* pointer  - Returns the JSON pointer of an attribute of the item
+* problems - Problems found
+ */
+func validateQueueSourceOptions(item TransferItem, pointer func(tokens ...interface{}) string, problems *requestProblems) {
+	if item.SourceType != "queue" {
+		if len(item.MessageDelimiter) > 0 || len(item.MessageDelimiterType) > 0 || len(item.MessageDelimiterPosition) > 0 ||
+			item.GroupMessages || item.WaitTime != nil {
+			problems.add(pointer("source"), nil, "message options only apply to a queue source")
+		}
+		return
+	}
+	if item.DestinationType != "file" && item.DestinationType != "directory" {
+		problems.add(pointer("destination", "type"), item.DestinationType, "must be file or directory for a queue source")
+	}
+	if !isQueueName(item.Source) {
+		problems.add(pointer("source", "name"), item.Source, "must be QUEUE or QUEUE@QMGR, with names of 1 to %d characters A-Z, a-z, 0-9, '.', '_', '/' or '%%'", maxMQNameLength)
+	}
+	switch item.MessageDelimiterType {
+	case "", "text":
+	case "binary":
+		if _, err := hex.DecodeString(item.MessageDelimiter); err != nil {
+			problems.add(pointer("source", "messageDelimiter"), item.MessageDelimiter, "must be hexadecimal bytes for a binary delimiter")
+		}
+	default:
+		problems.add(pointer("source", "messageDelimiterType"), item.MessageDelimiterType, "must be text or binary")
+	}
+	if item.MessageDelimiterPosition != "" && item.MessageDelimiterPosition != "prefix" && item.MessageDelimiterPosition != "postfix" {
+		problems.add(pointer("source", "messageDelimiterPosition"), item.MessageDelimiterPosition, "must be prefix or postfix")
+	}
+	if item.WaitTime != nil && *item.WaitTime < 0 {
+		problems.add(pointer("source", "waitTime"), *item.WaitTime, "must be a number of seconds")
+	}
+}
+
+// Check if a name is QUEUE or QUEUE@QMGR.
+func isQueueName(name string) bool {
+	queue, queueManager, hasQueueManager := strings.Cut(name, "@")
+	return isMQName(queue) && (!hasQueueManager || isMQName(queueManager))
+}
+
+// Check if a name is a valid MQ object name.
+func isMQName(name string) bool {
+	if len(name) == 0 || len(name) > maxMQNameLength {
+		return false
+	}
+	for _, r := range name {
+		if !(r >= 'A' && r <= 'Z' || r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || strings.ContainsRune("._/%", r)) {
+			return false
+		}
+	}
+	return true
+}
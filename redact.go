@@ -0,0 +1,149 @@
+/*
+© Copyright IBM Corporation 2022, 2022
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+/*
+* This file contains the redaction of secrets from everything the program
+* logs. Every record goes through redactingHandler, whatever the format and
+* level, so that messages, error texts, fields and the request and response
+* dumps of -vv are covered without each caller masking them. Masked with
+* "****" are:
+*   - the passwords resolved by the program, for the MQ Web Server and the
+*     proxy, registered with registerSecret once known
+*   - the credentials of Authorization, Proxy-Authorization, Cookie and
+*     Set-Cookie headers
+*   - the values of JSON attributes, query parameters and key=value pairs
+*     whose name contains password, passwd, secret or token
+*   - the password of the user information of URLs
+ */
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// Replacement of the secrets.
+const redactedText = "****"
+
+// Secrets registered by registerSecret.
+var secrets struct {
+	mutex  sync.Mutex
+	values []string
+}
+
+// Pattern of secrets that are not registered and its replacement.
+type SecretPattern struct {
+	pattern     *regexp.Regexp
+	replacement string
+}
+
+// Secrets that are not registered.
+var secretPatterns = []SecretPattern{
+	{regexp.MustCompile(`(?i)((?:proxy-)?authorization:\s*(?:basic|bearer)?\s*)[^\s,]+`), "${1}" + redactedText},
+	{regexp.MustCompile(`(?i)((?:set-)?cookie:\s*)[^\r\n]+`), "${1}" + redactedText},
+	{regexp.MustCompile(`(?i)("[^"]*(?:password|passwd|secret|token)[^"]*"\s*:\s*)"(?:[^"\\]|\\.)*"`), "${1}\"" + redactedText + "\""},
+	{regexp.MustCompile(`(?i)([\w.-]*(?:password|passwd|secret|token)[\w.-]*=)[^&\s"',;]+`), "${1}" + redactedText},
+	{regexp.MustCompile(`(://[^:/@\s]+:)[^@/\s]+@`), "${1}" + redactedText + "@"},
+}
+
+// Names of log fields whose value is always masked.
+var secretFieldPattern = regexp.MustCompile(`(?i)password|passwd|secret|token|authorization`)
+
+/* Register a secret so that it is masked wherever it appears in the log.
+* value - Secret, ignored when shorter than 4 characters as it would mask
+*         unrelated text
+ */
+func registerSecret(value string) {
+	if len(value) < 4 {
+		return
+	}
+	secrets.mutex.Lock()
+	defer secrets.mutex.Unlock()
+	for _, known := range secrets.values {
+		if known == value {
+			return
+		}
+	}
+	secrets.values = append(secrets.values, value)
+}
+
+/* Return a text with its secrets masked.
+* text - Text to log
+ */
+func redact(text string) string {
+	secrets.mutex.Lock()
+	for _, value := range secrets.values {
+		text = strings.ReplaceAll(text, value, redactedText)
+	}
+	secrets.mutex.Unlock()
+	for _, secret := range secretPatterns {
+		text = secret.pattern.ReplaceAllString(text, secret.replacement)
+	}
+	return text
+}
+
+/* Return a log field with its secrets masked.
+* attr - Field of a log record
+ */
+func redactAttr(attr slog.Attr) slog.Attr {
+	value := attr.Value.Resolve()
+	switch {
+	case value.Kind() == slog.KindGroup:
+		group := []any{}
+		for _, member := range value.Group() {
+			group = append(group, redactAttr(member))
+		}
+		return slog.Group(attr.Key, group...)
+	case secretFieldPattern.MatchString(attr.Key):
+		return slog.String(attr.Key, redactedText)
+	case value.Kind() == slog.KindString:
+		return slog.String(attr.Key, redact(value.String()))
+	case value.Kind() == slog.KindAny:
+		return slog.String(attr.Key, redact(fmt.Sprint(value.Any())))
+	}
+	return attr
+}
+
+// Handler masking the secrets of the records before passing them on.
+type redactingHandler struct {
+	next slog.Handler
+}
+
+func (handler redactingHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return handler.next.Enabled(ctx, level)
+}
+
+func (handler redactingHandler) Handle(ctx context.Context, record slog.Record) error {
+	redacted := slog.NewRecord(record.Time, record.Level, redact(record.Message), record.PC)
+	record.Attrs(func(attr slog.Attr) bool {
+		redacted.AddAttrs(redactAttr(attr))
+		return true
+	})
+	return handler.next.Handle(ctx, redacted)
+}
+
+func (handler redactingHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	redacted := []slog.Attr{}
+	for _, attr := range attrs {
+		redacted = append(redacted, redactAttr(attr))
+	}
+	return redactingHandler{next: handler.next.WithAttrs(redacted)}
+}
+
+func (handler redactingHandler) WithGroup(name string) slog.Handler {
+	return redactingHandler{next: handler.next.WithGroup(name)}
+}
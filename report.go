@@ -0,0 +1,266 @@
+/*
+© Copyright IBM Corporation 2022, 2022
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+/*
+* This file contains the "report" command, which renders transfers into a
+* standalone HTML page for people who do not use the command line. The
+* transfers are those of a run, selected with -run-id from the runId
+* metadata that every transfer submitted by this program carries, so that
+* the report of a batch is:
+*
+*   mft-rest-submit-transfer-go report -html nightly.html -run-id 6f1c...
+*
+* or those whose IDs are given. The page has a summary of the states, a table
+* of the transfers and, for each transfer, the state, bytes and error
+* description of its items. Styles are inline so that the page can be sent
+* by mail or attached to a ticket.
+ */
+package main
+
+import (
+	"html/template"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/tidwall/gjson"
+)
+
+// Transfer shown in an HTML report.
+type ReportTransfer struct {
+	Id               string
+	State            string
+	Class            string
+	Description      string
+	SourceAgent      string
+	DestinationAgent string
+	JobName          string
+	Started          string
+	Ended            string
+	Bytes            string
+	Items            []ItemResult
+}
+
+// Content of an HTML report.
+type Report struct {
+	Title      string
+	Generated  string
+	RunId      string
+	Successful int
+	Partial    int
+	Failed     int
+	Other      int
+	Transfers  []ReportTransfer
+}
+
+// Template of the HTML report.
+var reportTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"stateClass": reportStateClass,
+	"bytes":      formatBytes,
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+body { font-family: Arial, Helvetica, sans-serif; margin: 2em; color: #161616; }
+h1 { font-size: 1.6em; margin-bottom: 0.2em; }
+h2 { font-size: 1.2em; margin-top: 2em; }
+p.meta { color: #525252; margin-top: 0; }
+table { border-collapse: collapse; margin-top: 0.5em; width: 100%; }
+th, td { border: 1px solid #c6c6c6; padding: 0.3em 0.6em; text-align: left; vertical-align: top; }
+th { background: #f4f4f4; }
+td.bytes { text-align: right; white-space: nowrap; }
+.summary span { display: inline-block; margin-right: 1.5em; padding: 0.3em 0.8em; border-radius: 0.3em; }
+.successful { background: #defbe6; }
+.partial { background: #fcf4d6; }
+.failed { background: #fff1f1; }
+.other { background: #edf5ff; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+<p class="meta">Generated {{.Generated}}{{if .RunId}} for run {{.RunId}}{{end}}</p>
+<p class="summary">
+<span class="successful">{{.Successful}} successful</span>
+<span class="partial">{{.Partial}} partially successful</span>
+<span class="failed">{{.Failed}} failed</span>
+<span class="other">{{.Other}} other</span>
+</p>
+<table>
+<tr><th>Transfer</th><th>State</th><th>Source agent</th><th>Destination agent</th><th>Job</th><th>Started</th><th>Ended</th><th>Bytes</th></tr>
+{{- range .Transfers}}
+<tr class="{{.Class}}"><td><a href="#{{.Id}}">{{.Id}}</a></td><td>{{.State}}</td><td>{{.SourceAgent}}</td><td>{{.DestinationAgent}}</td><td>{{.JobName}}</td><td>{{.Started}}</td><td>{{.Ended}}</td><td class="bytes">{{.Bytes}}</td></tr>
+{{- end}}
+</table>
+{{- range .Transfers}}
+<h2 id="{{.Id}}">Transfer {{.Id}}</h2>
+<p class="meta">{{.SourceAgent}} &rarr; {{.DestinationAgent}}, {{.State}}{{if .Description}}: {{.Description}}{{end}}</p>
+{{- if .Items}}
+<table>
+<tr><th>Source</th><th>Destination</th><th>State</th><th>Bytes</th><th>Description</th></tr>
+{{- range .Items}}
+<tr class="{{stateClass .State}}"><td>{{.Source}}</td><td>{{.Destination}}</td><td>{{.State}}</td><td class="bytes">{{bytes .Bytes}}</td><td>{{.Description}}</td></tr>
+{{- end}}
+</table>
+{{- end}}
+{{- end}}
+</body>
+</html>
+`))
+
+// Return the style class of a transfer or item state.
+func reportStateClass(state string) string {
+	switch strings.ToLower(state) {
+	case "successful":
+		return "successful"
+	case "partiallysuccessful":
+		return "partial"
+	case "failed", "cancelled":
+		return "failed"
+	}
+	return "other"
+}
+
+// Command "report" - write an HTML report of transfers.
+func reportCommand(args []string) int {
+	flags := newFlagSet("report")
+	htmlFile := flags.String("html", "", "HTML file to write")
+	runIdFilter := flags.String("run-id", "", "Report the transfers of a run, such as a batch")
+	since := flags.String("since", "", "Only transfers of the run started after a time or a duration before now")
+	title := flags.String("title", "", "Title of the report")
+	routeName := flags.String("route", "", "Route whose MQ Web Server is queried")
+	if !parseCommandLine(flags, args) || len(*htmlFile) == 0 || (len(*runIdFilter) > 0) == (flags.NArg() > 0) ||
+		(len(*since) > 0 && len(*runIdFilter) == 0) {
+		printMessage("reportUsage")
+		return 2
+	}
+	route, err := findRoute(*routeName)
+	if err != nil {
+		printError(err)
+		return 2
+	}
+
+	transferIds := []string{}
+	for _, arg := range flags.Args() {
+		transferId, err := normalizeTransferId(arg)
+		if err != nil {
+			printError(err)
+			return 2
+		}
+		transferIds = append(transferIds, transferId)
+	}
+	if len(*runIdFilter) > 0 {
+		filter := TransferFilter{RunId: *runIdFilter}
+		if len(*since) > 0 {
+			if filter.Since, err = parseTimeFilter(*since, time.Now()); err != nil {
+				printError(err)
+				return 2
+			}
+		}
+		query := filter.query()
+		query.Set("attributes", "*")
+		transfers, found := queryTransfers(strings.TrimSuffix(route.transferUrl(), "/")+"?"+query.Encode(), filter, 0)
+		if !found {
+			return 1
+		}
+		for _, transfer := range transfers {
+			transferIds = append(transferIds, transfer.Get("id").String())
+		}
+	}
+
+	report := Report{Title: *title, Generated: time.Now().Format("2006-01-02 15:04:05 MST"), RunId: *runIdFilter}
+	if len(report.Title) == 0 {
+		report.Title = message("reportTitle")
+	}
+	for _, transferId := range transferIds {
+		// The collection may not give the results of the items
+		transferUrl := transferResourceUrl(route, transferId)
+		response, body, err := callMQWeb("GET", transferUrl+"?attributes=*", "")
+		if err == nil && response.StatusCode != http.StatusOK {
+			err = mqWebError(response, body)
+		}
+		if err != nil {
+			logMessage("statusQueryFailed", []any{logUrl, transferUrl}, transferUrl, err)
+			return 1
+		}
+		transfer := gjson.Get(body, "transfer.0")
+		if !transfer.Exists() {
+			logMessage("transferIdNotFound", []any{logTransferId, transferId}, transferId)
+			return 1
+		}
+		report.add(transfer)
+	}
+
+	if err := report.write(*htmlFile); err != nil {
+		printMessage("reportWriteFailed", *htmlFile, err)
+		return 1
+	}
+	printMessage("reportWritten", len(report.Transfers), *htmlFile)
+	return 0
+}
+
+/* Add a transfer to a report and count its state.
+* transfer - Transfer as returned by the status query
+ */
+func (report *Report) add(transfer gjson.Result) {
+	entry := ReportTransfer{
+		Id:               transfer.Get("id").String(),
+		State:            transfer.Get("status.state").String(),
+		Description:      transfer.Get("status.description").String(),
+		SourceAgent:      transfer.Get("sourceAgent.name").String(),
+		DestinationAgent: transfer.Get("destinationAgent.name").String(),
+		JobName:          transfer.Get("job.name").String(),
+		Started:          reportTime(transfer.Get("statistics.startTime").String()),
+		Ended:            reportTime(transfer.Get("statistics.endTime").String()),
+		Bytes:            formatBytes(transferProgress(transfer).Bytes),
+		Items:            transferItemResults(transfer),
+	}
+	entry.Class = reportStateClass(entry.State)
+	switch entry.Class {
+	case "successful":
+		report.Successful++
+	case "partial":
+		report.Partial++
+	case "failed":
+		report.Failed++
+	default:
+		report.Other++
+	}
+	report.Transfers = append(report.Transfers, entry)
+}
+
+// Return a time of the statistics of a transfer in local time, or as given
+// if it cannot be parsed.
+func reportTime(value string) string {
+	if parsed, err := time.Parse(time.RFC3339, value); err == nil {
+		return parsed.Local().Format("2006-01-02 15:04:05")
+	}
+	return value
+}
+
+/* Render a report to an HTML file.
+* fileName - HTML file to write
+ */
+func (report Report) write(fileName string) error {
+	file, err := os.Create(fileName)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	if err := reportTemplate.Execute(file, report); err != nil {
+		return err
+	}
+	return file.Close()
+}
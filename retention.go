@@ -0,0 +1,122 @@
+/*
+© Copyright IBM Corporation 2022, 2022
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+/*
+* This file contains the retention of released held transfers. A held
+* transfer is moved to the "archive" directory under the state directory when
+* it is released, with the time and result of its release, so that what was
+* held and when it was submitted can be audited. Archived transfers are purged
+* after the number of days set by "retention" in the configuration:
+*
+*   "retention": { "archive": true, "days": 30 }
+*
+* With "archive" false, released transfers are deleted at once. With "days"
+* 0, archived transfers are kept until removed by hand. The "held -archived"
+* command lists the archived transfers.
+ */
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Retention of released held transfers.
+type RetentionPolicy struct {
+	Archive bool `json:"archive"`
+	Days    int  `json:"days"`
+}
+
+// Returns the default retention policy.
+func defaultRetentionPolicy() RetentionPolicy {
+	return RetentionPolicy{Archive: true, Days: 30}
+}
+
+// Returns the directory of the archived held transfers.
+func archiveDirectory() string {
+	return filepath.Join(config.StateDirectory, "archive")
+}
+
+/* Move a held transfer being released to the archive, or delete it if the
+* archive is disabled.
+* held - Transfer being released
+ */
+func archiveHeldTransfer(held HeldTransfer) (HeldTransfer, error) {
+	if !config.Retention.Archive {
+		return held, removeHeldTransfer(held)
+	}
+	now := time.Now()
+	held.ReleasedAt = &now
+	if err := writeArchivedTransfer(held); err != nil {
+		return held, err
+	}
+	return held, removeHeldTransfer(held)
+}
+
+// Write an archived held transfer.
+func writeArchivedTransfer(held HeldTransfer) error {
+	if err := os.MkdirAll(archiveDirectory(), 0700); err != nil {
+		return err
+	}
+	content, err := json.MarshalIndent(held, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(archiveDirectory(), held.Id+".json"), content, 0600)
+}
+
+/* Record the result of the release of an archived held transfer.
+* held   - Archived transfer
+* result - Result of the submission
+ */
+func recordReleaseResult(held HeldTransfer, result string) {
+	if held.ReleasedAt == nil {
+		return
+	}
+	held.ReleaseResult = result
+	if err := writeArchivedTransfer(held); err != nil {
+		printMessage("archiveWriteFailed", held.Id, err)
+	}
+}
+
+/* Delete the archived transfers released longer ago than the retention
+* period.
+* now - Current time
+ */
+func purgeArchivedTransfers(now time.Time) {
+	if config.Retention.Days <= 0 {
+		return
+	}
+	archived, err := readHeldTransfersIn(archiveDirectory())
+	if err != nil {
+		printMessage("heldReadFailed", err)
+		return
+	}
+	horizon := now.AddDate(0, 0, -config.Retention.Days)
+	purged := 0
+	for _, held := range archived {
+		if held.ReleasedAt == nil || held.ReleasedAt.After(horizon) {
+			continue
+		}
+		if err := os.Remove(filepath.Join(archiveDirectory(), held.Id+".json")); err != nil {
+			printMessage("heldRemoveFailed", held.Id, err)
+			continue
+		}
+		purged++
+	}
+	if purged > 0 {
+		printMessage("archivePurged", purged, config.Retention.Days)
+	}
+}
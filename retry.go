@@ -0,0 +1,154 @@
+/*
+© Copyright IBM Corporation 2022, 2022
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+/*
+* This file contains the retry of requests to the MQ Web Server that fail
+* because of a transient problem: the connection is refused or times out, or
+* the server or a gateway answers 502, 503 or 504. The delay between attempts
+* grows exponentially from initialDelay up to maxDelay, and a random jitter is
+* applied so that many clients do not retry at the same moment:
+*
+*   "retry": { "maxAttempts": 3, "initialDelay": "1s", "maxDelay": "30s" }
+*
+* maxAttempts counts the first attempt, 1 disables retries.
+*
+* A server throttling its clients answers 429 Too Many Requests or 503 with a
+* Retry-After header, in seconds or as a date. The request is then retried
+* after the delay asked by the server instead of the backoff, unless the delay
+* is longer than maxRetryAfter (default 5m), in which case the request fails.
+*
+* A POST that may have reached the MQ Web Server is not retried, as that
+* could submit the same transfer twice. A POST is only retried when the
+* connection could not be established or the server answered 503 Service
+* Unavailable, which means the request was not processed.
+ */
+package main
+
+import (
+	"errors"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// Retry policy of requests to the MQ Web Server.
+type RetryPolicy struct {
+	MaxAttempts   int      `json:"maxAttempts"`
+	InitialDelay  Duration `json:"initialDelay"`
+	MaxDelay      Duration `json:"maxDelay"`
+	MaxRetryAfter Duration `json:"maxRetryAfter"`
+}
+
+// Returns the default retry policy.
+func defaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts:   3,
+		InitialDelay:  Duration(time.Second),
+		MaxDelay:      Duration(30 * time.Second),
+		MaxRetryAfter: Duration(5 * time.Minute),
+	}
+}
+
+/* Check whether a failed request is worth retrying.
+* httpVerb - Method of the request
+* response - Response received, nil if the request failed
+* err      - Error of the request
+ */
+func isRetryable(httpVerb string, response *http.Response, err error) bool {
+	idempotent := httpVerb != "POST"
+	if err != nil {
+		if isConnectError(err) {
+			return true
+		}
+		var netError net.Error
+		return idempotent && errors.As(err, &netError) && netError.Timeout()
+	}
+	switch response.StatusCode {
+	case http.StatusTooManyRequests, http.StatusServiceUnavailable:
+		return true
+	case http.StatusBadGateway, http.StatusGatewayTimeout:
+		return idempotent
+	}
+	return false
+}
+
+// Check if an error happened while establishing the connection, before the
+// request was sent.
+func isConnectError(err error) bool {
+	if errors.Is(err, syscall.ECONNREFUSED) {
+		return true
+	}
+	var opError *net.OpError
+	return errors.As(err, &opError) && opError.Op == "dial"
+}
+
+/* Return the delay before the next attempt, and false if the request must
+* not be retried because the server asks to wait longer than allowed.
+* attempt  - Number of the failed attempt, from 1
+* response - Response of the failed attempt, nil if the request failed
+ */
+func (policy RetryPolicy) nextDelay(attempt int, response *http.Response) (time.Duration, bool) {
+	if response != nil && (response.StatusCode == http.StatusTooManyRequests || response.StatusCode == http.StatusServiceUnavailable) {
+		if delay, found := retryAfter(response.Header.Get("Retry-After"), time.Now()); found {
+			return delay, delay <= time.Duration(policy.MaxRetryAfter)
+		}
+	}
+	return policy.delay(attempt), true
+}
+
+/* Parse a Retry-After header, a number of seconds or a HTTP date.
+* value - Value of the header
+* now   - Current time
+ */
+func retryAfter(value string, now time.Time) (time.Duration, bool) {
+	value = strings.TrimSpace(value)
+	if len(value) == 0 {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			seconds = 0
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+	date, err := http.ParseTime(value)
+	if err != nil {
+		return 0, false
+	}
+	if delay := date.Sub(now); delay > 0 {
+		return delay, true
+	}
+	return 0, true
+}
+
+/* Return the delay before an attempt: exponential backoff with jitter, a
+* random delay between half and all of the backoff.
+* attempt - Number of the failed attempt, from 1
+ */
+func (policy RetryPolicy) delay(attempt int) time.Duration {
+	delay := time.Duration(policy.InitialDelay)
+	for i := 1; i < attempt && delay < time.Duration(policy.MaxDelay); i++ {
+		delay *= 2
+	}
+	if delay > time.Duration(policy.MaxDelay) {
+		delay = time.Duration(policy.MaxDelay)
+	}
+	if delay <= 0 {
+		return 0
+	}
+	return delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1))
+}
@@ -0,0 +1,124 @@
+/*
+© Copyright IBM Corporation 2022, 2022
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+/*
+* This file contains the run ID, a UUID generated for each invocation of the
+* program so that a submission can be traced across the output, held
+* transfers, alerts and MFT records before the transfer ID exists, and even if
+* the transfer is never submitted. The run ID is:
+*   - printed when a transfer is submitted
+*   - sent in the user defined metadata of the transfer under the key runId
+*   - recorded with held transfers
+*   - passed to the alert command in MFT_ALERT_RUN_ID
+*   - written to the -result file of the submit command
+*
+* A scheduler or script running the program several times for one job can
+* set the MFT_RUN_ID environment variable to use its own ID instead.
+ */
+package main
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// Metadata key of the run ID.
+const runIdMetadataKey = "runId"
+
+// Run ID printed by -dry-run in place of the run ID, which changes with
+// every run, so that the request of two runs can be compared.
+const dryRunIdPlaceholder = "dry-run"
+
+// ID of this invocation of the program.
+var runId = newRunId()
+
+// Return the run ID set in the environment, or a new random UUID.
+func newRunId() string {
+	if id := strings.TrimSpace(os.Getenv("MFT_RUN_ID")); len(id) > 0 {
+		return id
+	}
+	var uuid [16]byte
+	if _, err := rand.Read(uuid[:]); err != nil {
+		// Unique enough to trace a run without a random source
+		return fmt.Sprintf("%d-%d", time.Now().UnixNano(), os.Getpid())
+	}
+	// Version 4, variant RFC 4122
+	uuid[6] = uuid[6]&0x0f | 0x40
+	uuid[8] = uuid[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", uuid[0:4], uuid[4:6], uuid[6:8], uuid[8:10], uuid[10:])
+}
+
+/* Return metadata with the run ID added.
+* metadata - Metadata of the route, not modified
+ */
+func withRunIdMetadata(metadata map[string]string) map[string]string {
+	merged := map[string]string{}
+	for key, value := range metadata {
+		merged[key] = value
+	}
+	merged[runIdMetadataKey] = runId
+	return merged
+}
+
+/* Return a route whose metadata holds the placeholder of -dry-run instead of
+* the run ID.
+* route - Route to submit, not modified
+ */
+func withDryRunId(route Route) Route {
+	if _, found := route.Metadata[runIdMetadataKey]; !found {
+		return route
+	}
+	metadata := map[string]string{}
+	for key, value := range route.Metadata {
+		metadata[key] = value
+	}
+	metadata[runIdMetadataKey] = dryRunIdPlaceholder
+	route.Metadata = metadata
+	return route
+}
+
+// Result of the submit command, written with -result.
+type SubmissionResult struct {
+	RunId      string    `json:"runId"`
+	Route      string    `json:"route"`
+	Time       time.Time `json:"time"`
+	Outcome    string    `json:"outcome"`
+	TransferId string    `json:"transferId,omitempty"`
+	State      string    `json:"state,omitempty"`
+	HeldId     string    `json:"heldId,omitempty"`
+	Message    string    `json:"message,omitempty"`
+}
+
+/* Write the result of the submit command to a JSON file. Nothing is written
+* for a blank file name.
+* fileName - JSON file to write
+* result   - Result of the submission
+ */
+func writeSubmissionResult(fileName string, result SubmissionResult) {
+	if len(fileName) == 0 {
+		return
+	}
+	result.RunId = runId
+	result.Time = time.Now()
+	content, err := json.MarshalIndent(result, "", "  ")
+	if err == nil {
+		err = os.WriteFile(fileName, append(content, '\n'), 0644)
+	}
+	if err != nil {
+		printMessage("resultWriteFailed", fileName, err)
+	}
+}
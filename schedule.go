@@ -0,0 +1,208 @@
+/*
+© Copyright IBM Corporation 2022, 2022
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+/*
+* This file contains the scheduled transfers, lodged with the source agent
+* now and started by it later. The "schedule" of a route, or the -start-time,
+* -time-base and -timezone flags of the submit command, give the start time:
+*
+*   "schedule": { "startTime": "2026-10-18T09:00", "timeBase": "admin", "timezone": "Europe/London" }
+*
+* startTime is a local time, yyyy-MM-ddThh:mm, in the time base: admin for the
+* time zone of this host, or of timezone when set, source for the time zone
+* of the source agent, or UTC. A scheduled transfer has no status until it
+* starts, so the submit command does not wait for it.
+*
+* A scheduled transfer is repeated every frequency minutes, hours, days, weeks,
+* months or years, until an end time in the same time base or for a count of
+* occurrences, or forever when neither is set:
+*
+*   "schedule": { "startTime": "2026-10-18T09:00",
+*                 "repeat": { "frequency": 2, "interval": "hours", "count": 12 } }
+*
+* The -repeat-every flag of the submit command gives the frequency and
+* interval as 30m, 2h, 1d, 1w or 6months, and -repeat-count or -repeat-until
+* the end of the repetitions.
+ */
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	j "github.com/ricardolonga/jsongo"
+)
+
+// Layout of the start time of a scheduled transfer.
+const scheduleTimeLayout = "2006-01-02T15:04"
+
+// Time bases of the start time of a scheduled transfer.
+var scheduleTimeBases = map[string]bool{"admin": true, "source": true, "UTC": true}
+
+// Intervals of the repetitions of a scheduled transfer, by their names and
+// abbreviations in -repeat-every.
+var repeatIntervals = map[string]string{
+	"m":       "minutes",
+	"min":     "minutes",
+	"minutes": "minutes",
+	"h":       "hours",
+	"hours":   "hours",
+	"d":       "days",
+	"days":    "days",
+	"w":       "weeks",
+	"weeks":   "weeks",
+	"months":  "months",
+	"y":       "years",
+	"years":   "years",
+}
+
+// Start and repetitions of a scheduled transfer.
+type TransferSchedule struct {
+	StartTime string          `json:"startTime"`
+	TimeBase  string          `json:"timeBase"`
+	Timezone  string          `json:"timezone"`
+	Repeat    *ScheduleRepeat `json:"repeat"`
+}
+
+// Repetitions of a scheduled transfer. Count and EndTime are exclusive.
+type ScheduleRepeat struct {
+	Frequency int    `json:"frequency"`
+	Interval  string `json:"interval"`
+	Count     int    `json:"count"`
+	EndTime   string `json:"endTime"`
+}
+
+/* Parse the frequency and interval of -repeat-every, a number followed by an
+* interval such as 30m, 2h or 6months.
+* value - Value of the flag
+ */
+func parseRepeatEvery(value string) (int, string, error) {
+	digits := strings.IndexFunc(value, func(r rune) bool { return r < '0' || r > '9' })
+	if digits <= 0 {
+		return 0, "", fmt.Errorf("repeat interval %q must be a number followed by m, h, d, w, months or y", value)
+	}
+	frequency, err := strconv.Atoi(value[:digits])
+	interval, found := repeatIntervals[strings.ToLower(value[digits:])]
+	if err != nil || !found {
+		return 0, "", fmt.Errorf("repeat interval %q must be a number followed by m, h, d, w, months or y", value)
+	}
+	return frequency, interval, nil
+}
+
+// Return the schedule section of a transfer request.
+func (schedule TransferSchedule) jsonObject() j.O {
+	object := j.Object().Put("startTime", schedule.StartTime)
+	if len(schedule.TimeBase) > 0 {
+		object.Put("timeBase", schedule.TimeBase)
+	}
+	if len(schedule.Timezone) > 0 {
+		object.Put("timezone", schedule.Timezone)
+	}
+	if repeat := schedule.Repeat; repeat != nil {
+		repeatObject := j.Object().Put("frequency", repeat.Frequency)
+		repeatObject.Put("interval", repeat.Interval)
+		if repeat.Count > 0 {
+			repeatObject.Put("count", repeat.Count)
+		}
+		if len(repeat.EndTime) > 0 {
+			repeatObject.Put("endTime", repeat.EndTime)
+		}
+		object.Put("repeat", repeatObject)
+	}
+	return object
+}
+
+// Return the time base of a schedule, admin by default.
+func (schedule TransferSchedule) timeBase() string {
+	if len(schedule.TimeBase) == 0 {
+		return "admin"
+	}
+	return schedule.TimeBase
+}
+
+/* Return the start time of a schedule in its time base, and false if the
+* time base is that of the source agent, unknown to this host.
+ */
+func (schedule TransferSchedule) start() (time.Time, bool, error) {
+	location := time.Local
+	switch {
+	case schedule.timeBase() == "source":
+		return time.Time{}, false, nil
+	case schedule.TimeBase == "UTC":
+		location = time.UTC
+	case len(schedule.Timezone) > 0:
+		var err error
+		if location, err = time.LoadLocation(schedule.Timezone); err != nil {
+			return time.Time{}, false, err
+		}
+	}
+	start, err := time.ParseInLocation(scheduleTimeLayout, schedule.StartTime, location)
+	return start, err == nil, err
+}
+
+/* Check the schedule of a transfer, if set. A start time that is known on
+* this host must not be in the past.
+* schedule - Schedule of the transfer
+* now      - Current time
+ */
+func validateSchedule(schedule *TransferSchedule, now time.Time) error {
+	if schedule == nil {
+		return nil
+	}
+	var problems requestProblems
+	pointer := func(name string) string {
+		return jsonPointer("schedule", name)
+	}
+	if !scheduleTimeBases[schedule.TimeBase] && len(schedule.TimeBase) > 0 {
+		problems.add(pointer("timeBase"), schedule.TimeBase, "must be admin, source or UTC")
+	}
+	if len(schedule.Timezone) > 0 {
+		if schedule.TimeBase == "source" || schedule.TimeBase == "UTC" {
+			problems.add(pointer("timezone"), schedule.Timezone, "only applies to the admin time base")
+		} else if _, err := time.LoadLocation(schedule.Timezone); err != nil {
+			problems.add(pointer("timezone"), schedule.Timezone, "is not a time zone such as Europe/London")
+		}
+	}
+	if _, err := time.Parse(scheduleTimeLayout, schedule.StartTime); err != nil {
+		problems.add(pointer("startTime"), schedule.StartTime, "must be a time such as 2026-10-18T09:00")
+	} else if start, known, err := schedule.start(); err == nil && known && start.Before(now.Truncate(time.Minute)) {
+		problems.add(pointer("startTime"), schedule.StartTime, "is in the past")
+	}
+	if repeat := schedule.Repeat; repeat != nil {
+		repeatPointer := func(name string) string {
+			return jsonPointer("schedule", "repeat", name)
+		}
+		if repeat.Frequency < 1 {
+			problems.add(repeatPointer("frequency"), repeat.Frequency, "must be at least 1")
+		}
+		if _, found := repeatIntervals[repeat.Interval]; !found || repeat.Interval != repeatIntervals[repeat.Interval] {
+			problems.add(repeatPointer("interval"), repeat.Interval, "must be minutes, hours, days, weeks, months or years")
+		}
+		if repeat.Count < 0 {
+			problems.add(repeatPointer("count"), repeat.Count, "must be at least 1")
+		}
+		if len(repeat.EndTime) > 0 {
+			if repeat.Count > 0 {
+				problems.add(repeatPointer("endTime"), repeat.EndTime, "cannot be set with a count")
+			}
+			if _, err := time.Parse(scheduleTimeLayout, repeat.EndTime); err != nil {
+				problems.add(repeatPointer("endTime"), repeat.EndTime, "must be a time such as 2026-10-18T09:00")
+			} else if repeat.EndTime <= schedule.StartTime {
+				problems.add(repeatPointer("endTime"), repeat.EndTime, "is not after the start time")
+			}
+		}
+	}
+	return problems.err()
+}
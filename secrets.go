@@ -0,0 +1,303 @@
+/*
+© Copyright IBM Corporation 2022, 2022
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+/*
+* This file contains the credential providers for cloud secrets managers.
+* The providers call the REST APIs of the secrets managers directly:
+*
+*   awssm://SECRET_ID[?region=REGION][#KEY]
+*     AWS Secrets Manager. SECRET_ID is a name or an ARN, taken as it is.
+*     Credentials are taken from AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY and
+*     AWS_SESSION_TOKEN, region from AWS_REGION or AWS_DEFAULT_REGION unless
+*     given in the reference.
+*   azkv://VAULT/SECRET[/VERSION][#KEY]
+*     Azure Key Vault. The access token is taken from AZURE_ACCESS_TOKEN or
+*     requested from the managed identity endpoint.
+*   gcpsm://PROJECT/SECRET[/VERSION][#KEY]
+*     GCP Secret Manager. The access token is taken from
+*     GOOGLE_OAUTH_ACCESS_TOKEN or requested from the metadata server.
+*   ibmsm://INSTANCE.REGION/SECRET_ID[#KEY]
+*     IBM Cloud Secrets Manager. An IAM token is requested with the API key
+*     in IBMCLOUD_API_KEY.
+*
+* If the secret value is a JSON object, KEY selects the attribute holding
+* the password.
+ */
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	j "github.com/ricardolonga/jsongo"
+	"github.com/tidwall/gjson"
+)
+
+// Client used to call the secrets managers.
+var secretsClient = &http.Client{Timeout: 30 * time.Second}
+
+/* Issue a HTTP request to a secrets manager and return the response body.
+* request - HTTP request
+ */
+func callSecretsManager(request *http.Request) (string, error) {
+	response, err := secretsClient.Do(request)
+	if err != nil {
+		return "", err
+	}
+	defer response.Body.Close()
+	body, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return "", err
+	}
+	if response.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("%s returned %s: %s", request.URL.Host, response.Status, strings.TrimSpace(string(body)))
+	}
+	return string(body), nil
+}
+
+/* Select the password from a secret value.
+* secret - Secret value
+* key    - Attribute of a JSON secret value holding the password, or blank
+ */
+func selectSecretKey(secret string, key string) (string, error) {
+	if len(key) == 0 {
+		return secret, nil
+	}
+	value := gjson.Get(secret, strings.NewReplacer(".", `\.`, "*", `\*`, "?", `\?`).Replace(key))
+	if !value.Exists() {
+		return "", fmt.Errorf("secret has no attribute %s", key)
+	}
+	return value.String(), nil
+}
+
+// Return the path segments of a reference.
+func referencePath(reference *url.URL) []string {
+	return strings.Split(strings.Trim(reference.Path, "/"), "/")
+}
+
+// Credential provider backed by AWS Secrets Manager.
+type awsSecretsManagerProvider struct{}
+
+func (awsSecretsManagerProvider) Password(reference *url.URL, userId string) (string, error) {
+	secretId := strings.TrimPrefix(reference.Host+reference.Path, "/")
+	region := reference.Query().Get("region")
+	if len(region) == 0 {
+		region = os.Getenv("AWS_REGION")
+	}
+	if len(region) == 0 {
+		region = os.Getenv("AWS_DEFAULT_REGION")
+	}
+	accessKey, secretKey := os.Getenv("AWS_ACCESS_KEY_ID"), os.Getenv("AWS_SECRET_ACCESS_KEY")
+	if len(region) == 0 || len(accessKey) == 0 || len(secretKey) == 0 {
+		return "", fmt.Errorf("AWS region and credentials must be set in the environment")
+	}
+
+	body := j.Object().Put("SecretId", secretId).String()
+	host := "secretsmanager." + region + ".amazonaws.com"
+	request, err := http.NewRequest("POST", "https://"+host+"/", strings.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	request.Header.Set("Content-Type", "application/x-amz-json-1.1")
+	request.Header.Set("X-Amz-Target", "secretsmanager.GetSecretValue")
+	if token := os.Getenv("AWS_SESSION_TOKEN"); len(token) > 0 {
+		request.Header.Set("X-Amz-Security-Token", token)
+	}
+	signAwsRequest(request, body, region, "secretsmanager", accessKey, secretKey, time.Now().UTC())
+
+	response, err := callSecretsManager(request)
+	if err != nil {
+		return "", err
+	}
+	return selectSecretKey(gjson.Get(response, "SecretString").String(), reference.Fragment)
+}
+
+/* Sign a request with AWS Signature Version 4.
+* request   - Request to sign. Host and X-Amz-* headers must be set.
+* body      - Body of the request
+* region    - AWS region
+* service   - AWS service name
+* accessKey - AWS access key id
+* secretKey - AWS secret access key
+* now       - Signing time in UTC
+ */
+func signAwsRequest(request *http.Request, body string, region string, service string, accessKey string, secretKey string, now time.Time) {
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	request.Header.Set("X-Amz-Date", amzDate)
+
+	payloadHash := sha256.Sum256([]byte(body))
+	signedHeaders := []string{"content-type", "host", "x-amz-date", "x-amz-target"}
+	canonicalHeaders := "content-type:" + request.Header.Get("Content-Type") + "\n" +
+		"host:" + request.URL.Host + "\n" +
+		"x-amz-date:" + amzDate + "\n" +
+		"x-amz-target:" + request.Header.Get("X-Amz-Target") + "\n"
+	if token := request.Header.Get("X-Amz-Security-Token"); len(token) > 0 {
+		signedHeaders = append(signedHeaders[:3], "x-amz-security-token", "x-amz-target")
+		canonicalHeaders = strings.Replace(canonicalHeaders, "x-amz-target:", "x-amz-security-token:"+token+"\nx-amz-target:", 1)
+	}
+	canonicalRequest := strings.Join([]string{
+		request.Method, "/", "", canonicalHeaders, strings.Join(signedHeaders, ";"), hex.EncodeToString(payloadHash[:]),
+	}, "\n")
+
+	scope := date + "/" + region + "/" + service + "/aws4_request"
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(requestHash[:])
+
+	key := hmacSHA256([]byte("AWS4"+secretKey), date)
+	key = hmacSHA256(key, region)
+	key = hmacSHA256(key, service)
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	request.Header.Set("Authorization", "AWS4-HMAC-SHA256 Credential="+accessKey+"/"+scope+
+		", SignedHeaders="+strings.Join(signedHeaders, ";")+", Signature="+signature)
+}
+
+// Return the HMAC-SHA256 of data with the given key.
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// Credential provider backed by Azure Key Vault.
+type azureKeyVaultProvider struct{}
+
+func (azureKeyVaultProvider) Password(reference *url.URL, userId string) (string, error) {
+	token := os.Getenv("AZURE_ACCESS_TOKEN")
+	if len(token) == 0 {
+		var err error
+		if token, err = azureManagedIdentityToken(); err != nil {
+			return "", err
+		}
+	}
+	secretUrl := "https://" + reference.Host + ".vault.azure.net/secrets/" + strings.Join(referencePath(reference), "/") + "?api-version=7.4"
+	request, err := http.NewRequest("GET", secretUrl, nil)
+	if err != nil {
+		return "", err
+	}
+	request.Header.Set("Authorization", "Bearer "+token)
+	response, err := callSecretsManager(request)
+	if err != nil {
+		return "", err
+	}
+	return selectSecretKey(gjson.Get(response, "value").String(), reference.Fragment)
+}
+
+// Request an access token for Key Vault from the Azure managed identity endpoint.
+func azureManagedIdentityToken() (string, error) {
+	request, err := http.NewRequest("GET", "http://169.254.169.254/metadata/identity/oauth2/token?api-version=2018-02-01&resource=https%3A%2F%2Fvault.azure.net", nil)
+	if err != nil {
+		return "", err
+	}
+	request.Header.Set("Metadata", "true")
+	response, err := callSecretsManager(request)
+	if err != nil {
+		return "", fmt.Errorf("AZURE_ACCESS_TOKEN is not set and managed identity is not available: %v", err)
+	}
+	return gjson.Get(response, "access_token").String(), nil
+}
+
+// Credential provider backed by GCP Secret Manager.
+type gcpSecretManagerProvider struct{}
+
+func (gcpSecretManagerProvider) Password(reference *url.URL, userId string) (string, error) {
+	token := os.Getenv("GOOGLE_OAUTH_ACCESS_TOKEN")
+	if len(token) == 0 {
+		var err error
+		if token, err = gcpMetadataToken(); err != nil {
+			return "", err
+		}
+	}
+	path := referencePath(reference)
+	version := "latest"
+	if len(path) > 1 {
+		version = path[1]
+	}
+	secretUrl := "https://secretmanager.googleapis.com/v1/projects/" + reference.Host + "/secrets/" + path[0] + "/versions/" + version + ":access"
+	request, err := http.NewRequest("GET", secretUrl, nil)
+	if err != nil {
+		return "", err
+	}
+	request.Header.Set("Authorization", "Bearer "+token)
+	response, err := callSecretsManager(request)
+	if err != nil {
+		return "", err
+	}
+	secret, err := base64.StdEncoding.DecodeString(gjson.Get(response, "payload.data").String())
+	if err != nil {
+		return "", err
+	}
+	return selectSecretKey(string(secret), reference.Fragment)
+}
+
+// Request an access token from the GCP metadata server.
+func gcpMetadataToken() (string, error) {
+	request, err := http.NewRequest("GET", "http://metadata.google.internal/computeMetadata/v1/instance/service-accounts/default/token", nil)
+	if err != nil {
+		return "", err
+	}
+	request.Header.Set("Metadata-Flavor", "Google")
+	response, err := callSecretsManager(request)
+	if err != nil {
+		return "", fmt.Errorf("GOOGLE_OAUTH_ACCESS_TOKEN is not set and the metadata server is not available: %v", err)
+	}
+	return gjson.Get(response, "access_token").String(), nil
+}
+
+// Credential provider backed by IBM Cloud Secrets Manager.
+type ibmSecretsManagerProvider struct{}
+
+func (ibmSecretsManagerProvider) Password(reference *url.URL, userId string) (string, error) {
+	apiKey := os.Getenv("IBMCLOUD_API_KEY")
+	if len(apiKey) == 0 {
+		return "", fmt.Errorf("IBMCLOUD_API_KEY must be set in the environment")
+	}
+	form := url.Values{"grant_type": {"urn:ibm:params:oauth:grant-type:apikey"}, "apikey": {apiKey}}
+	tokenRequest, err := http.NewRequest("POST", "https://iam.cloud.ibm.com/identity/token", strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	tokenRequest.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	tokenResponse, err := callSecretsManager(tokenRequest)
+	if err != nil {
+		return "", err
+	}
+
+	secretUrl := "https://" + reference.Host + ".secrets-manager.appdomain.cloud/api/v2/secrets/" + referencePath(reference)[0]
+	request, err := http.NewRequest("GET", secretUrl, nil)
+	if err != nil {
+		return "", err
+	}
+	request.Header.Set("Authorization", "Bearer "+gjson.Get(tokenResponse, "access_token").String())
+	response, err := callSecretsManager(request)
+	if err != nil {
+		return "", err
+	}
+	// Arbitrary secrets hold the value in payload, user credentials in password
+	secret := gjson.Get(response, "payload")
+	if !secret.Exists() {
+		secret = gjson.Get(response, "password")
+	}
+	return selectSecretKey(secret.String(), reference.Fragment)
+}
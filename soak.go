@@ -0,0 +1,313 @@
+/*
+© Copyright IBM Corporation 2022, 2022
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+/*
+* This file contains the "soak" command, which submits small synthetic
+* transfers for a route at a fixed interval until stopped, and reports the
+* latency of successful transfers, from submission to completion, as
+* percentiles. It gives a baseline of the health of the network and agents.
+*
+* With -source-dir a file of -size bytes is created in the directory for each
+* transfer and deleted when the transfer ends. The directory must be readable
+* by the source agent, so the command is normally run on the source agent
+* host. Without -source-dir the source item of the route is transferred as is.
+*
+* With -results each transfer is appended to a CSV file with the columns
+* time, transfer_id, state, latency_ms and error.
+ */
+package main
+
+import (
+	"crypto/rand"
+	"encoding/csv"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/tidwall/gjson"
+)
+
+// Interval between status queries of a synthetic transfer.
+const soakPollInterval = 500 * time.Millisecond
+
+// Outcome of a transfer submitted by submitAndAwait.
+type transferOutcome struct {
+	Started    time.Time
+	TransferId string
+	State      string
+	Latency    time.Duration
+	Err        error
+}
+
+// Statistics of a soak test.
+type soakStatistics struct {
+	submitted  int
+	successful int
+	failed     int
+	latencies  []time.Duration
+}
+
+// Command "soak" - submit synthetic transfers continuously and report latency.
+func soakCommand(args []string) int {
+	flags := newFlagSet("soak")
+	routeName := flags.String("route", "", "Name of the route to transfer")
+	interval := flags.Duration("interval", 10*time.Second, "Time between submissions")
+	duration := flags.Duration("duration", 0, "Time to run, 0 to run until interrupted")
+	size := flags.Int("size", 1024, "Size in bytes of the synthetic files")
+	sourceDir := flags.String("source-dir", "", "Directory in which synthetic source files are created")
+	timeout := flags.Duration("timeout", 5*time.Minute, "Time allowed for a transfer to complete")
+	maxInFlight := flags.Int("max-in-flight", 4, "Maximum number of transfers in flight")
+	reportInterval := flags.Duration("report", time.Minute, "Time between latency reports")
+	resultsFile := flags.String("results", "", "Append the result of each transfer to a CSV file")
+	if !parseCommandLine(flags, args) || flags.NArg() != 0 || *interval <= 0 || *reportInterval <= 0 || *size < 0 {
+		printMessage("soakUsage")
+		return 2
+	}
+	route, err := findSubmissionRoute(*routeName, nil)
+	if err != nil {
+		printError(err)
+		return 2
+	}
+	// Resolve the password and build the client before transfers run concurrently
+	if _, err := webPassword(); err != nil {
+		printMessage("submitFailed", route.transferUrl(), err)
+		return 1
+	}
+	if _, err := webClient(); err != nil {
+		printMessage("submitFailed", route.transferUrl(), err)
+		return 1
+	}
+	var results *csv.Writer
+	if len(*resultsFile) > 0 {
+		file, err := openSoakResults(*resultsFile)
+		if err != nil {
+			printMessage("soakResultsWriteFailed", *resultsFile, err)
+			return 1
+		}
+		defer file.Close()
+		results = csv.NewWriter(file)
+		defer results.Flush()
+	}
+
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	defer signal.Stop(interrupt)
+	var deadline <-chan time.Time
+	if *duration > 0 {
+		deadline = time.After(*duration)
+	}
+	submitTicker := time.NewTicker(*interval)
+	defer submitTicker.Stop()
+	reportTicker := time.NewTicker(*reportInterval)
+	defer reportTicker.Stop()
+
+	printMessage("soakStarted", route.Name, *interval)
+	finished := make(chan transferOutcome)
+	statistics := &soakStatistics{}
+	inFlight := 0
+	submit := func() {
+		if inFlight >= *maxInFlight {
+			printMessage("soakSkipped", inFlight)
+			return
+		}
+		inFlight++
+		statistics.submitted++
+		go func(sequence int) {
+			finished <- runSoakTransfer(route, sequence, *size, *sourceDir, *timeout)
+		}(statistics.submitted)
+	}
+	record := func(result transferOutcome) {
+		inFlight--
+		statistics.add(result)
+		if result.Err != nil || result.State != "successful" {
+			reason := result.State
+			if result.Err != nil {
+				reason = result.Err.Error()
+			}
+			printMessage("soakTransferFailed", result.TransferId, reason)
+		}
+		if results != nil {
+			results.Write(result.csvRecord())
+			results.Flush()
+		}
+	}
+
+	submit()
+	running := true
+	for running {
+		select {
+		case <-submitTicker.C:
+			submit()
+		case result := <-finished:
+			record(result)
+		case <-reportTicker.C:
+			statistics.report(inFlight)
+		case <-interrupt:
+			running = false
+		case <-deadline:
+			running = false
+		}
+	}
+	if inFlight > 0 {
+		printMessage("soakWaiting", inFlight)
+	}
+	for inFlight > 0 {
+		record(<-finished)
+	}
+	statistics.report(0)
+	if statistics.failed > 0 {
+		return 1
+	}
+	return 0
+}
+
+/* Submit a soak transfer and wait for it to end.
+* route     - Route of the transfer
+* sequence  - Sequence number of the transfer in the soak test
+* size      - Size of the synthetic source file
+* sourceDir - Directory of the synthetic source file, blank to use the route source
+* timeout   - Time allowed for the transfer to complete
+ */
+func runSoakTransfer(route Route, sequence int, size int, sourceDir string, timeout time.Duration) transferOutcome {
+	if len(sourceDir) > 0 {
+		fileName, err := createSyntheticFile(sourceDir, fmt.Sprintf("mft-soak-%d-%d.dat", os.Getpid(), sequence), size)
+		if err != nil {
+			return transferOutcome{Started: time.Now(), Err: err}
+		}
+		defer os.Remove(fileName)
+		route.SourceItem = fileName
+		route.SourceItemType = "file"
+		route.Items = nil
+	}
+	return submitAndAwait(route, timeout)
+}
+
+/* Submit a transfer for a route and wait for it to end, without printing.
+* route   - Route of the transfer
+* timeout - Time allowed for the transfer to complete
+ */
+func submitAndAwait(route Route, timeout time.Duration) transferOutcome {
+	outcome := transferOutcome{Started: time.Now()}
+	response, _, err := callMQWeb("POST", route.transferUrl(), buildTransferJsonRequest(route))
+	if err != nil {
+		outcome.Err = err
+		return outcome
+	}
+	if response.StatusCode != http.StatusAccepted {
+		outcome.Err = fmt.Errorf("submission returned %s", response.Status)
+		return outcome
+	}
+	transferUrl := response.Header.Get("location")
+	outcome.TransferId = path.Base(transferUrl)
+
+	for time.Since(outcome.Started) < timeout {
+		time.Sleep(soakPollInterval)
+		// Not cached, a cached status would add to the latency measured
+		response, body, err := callMQWebUncached("GET", transferUrl+"?attributes=status", "")
+		// The transfer is not known until the source agent has started it
+		if err != nil || response.StatusCode != http.StatusOK {
+			continue
+		}
+		outcome.State = gjson.Get(body, "transfer.0.status.state").String()
+		if isTerminalTransferState(outcome.State) {
+			outcome.Latency = time.Since(outcome.Started)
+			return outcome
+		}
+	}
+	outcome.Err = fmt.Errorf("not complete after %v", timeout)
+	return outcome
+}
+
+/* Create a synthetic source file of random content. Returns the absolute
+* path of the file.
+* directory - Directory of the file
+* name      - Name of the file
+* size      - Size of the file in bytes
+ */
+func createSyntheticFile(directory string, name string, size int) (string, error) {
+	fileName, err := filepath.Abs(filepath.Join(directory, name))
+	if err != nil {
+		return "", err
+	}
+	content := make([]byte, size)
+	if _, err := rand.Read(content); err != nil {
+		return "", err
+	}
+	return fileName, os.WriteFile(fileName, content, 0644)
+}
+
+// Open the soak results file for appending, writing the header to a new file.
+func openSoakResults(fileName string) (*os.File, error) {
+	file, err := os.OpenFile(fileName, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, err
+	}
+	if info, err := file.Stat(); err == nil && info.Size() == 0 {
+		writer := csv.NewWriter(file)
+		writer.Write([]string{"time", "transfer_id", "state", "latency_ms", "error"})
+		writer.Flush()
+	}
+	return file, nil
+}
+
+// Return the CSV record of a transfer outcome.
+func (result transferOutcome) csvRecord() []string {
+	latency, reason := "", ""
+	if result.Latency > 0 {
+		latency = strconv.FormatInt(result.Latency.Milliseconds(), 10)
+	}
+	if result.Err != nil {
+		reason = result.Err.Error()
+	}
+	return []string{result.Started.Format(time.RFC3339), result.TransferId, result.State, latency, reason}
+}
+
+// Add the result of a transfer to the statistics.
+func (statistics *soakStatistics) add(result transferOutcome) {
+	if result.Err != nil || result.State != "successful" {
+		statistics.failed++
+		return
+	}
+	statistics.successful++
+	statistics.latencies = append(statistics.latencies, result.Latency)
+}
+
+// Print the counts and latency percentiles of the soak test.
+func (statistics *soakStatistics) report(inFlight int) {
+	sorted := append([]time.Duration{}, statistics.latencies...)
+	sort.Slice(sorted, func(i, k int) bool { return sorted[i] < sorted[k] })
+	printMessage("soakReport", statistics.submitted, statistics.successful, statistics.failed, inFlight,
+		percentile(sorted, 50), percentile(sorted, 90), percentile(sorted, 95), percentile(sorted, 99),
+		percentile(sorted, 100))
+}
+
+/* Return a percentile of sorted latencies using the nearest rank method.
+* sorted - Latencies in ascending order
+* p      - Percentile, from 1 to 100
+ */
+func percentile(sorted []time.Duration, p int) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := (p*len(sorted) + 99) / 100
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1].Round(time.Millisecond)
+}
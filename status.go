@@ -0,0 +1,169 @@
+/*
+© Copyright IBM Corporation 2022, 2022
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+/*
+* This file contains the "status" command, which queries the status of a
+* transfer by its transfer ID. Any transfer known to the MQ Web Server of the
+* route can be queried, not only those submitted by this program: the URL of
+* the transfer resource is built from the ID. With -tui the items of the
+* transfer are shown in a terminal user interface, see tui.go. With -output
+* csv or csv-items the transfer is queried once, without waiting for it to
+* end, and printed as CSV, see export.go.
+ */
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/tidwall/gjson"
+)
+
+// Result of a transfer item as reported by the MQ Web Server.
+type ItemResult struct {
+	Source              string
+	Destination         string
+	State               string
+	Bytes               int64
+	Checksum            string
+	DestinationChecksum string
+	Description         string
+}
+
+// States in which a transfer has ended.
+var terminalTransferStates = map[string]bool{
+	"successful":          true,
+	"partiallysuccessful": true,
+	"failed":              true,
+	"cancelled":           true,
+	"malformed":           true,
+	"notauthorized":       true,
+	"deleted":             true,
+}
+
+// Check if a transfer in the given state has ended.
+func isTerminalTransferState(state string) bool {
+	return terminalTransferStates[strings.ToLower(state)]
+}
+
+// Length of a transfer ID, the MQ message ID of the transfer request in
+// hexadecimal.
+const transferIdLength = 48
+
+// Return a transfer ID in upper case, or an error if it is not 48
+// hexadecimal digits.
+func normalizeTransferId(transferId string) (string, error) {
+	if len(transferId) != transferIdLength {
+		return transferId, fmt.Errorf("%s", message("transferIdInvalid", transferId, transferIdLength))
+	}
+	for _, char := range transferId {
+		if !(char >= '0' && char <= '9' || char >= 'a' && char <= 'f' || char >= 'A' && char <= 'F') {
+			return transferId, fmt.Errorf("%s", message("transferIdInvalid", transferId, transferIdLength))
+		}
+	}
+	return strings.ToUpper(transferId), nil
+}
+
+// Return the URL of a transfer resource on the MQ Web Server of a route.
+func transferResourceUrl(route Route, transferId string) string {
+	return strings.TrimSuffix(route.transferUrl(), "/") + "/" + transferId
+}
+
+/* Return the item results of a transfer.
+* transfer - Transfer as returned by the status query
+ */
+func transferItemResults(transfer gjson.Result) []ItemResult {
+	items := []ItemResult{}
+	for _, item := range transfer.Get("transferSet.item").Array() {
+		items = append(items, ItemResult{
+			Source:              item.Get("source.name").String(),
+			Destination:         item.Get("destination.name").String(),
+			State:               item.Get("status.state").String(),
+			Bytes:               item.Get("source.size").Int(),
+			Checksum:            item.Get("source.checksum").String(),
+			DestinationChecksum: item.Get("destination.checksum").String(),
+			Description:         item.Get("status.description").String(),
+		})
+	}
+	return items
+}
+
+// Command "status" - query the status of a transfer.
+func statusCommand(args []string) int {
+	flags := newFlagSet("status")
+	tui := flags.Bool("tui", false, "Browse the item results in a terminal user interface")
+	itemsCsv := flags.String("items-csv", "", "Write the result of each transfer item to a CSV file")
+	output := flags.String("output", "text", "Output format, text, csv or csv-items")
+	routeName := flags.String("route", "", "Route whose MQ Web Server is queried")
+	if !parseCommandLine(flags, args) || flags.NArg() != 1 ||
+		(*output != "text" && *output != "csv" && *output != "csv-items") || (*tui && *output != "text") {
+		printMessage("statusUsage")
+		return 2
+	}
+	transferId, err := normalizeTransferId(flags.Arg(0))
+	if err != nil {
+		printError(err)
+		return 2
+	}
+	route, err := findRoute(*routeName)
+	if err != nil {
+		printError(err)
+		return 2
+	}
+	transferUrl := transferResourceUrl(route, transferId)
+	if !*tui && *output == "text" {
+		respCode, respBody := waitForTransferStatus(transferUrl)
+		if respCode == http.StatusNotFound {
+			logMessage("transferIdNotFound", []any{logTransferId, transferId}, transferId)
+		}
+		if respCode != http.StatusOK {
+			return 1
+		}
+		if len(*itemsCsv) > 0 && !writeItemsCsvFile(*itemsCsv, respBody) {
+			return 1
+		}
+		return 0
+	}
+
+	respGET, respBody, err := callMQWeb("GET", transferUrl+"?attributes=*", "")
+	if err != nil {
+		logMessage("statusQueryFailed", []any{logUrl, transferUrl}, transferUrl, err)
+		return 1
+	}
+	if respGET.StatusCode != http.StatusOK {
+		logMessage("responseCodeReceived", []any{logUrl, transferUrl, logStatusCode, respGET.StatusCode}, respGET.Status)
+		return 1
+	}
+	transfer := gjson.Get(respBody, "transfer.0")
+	if !transfer.Exists() {
+		logMessage("transferIdNotFound", []any{logTransferId, transferId}, transferId)
+		return 1
+	}
+	if !*tui {
+		if err := writeTransfersCsv(os.Stdout, []gjson.Result{transfer}, *output == "csv-items"); err != nil {
+			printError(err)
+			return 1
+		}
+		return 0
+	}
+	title := fmt.Sprintf("Transfer %s  %s -> %s  %s", transfer.Get("id").String(),
+		transfer.Get("sourceAgent.name").String(), transfer.Get("destinationAgent.name").String(),
+		transfer.Get("status.state").String())
+	if err := browseItemResults(title, transferItemResults(transfer)); err != nil {
+		printMessage("tuiFailed", err)
+		return 1
+	}
+	return 0
+}
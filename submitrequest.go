@@ -24,6 +24,9 @@ limitations under the License.
 *    program waits for 5 seconds and resubmits the HTTP GET request again to
 *    query the transfer status.
 *
+* Transfers can be held during maintenance windows, see maintenance.go, and
+* skipped on holidays, see calendar.go.
+*
 * This program assumes the following:
 * 1) MFT network has been setup with at least two agents.
 * 2) Basic authentication for REST APIs has been configured.
@@ -34,9 +37,12 @@ package main
 import (
 	"bytes"
 	"encoding/base64"
+	"encoding/json"
 	"fmt"
-	"io/ioutil"
 	"net/http"
+	"os"
+	"path"
+	"strconv"
 	"strings"
 	"time"
 
@@ -46,7 +52,8 @@ import (
 )
 
 /**
-* Constants used by this application. Modify per your requirement.
+* Constants used by this application. Modify per your requirement or
+* override them in a configuration file, see config.go.
  */
 const mqRestXferUrl = "http://localhost:8080/ibmmq/rest/v2/admin/mft/transfer"
 const mqWebUserId = "mqmftadminusr"
@@ -60,62 +67,530 @@ const destinationItemName = "/usr/destdir"
 const sourceItemType = "file"
 const destinationItemType = "directory"
 
+// Name and version of this program. The version is set at build time with
+// go build -ldflags "-X main.programVersion=1.2.3"
+const programName = "mft-rest-submit-transfer-go"
+
+var programVersion = "dev"
+
+// Commands supported by this program. Default command is "submit".
+var commands = map[string]func(args []string) int{
+	"submit":      submitCommand,
+	"held":        heldCommand,
+	"release":     releaseCommand,
+	"credentials": credentialsCommand,
+	"status":      statusCommand,
+	"soak":        soakCommand,
+	"canary":      canaryCommand,
+	"onboard":     onboardCommand,
+	"refresh":     refreshCommand,
+	"fixup":       fixupCommand,
+	"monitor":     monitorCommand,
+	"template":    templateCommand,
+	"list":        listCommand,
+	"cancel":      cancelCommand,
+	"dashboard":   dashboardCommand,
+	"agents":      agentsCommand,
+	"version":     versionCommand,
+	"batch":       batchCommand,
+	"report":      reportCommand,
+	"audit":       auditCommand,
+}
+
 /**
 * Main entry point
  */
 func main() {
+	command := "submit"
+	args := os.Args[1:]
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		command = args[0]
+		args = args[1:]
+	}
+	run, found := commands[command]
+	if !found {
+		printMessage("unknownCommand", command)
+		os.Exit(2)
+	}
+	os.Exit(run(args))
+}
+
+// Command "submit" - submit a transfer request for a route.
+func submitCommand(args []string) int {
+	flags := newFlagSet("submit")
+	routeName := flags.String("route", "", "Name of the route to transfer")
+	templateName := flags.String("template", "", "Name of the template to transfer instead of a route")
+	routeFile := flags.String("file", "", "Transfer the route defined in a JSON or YAML file instead of a route")
+	overrides := map[string]string{}
+	flags.Func("set", "Override KEY=VALUE of the template, src, dest or an agent or queue manager. May be repeated", func(value string) error {
+		return parseTemplateOverride(value, overrides)
+	})
+	compare := flags.Bool("compare", false, "Compare the source with the previous run of the route")
+	confirm := flags.Bool("confirm", false, "Ask for confirmation of a transfer above the confirmation thresholds")
+	itemsCsv := flags.String("items-csv", "", "Write the result of each transfer item to a CSV file")
+	junitFile := flags.String("junit", "", "Write the result of the transfer to a JUnit XML report")
+	resultFile := flags.String("result", "", "Write the result of the submission to a JSON file")
+	items := []TransferItem{}
+	flags.Func("item", "Transfer SOURCE=DESTINATION instead of the items of the route. May be repeated", func(value string) error {
+		item, err := parseTransferItem(value)
+		items = append(items, item)
+		return err
+	})
+	checksumMethod := flags.String("checksum", "", "Checksum method of the transfer, MD5 or none")
+	jobName := flags.String("job-name", "", "Job name of the transfer")
+	metadata := map[string]string{}
+	flags.Func("metadata", "Add KEY=VALUE to the metadata of the transfer. May be repeated", func(value string) error {
+		return parseMetadataFlag(value, metadata)
+	})
+	actionIfExists := flags.String("if-exists", "", "Action when a destination file exists, error or overwrite")
+	priority := flags.Int("priority", -1, "Priority of the transfer from 0 (lowest) to 9 (highest)")
+	interactive := flags.Bool("interactive", false, "Choose the source and destination agents from a menu of the agents")
+	checkAgents := flags.Bool("check-agents", false, "Check that the agents exist and are ready before submitting the transfer")
+	verify := flags.Bool("verify", false, "Compare the checksums of the source and destination files after the transfer")
+	dryRun := flags.Bool("dry-run", false, "Print the transfer request without submitting it")
+	manifest := flags.String("manifest", "", "Transfer the items listed in a CSV or JSON Lines manifest file")
+	transferDefinition := flags.String("td", "", "Transfer the items, or the request, of an fteCreateTransfer XML transfer definition file")
+	startTime := flags.String("start-time", "", "Schedule the transfer to start at a time, yyyy-MM-ddThh:mm")
+	timeBase := flags.String("time-base", "", "Time base of -start-time, admin, source or UTC")
+	timezone := flags.String("timezone", "", "Time zone of -start-time in the admin time base")
+	repeatEvery := flags.String("repeat-every", "", "Repeat the scheduled transfer every interval, such as 30m, 2h or 1d")
+	repeatCount := flags.Int("repeat-count", 0, "Number of occurrences of a repeated transfer")
+	repeatUntil := flags.String("repeat-until", "", "End time of a repeated transfer, yyyy-MM-ddThh:mm")
+	pollInterval := flags.Duration("poll-interval", 0, "Interval between the first status queries of the transfer, see poll.go")
+	waitTimeout := flags.Duration("wait-timeout", -1, "Longest time to wait for the transfer to end, 0 to wait until it ends")
+	var recoveryTimeout *int
+	flags.Func("recovery-timeout", "Seconds to recover a stalled transfer before it fails, -1 to recover until it completes", func(value string) (err error) {
+		recoveryTimeout, err = parseRecoveryTimeout(value)
+		return err
+	})
+	if !parseCommandLine(flags, args) {
+		return 2
+	}
+	if *pollInterval > 0 {
+		config.Polling.Interval = Duration(*pollInterval)
+	}
+	if *waitTimeout >= 0 {
+		config.Polling.Timeout = Duration(*waitTimeout)
+	}
+	// The transfer is that of a route, a template or a definition file
+	routeSources := 0
+	for _, source := range []string{*routeName, *templateName, *routeFile} {
+		if len(source) > 0 {
+			routeSources++
+		}
+	}
+	if routeSources > 1 || len(*templateName) == 0 && len(overrides) > 0 {
+		printMessage("submitTemplateFlags")
+		return 2
+	}
+	if len(*manifest) > 0 {
+		manifestItems, err := readManifest(*manifest)
+		if err != nil {
+			printError(err)
+			writeSubmissionResult(*resultFile, SubmissionResult{Route: *routeName, Outcome: "invalid", Message: err.Error()})
+			return 2
+		}
+		items = append(items, manifestItems...)
+	}
+	var td TransferDefinition
+	if len(*transferDefinition) > 0 {
+		var err error
+		td, err = readTransferDefinition(*transferDefinition)
+		if err == nil && td.Route != nil && routeSources > 0 {
+			err = fmt.Errorf("%s is a transfer request with its own agents, it cannot be used with -route, -template or -file", *transferDefinition)
+		}
+		if err != nil {
+			printError(err)
+			writeSubmissionResult(*resultFile, SubmissionResult{Route: *routeName, Outcome: "invalid", Message: err.Error()})
+			return 2
+		}
+		items = append(items, td.Items...)
+	}
+	var route Route
+	var err error
+	switch {
+	case td.Route != nil:
+		route, err = td.Route.forSubmission(items)
+	case len(*templateName) > 0:
+		route, err = findTemplateRoute(*templateName, overrides, items)
+	case len(*routeFile) > 0:
+		if route, err = readRouteDefinition(*routeFile); err == nil {
+			route, err = route.forSubmission(items)
+		}
+	default:
+		route, err = findSubmissionRoute(*routeName, items)
+	}
+	if err == nil && *interactive {
+		route, err = selectRouteAgents(route)
+	}
+	if err == nil && len(*checksumMethod) == 0 && len(td.ChecksumMethod) > 0 {
+		route.ChecksumMethod, err = normalizeChecksumMethod(td.ChecksumMethod)
+	}
+	if err == nil && len(*checksumMethod) > 0 {
+		route.ChecksumMethod, err = normalizeChecksumMethod(*checksumMethod)
+	}
+	if err == nil && len(*actionIfExists) > 0 {
+		// The flag applies to every item, replacing the item settings
+		for index := range route.Items {
+			route.Items[index].ActionIfExists = ""
+		}
+		route.ActionIfExists = *actionIfExists
+		err = validateTransferItems(route)
+	}
+	if err == nil && *priority >= 0 {
+		route.Priority = priority
+		err = validatePriority(route.Priority)
+	}
+	if err == nil && len(metadata) > 0 {
+		for key, value := range route.Metadata {
+			if _, found := metadata[key]; !found {
+				metadata[key] = value
+			}
+		}
+		route.Metadata = metadata
+		err = validateMetadata(route.Metadata)
+	}
+	if len(*jobName) > 0 {
+		route.JobName = *jobName
+	}
+	if err == nil && recoveryTimeout != nil {
+		route.RecoveryTimeout = recoveryTimeout
+		err = validateTransferSetOptions(route)
+	}
+	if err == nil && (len(*startTime) > 0 || len(*timeBase) > 0 || len(*timezone) > 0 ||
+		len(*repeatEvery) > 0 || *repeatCount > 0 || len(*repeatUntil) > 0) {
+		schedule := TransferSchedule{}
+		if route.Schedule != nil {
+			schedule = *route.Schedule
+		}
+		if len(*repeatEvery) > 0 || *repeatCount > 0 || len(*repeatUntil) > 0 {
+			repeat := ScheduleRepeat{}
+			if schedule.Repeat != nil {
+				repeat = *schedule.Repeat
+			}
+			if len(*repeatEvery) > 0 {
+				repeat.Frequency, repeat.Interval, err = parseRepeatEvery(*repeatEvery)
+			}
+			// The flags replace the end of the repetitions of the route
+			if *repeatCount > 0 || len(*repeatUntil) > 0 {
+				repeat.Count, repeat.EndTime = *repeatCount, *repeatUntil
+			}
+			schedule.Repeat = &repeat
+		}
+		if len(*startTime) > 0 {
+			schedule.StartTime = *startTime
+		}
+		if len(*timeBase) > 0 {
+			schedule.TimeBase = *timeBase
+		}
+		if len(*timezone) > 0 {
+			schedule.Timezone = *timezone
+		}
+		route.Schedule = &schedule
+		if err == nil {
+			err = validateSchedule(route.Schedule, time.Now())
+		}
+	}
+	if err != nil {
+		printError(err)
+		writeSubmissionResult(*resultFile, SubmissionResult{Route: *routeName, Outcome: "invalid", Message: err.Error()})
+		return 2
+	}
+	if *dryRun {
+		fmt.Println(indentRequest(buildTransferJsonRequest(withDryRunId(route))))
+		return 0
+	}
+	printMessage("runId", runId)
+	// Submit transfers held during a maintenance window that has now ended
+	releaseDueTransfers()
+
+	// Business day only routes are skipped on weekends and holidays
+	businessDay, holiday, err := isBusinessDay(route, time.Now())
+	if err != nil {
+		printMessage("holidayCalendarReadFailed", err)
+		writeSubmissionResult(*resultFile, SubmissionResult{Route: route.Name, Outcome: "failed", Message: err.Error()})
+		return 1
+	}
+	if !businessDay {
+		printMessage("routeNotBusinessDay", route.Name, holiday)
+		writeSubmissionResult(*resultFile, SubmissionResult{Route: route.Name, Outcome: "skipped", Message: holiday})
+		return 0
+	}
+
+	// Check the sources against their expected checksums
+	if err := verifySourceChecksums(route); err != nil {
+		printMessage("integrityCheckFailed", err)
+		writeSubmissionResult(*resultFile, SubmissionResult{Route: route.Name, Outcome: "invalid", Message: err.Error()})
+		return 1
+	}
+
+	// Fail fast rather than leave a transfer waiting for a stopped agent
+	if err := checkQueueManagers(route); err != nil {
+		printMessage("qmgrCheckFailed", route.Name, err)
+		writeSubmissionResult(*resultFile, SubmissionResult{Route: route.Name, Outcome: "failed", Message: err.Error()})
+		return 1
+	}
+	if *checkAgents || config.CheckAgents {
+		if err := checkRouteAgents(route); err != nil {
+			printMessage("agentCheckFailed", route.Name, err)
+			writeSubmissionResult(*resultFile, SubmissionResult{Route: route.Name, Outcome: "failed", Message: err.Error()})
+			return 1
+		}
+	}
+
 	// Build a transfer request and put to agent's command queue
-	transferRequest := buildTransferJsonRequest()
-	// Post transfer request. Rerturn value will have URL to retrieve transfer status.
-	retCode, transferUrl := postTransferRequest(transferRequest)
-	if retCode == http.StatusAccepted {
-		// Requested submitted successfully. Now look for status of transfer
-		respCode := waitForTransferStatus(transferUrl)
-		// If response was anything other 200, then rerun the request as the transfer
-		// may not have started yet.
-		if respCode != http.StatusOK {
-			// Wiat for 5 seconds and resubmit the HTTP GET request again
-			time.Sleep(5 * time.Second)
-			// Resubmit the transfer status GET request
-			respCode = waitForTransferStatus(transferUrl)
+	transferRequest := buildTransferJsonRequest(route)
+	estimate := estimateTransfer(route, transferRequest)
+	estimate.print()
+	if reasons := estimate.exceeded(config.ConfirmThresholds); *confirm && len(reasons) > 0 && !confirmSubmission(reasons) {
+		printMessage("previewCancelled")
+		writeSubmissionResult(*resultFile, SubmissionResult{Route: route.Name, Outcome: "cancelled"})
+		return 1
+	}
+	if window, end, active := activeMaintenanceWindow(route, time.Now()); active {
+		reason := message("maintenanceWindowReason", window.Start, window.End)
+		if len(window.Description) > 0 {
+			reason += " (" + window.Description + ")"
+		}
+		held, err := holdTransfer(HeldTransfer{Route: route.Name, Reason: reason, ReleaseAfter: end,
+			Request: transferRequest, TransferUrl: route.transferUrl()})
+		if err != nil {
+			printMessage("holdFailed", err)
+			writeSubmissionResult(*resultFile, SubmissionResult{Route: route.Name, Outcome: "failed", Message: err.Error()})
+			return 1
+		}
+		printMessage("heldForMaintenance", route.Name, reason, held.Id, end.Format(time.RFC1123))
+		writeSubmissionResult(*resultFile, SubmissionResult{Route: route.Name, Outcome: "held", HeldId: held.Id, Message: reason})
+		return 0
+	}
+
+	// Compare the source with the previous run to catch upstream data problems
+	var snapshot *Snapshot
+	if *compare || route.CompareBaseline || route.HoldOnAnomaly {
+		var anomalies []string
+		snapshot, anomalies = compareWithBaseline(route)
+		if len(anomalies) > 0 && route.HoldOnAnomaly {
+			heldId, exitCode := holdAnomalousTransfer(route, transferRequest, snapshot, anomalies)
+			writeSubmissionResult(*resultFile, SubmissionResult{Route: route.Name, Outcome: "held", HeldId: heldId,
+				Message: strings.Join(anomalies, ", ")})
+			return exitCode
 		}
 	}
+	if route.Schedule != nil {
+		// A scheduled transfer has no status until the source agent starts it
+		if retCode, _ := postTransferRequest(route.transferUrl(), transferRequest); retCode != http.StatusAccepted {
+			auditSubmission("submit", route.Name, route.transferUrl(), transferRequest, "failed", "")
+			writeSubmissionResult(*resultFile, SubmissionResult{Route: route.Name, Outcome: "failed"})
+			writeJUnitReport(*junitFile, []JUnitTestSuite{transferTestSuite(route.Name, "", "", 0)})
+			return 1
+		}
+		printMessage("transferScheduled", route.Name, route.Schedule.StartTime, route.Schedule.timeBase())
+		auditSubmission("submit", route.Name, route.transferUrl(), transferRequest, "scheduled", "")
+		writeSubmissionResult(*resultFile, SubmissionResult{Route: route.Name, Outcome: "scheduled", Message: route.Schedule.StartTime})
+		writeJUnitReport(*junitFile, []JUnitTestSuite{transferTestSuite(route.Name, "scheduled", "", 0)})
+		if snapshot != nil {
+			if err := saveBaseline(*snapshot); err != nil {
+				printMessage("baselineSaveFailed", route.Name, err)
+			}
+		}
+		return 0
+	}
+	submittedAt := time.Now()
+	respCode, transferStatus := submitTransfer(route.transferUrl(), transferRequest)
+	if len(*itemsCsv) > 0 && len(transferStatus) > 0 {
+		writeItemsCsvFile(*itemsCsv, transferStatus)
+	}
+	transfer := gjson.Get(transferStatus, "transfer.0")
+	outcome := submissionOutcome(respCode)
+	auditSubmission("submit", route.Name, route.transferUrl(), transferRequest, outcome, transferStatus)
+	if transfer.Exists() {
+		printQuietResult(transfer.Get("id").String(), transfer.Get("status.state").String())
+	}
+	writeJUnitReport(*junitFile, []JUnitTestSuite{transferTestSuite(route.Name, transfer.Get("status.state").String(),
+		transferStatus, time.Since(submittedAt))})
+	writeSubmissionResult(*resultFile, SubmissionResult{Route: route.Name, Outcome: outcome,
+		TransferId: transfer.Get("id").String(), State: transfer.Get("status.state").String()})
+	if respCode != http.StatusOK {
+		// Still running when the polling timed out, or failed to submit
+		return exitFailed
+	}
+	if snapshot != nil {
+		if err := saveBaseline(*snapshot); err != nil {
+			printMessage("baselineSaveFailed", route.Name, err)
+		}
+	}
+	if *verify {
+		if state := transfer.Get("status.state").String(); state != "successful" {
+			printMessage("integrityTransferNotComplete", state)
+			return 1
+		}
+		if !verifyTransferredFiles(route) {
+			return 1
+		}
+	}
+	return transferExitCode(transfer.Get("status.state").String())
 }
 
-// Build a simmple transfer JSON request.
-func buildTransferJsonRequest() string {
+/* Submit a transfer request and poll the status of the transfer until it
+* ends, see poll.go. Returns the HTTP status code of the last status query, -1
+* or pollTimedOutStatus, and the last transfer status returned.
+* xferReqURL      - URL of the transfer resource of the MQ Web Server
+* transferRequest - Transfer request in JSON format.
+ */
+func submitTransfer(xferReqURL string, transferRequest string) (int, string) {
+	// Post transfer request. Rerturn value will have URL to retrieve transfer status.
+	retCode, transferUrl := postTransferRequest(xferReqURL, transferRequest)
+	if retCode != http.StatusAccepted {
+		return -1, ""
+	}
+	// Requested submitted successfully. Now poll the status until the transfer ends
+	return pollTransferStatus(transferUrl, config.Polling)
+}
+
+// Build a transfer JSON request for a route, with one item per source and
+// destination pair of the route. The attributes of each object are written in
+// alphabetical order and the items in the order of the route, so that the
+// same route always gives the same request.
+func buildTransferJsonRequest(route Route) string {
 	// Source agent attributes
-	sourceAgent := j.Object().Put("qmgrName", sourceQMName)
-	sourceAgent.Put("name", sourceAgentName)
+	sourceAgent := j.Object().Put("qmgrName", route.SourceQM)
+	sourceAgent.Put("name", route.SourceAgent)
 	xferRequest := j.Object().Put("sourceAgent", sourceAgent)
 
 	// Destination agent attributes
-	destAgent := j.Object().Put("qmgrName", destinationQMName)
-	destAgent.Put("name", destinationAgentName)
+	destAgent := j.Object().Put("qmgrName", route.DestinationQM)
+	destAgent.Put("name", route.DestinationAgent)
 	xferRequest = xferRequest.Put("destinationAgent", destAgent)
 
-	// Source item attributes
-	sourceItem := j.Object().Put("name", sourceItemName)
-	sourceItem.Put("type", sourceItemType)
+	// Job name, if any
+	if len(route.JobName) > 0 {
+		xferRequest.Put("job", j.Object().Put("name", route.JobName))
+	}
 
-	// Destination item attributes
-	destItem := j.Object().Put("name", destinationItemName)
-	destItem.Put("type", destinationItemType)
+	// Start time of a scheduled transfer, if any
+	if route.Schedule != nil {
+		xferRequest.Put("schedule", route.Schedule.jsonObject())
+	}
 
-	// Set source and destination to item group
-	item := j.Object().Put("source", sourceItem)
-	item.Put("destination", destItem)
+	// Set each source and destination pair in to the transfer item array
+	itemsArray := j.Array()
+	for _, transferItem := range route.transferItems() {
+		// Source item attributes
+		sourceItem := j.Object().Put("name", transferItem.Source)
+		sourceItem.Put("type", transferItem.SourceType)
+		if len(transferItem.SourceEncoding) > 0 {
+			sourceItem.Put("encoding", transferItem.SourceEncoding)
+		}
+		if len(transferItem.SourceDisposition) > 0 {
+			sourceItem.Put("disposition", transferItem.SourceDisposition)
+		}
+		if transferItem.Recursive != nil {
+			sourceItem.Put("recursive", *transferItem.Recursive)
+		}
+		if len(transferItem.Exclude) > 0 {
+			exclude := j.Array()
+			for _, pattern := range transferItem.Exclude {
+				exclude.Put(pattern)
+			}
+			sourceItem.Put("exclude", exclude)
+		}
+		if len(transferItem.MessageDelimiter) > 0 {
+			sourceItem.Put("messageDelimiter", transferItem.MessageDelimiter)
+		}
+		if len(transferItem.MessageDelimiterType) > 0 {
+			sourceItem.Put("messageDelimiterType", transferItem.MessageDelimiterType)
+		}
+		if len(transferItem.MessageDelimiterPosition) > 0 {
+			sourceItem.Put("messageDelimiterPosition", transferItem.MessageDelimiterPosition)
+		}
+		if transferItem.GroupMessages {
+			sourceItem.Put("groupMessages", true)
+		}
+		if transferItem.WaitTime != nil {
+			sourceItem.Put("waitTime", *transferItem.WaitTime)
+		}
 
-	// Set item in to the transfer item array
-	itemsArray := j.Array().Put(item)
+		// Destination item attributes
+		destItem := j.Object().Put("name", transferItem.Destination)
+		destItem.Put("type", transferItem.DestinationType)
+		if len(transferItem.DestinationEncoding) > 0 {
+			destItem.Put("encoding", transferItem.DestinationEncoding)
+		}
+		if len(transferItem.DestinationEOL) > 0 {
+			destItem.Put("endOfLine", transferItem.DestinationEOL)
+		}
+		if len(transferItem.ActionIfExists) > 0 {
+			destItem.Put("actionIfExists", transferItem.ActionIfExists)
+		}
+		if len(transferItem.MessagePersistence) > 0 {
+			destItem.Put("messagePersistence", transferItem.MessagePersistence)
+		}
+		if transferItem.SetMqProps {
+			destItem.Put("setMqProps", true)
+		}
+		if transferItem.DatasetAttributes != nil {
+			destItem.Put("attributes", transferItem.DatasetAttributes.String())
+		}
+
+		// Set source and destination to item group
+		item := j.Object().Put("source", sourceItem)
+		item.Put("destination", destItem)
+		if len(transferItem.Mode) > 0 {
+			item.Put("mode", transferItem.Mode)
+		}
+		if len(route.ChecksumMethod) > 0 {
+			item.Put("checksumMethod", route.ChecksumMethod)
+		}
+		itemsArray.Put(item)
+	}
 	xfertSetItems := j.Object().Put("item", itemsArray)
 
+	// Set user defined metadata, if any
+	if len(route.Metadata) > 0 {
+		metadata := j.Object()
+		for key, value := range route.Metadata {
+			metadata.Put(key, value)
+		}
+		xfertSetItems.Put("userDefinedMetadata", metadata)
+	}
+
+	// Set the priority, the agent default if not set
+	if route.Priority != nil {
+		xfertSetItems.Put("priority", *route.Priority)
+	}
+
+	// Set the recovery timeout and reply queue, the agent defaults if not set
+	if route.RecoveryTimeout != nil {
+		xfertSetItems.Put("recoveryTimeout", *route.RecoveryTimeout)
+	}
+	if route.Reply != nil {
+		xfertSetItems.Put("reply", route.Reply.jsonObject())
+	}
+
+	// Set the programs called before and after the transfer, if any
+	calls := route.programCalls()
+	for _, section := range programCallSections {
+		if call := calls[section]; call != nil {
+			xfertSetItems.Put(section, call.jsonObject())
+		}
+	}
+
 	// Set transfer items array to transfer set
 	xferRequest.Put("transferSet", xfertSetItems)
 	//Return JSON object as string
 	return xferRequest.String()
 }
 
+// Indent a transfer request for display. The request is returned unchanged
+// if it is not valid JSON.
+func indentRequest(request string) string {
+	var indented bytes.Buffer
+	if err := json.Indent(&indented, []byte(request), "", "  "); err != nil {
+		return request
+	}
+	return indented.String()
+}
+
 /* Build a HTTP request with given inputs
 * httpVerb - Value can be GET or POST
 * url      - Url to which request will be submitted
@@ -147,89 +622,83 @@ func buildHTTPRequestHeader(httpVerb string, url string, body string, userId str
 }
 
 /* Submit transfer request.
+*  xferReqURL      - URL of the transfer resource of the MQ Web Server
 *  xferRequestJson - Transfer request in JSON format.
  */
-func postTransferRequest(xferRequestJson string) (int, string) {
-	xferReqURL := mqRestXferUrl
-	httpPOST, errPOST := buildHTTPRequestHeader("POST", xferReqURL, xferRequestJson, mqWebUserId, mqWebPassword)
-	if errPOST != nil {
-		fmt.Printf("Error occured creating HTTP request. The error is %v\n", errPOST)
-		return -1, ""
-	}
-	postClient := &http.Client{}
-	respPost, errPost := postClient.Do(httpPOST)
+func postTransferRequest(xferReqURL string, xferRequestJson string) (int, string) {
+	respPost, _, errPost := callMQWeb("POST", xferReqURL, xferRequestJson)
 	if errPost != nil {
-		fmt.Printf("An error occured while publishing transfer logs to %s. The error is: %v\n", xferReqURL, errPost)
+		logMessage("submitFailed", []any{logUrl, xferReqURL}, xferReqURL, errPost)
+		syslogEvent(syslogError, "submitFailed", []string{"url", xferReqURL}, message("submitFailed", xferReqURL, errPost))
 		return -1, ""
 	}
-	defer respPost.Body.Close()
 
-	fmt.Printf("Submitted transfer request to: %v\n", xferReqURL)
-	//Read the response body
+	logMessage("submitted", []any{logUrl, xferReqURL}, xferReqURL)
+	logMessage("httpResponseReceived", []any{logUrl, xferReqURL, logStatusCode, respPost.StatusCode}, respPost.Status)
 	var transferStatusUrl string = ""
-	var retCode int = -1
-	_, err := ioutil.ReadAll(respPost.Body)
-	if err != nil {
-		fmt.Printf("An error occurred while reading response from server %s. The error is: %v\n", xferReqURL, err)
+	if respPost.StatusCode == http.StatusAccepted {
+		transferStatusUrl = respPost.Header.Get("location")
+		logMessage("transferUrl", []any{logUrl, transferStatusUrl}, transferStatusUrl)
+		transferId := path.Base(transferStatusUrl)
+		syslogEvent(syslogInformational, "submitted", []string{"transferID", transferId, "url", transferStatusUrl},
+			message("syslogSubmitted", transferId, xferReqURL))
 	} else {
-		fmt.Printf("HTTP response received. Status: %v\n", respPost.Status)
-		retCode = respPost.StatusCode
-		if respPost.StatusCode == http.StatusAccepted {
-			transferStatusUrl = respPost.Header.Get("location")
-			fmt.Printf("Transfer URL:%v\n", transferStatusUrl)
-		}
+		syslogEvent(syslogError, "submitFailed", []string{"url", xferReqURL, "statusCode", strconv.Itoa(respPost.StatusCode)},
+			message("syslogRefused", xferReqURL, respPost.Status))
 	}
-	return retCode, transferStatusUrl
+	return respPost.StatusCode, transferStatusUrl
 }
 
 /**
-* Issue HTTP GET request to retrieve the status of transfer.
+* Issue HTTP GET request to retrieve the status of transfer. Returns the HTTP
+* status code and the response body.
 * transferUrl - URL to query transfer status. This URL is returned by POST verb request.
  */
-func waitForTransferStatus(transferUrl string) int {
-	// Build HTTP GET request to query all attributes of transfer.
-	fmt.Printf("Querying status of transfer\n")
-	httpGET, errGET := buildHTTPRequestHeader("GET", transferUrl+"?attributes=*", "", mqWebUserId, mqWebPassword)
-	if errGET != nil {
-		fmt.Printf("Error occured creating HTTP request. The error is %v\n", errGET)
-		return -1
-	}
-	getClient := &http.Client{}
-	// Run the request and handle errors.
-	respGET, errGET := getClient.Do(httpGET)
+func waitForTransferStatus(transferUrl string) (int, string) {
+	// Issue HTTP GET request to query all attributes of transfer.
+	printMessage("queryingStatus")
+	respGET, respBody, errGET := callMQWeb("GET", transferUrl+"?attributes=*", "")
 	if errGET != nil {
-		fmt.Printf("An error occured while publishing transfer logs to %s. The error is: %v\n", transferUrl, errGET)
-		return -1
+		logMessage("statusQueryFailed", []any{logUrl, transferUrl}, transferUrl, errGET)
+		return -1, ""
 	}
-	defer respGET.Body.Close()
 
 	// Verify the status code
 	if respGET.StatusCode == http.StatusOK {
-		respBody, errGET := ioutil.ReadAll(respGET.Body)
-		if errGET == nil {
-			respJson := gjson.Get(string(respBody), "transfer").Array()
-			status := gjson.Get(respJson[0].String(), "status.state")
-			id := gjson.Get(respJson[0].String(), "id")
-			fmt.Printf("Status of transfer with ID %v is %v\n", id.String(), status.String())
-			if !strings.EqualFold(status.String(), "successful") {
-				// Display additional details if the status is not successful
-				statusDescription := gjson.Get(respJson[0].String(), "status.description")
-				fmt.Printf("%s\nFollowing errors occurred:\n", statusDescription.String())
-				transferItems := gjson.Get(respJson[0].String(), "transferSet.item").Array()
-				if len(transferItems) > 0 {
-					itemCount := len(transferItems)
-					for index := 0; index < itemCount; index++ {
-						if !strings.EqualFold(transferItems[index].Get("status.state").String(), "successful") {
-							fmt.Printf("%s\n", transferItems[index].Get("status.description").String())
-						}
-					}
+		printTransferStatus(respBody)
+	} else {
+		logMessage("responseCodeReceived", []any{logUrl, transferUrl, logStatusCode, respGET.StatusCode}, respGET.Status)
+	}
+	return respGET.StatusCode, respBody
+}
+
+/* Display the status of a transfer, with the errors of its items if the
+* transfer is not successful.
+* respBody - Response of the transfer status query
+ */
+func printTransferStatus(respBody string) {
+	respJson := gjson.Get(respBody, "transfer").Array()
+	if len(respJson) == 0 {
+		printMessage("transferNotFound")
+		return
+	}
+	status := gjson.Get(respJson[0].String(), "status.state")
+	id := gjson.Get(respJson[0].String(), "id")
+	logMessage("transferStatus", []any{logTransferId, id.String()}, id.String(), status.String())
+	printTransferMetadata(respJson[0])
+	printItemChecksums(respJson[0])
+	if !strings.EqualFold(status.String(), "successful") {
+		// Display additional details if the status is not successful
+		statusDescription := gjson.Get(respJson[0].String(), "status.description")
+		logMessage("transferErrors", []any{logTransferId, id.String()}, statusDescription.String())
+		transferItems := gjson.Get(respJson[0].String(), "transferSet.item").Array()
+		if len(transferItems) > 0 {
+			itemCount := len(transferItems)
+			for index := 0; index < itemCount; index++ {
+				if !strings.EqualFold(transferItems[index].Get("status.state").String(), "successful") {
+					logger.Error(transferItems[index].Get("status.description").String(), logTransferId, id.String())
 				}
 			}
-		} else {
-			fmt.Printf("An error occurred while reading response from server %s. The error is: %v\n", transferUrl, errGET)
 		}
-	} else {
-		fmt.Printf("Response code received: %v\n", respGET.Status)
 	}
-	return respGET.StatusCode
 }
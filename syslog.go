@@ -0,0 +1,187 @@
+/*
+© Copyright IBM Corporation 2022, 2022
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+/*
+* This file contains the syslog events of the transfers, so that transfer
+* activity shows up in SIEM pipelines. An event is sent when a transfer
+* request is accepted or refused by the MQ Web Server, and when a polled
+* transfer ends. Events are RFC 5424 messages with the details of the transfer
+* as structured data:
+*
+*   <134>1 2026-10-17T10:00:05.123Z host mft-rest-submit-transfer-go 4242 completed
+*     [mft@2 runId="6f1c..." transferID="414D...0708" state="successful" bytes="1024"
+*      url="https://..."]
+*     Transfer 414D...0708 ended in state successful
+*
+* Syslog is configured with:
+*
+*   "syslog": { "network": "udp", "address": "siem.example.com:514", "facility": "local0" }
+*
+* Events are sent when any of network, address and facility is set. The
+* network is udp, tcp (with octet counting framing, RFC 6587) or unix for a
+* local syslog socket; without a network and address the local socket
+* /dev/log is used. The facility defaults to user. The message IDs are
+* submitted, submitFailed and completed. A successful transfer is logged
+* with the informational severity, a partially successful one as a warning
+* and the others as errors. When syslog cannot be reached, a warning is
+* printed once and the transfers are not affected. Secrets are masked as in
+* the log, see redact.go.
+ */
+package main
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Structured data ID of the events, with the private enterprise number of
+// IBM.
+const syslogDataId = "mft@2"
+
+// Syslog severities used by the events.
+const (
+	syslogError         = 3
+	syslogWarning       = 4
+	syslogInformational = 6
+)
+
+// Syslog settings of the configuration.
+type SyslogSettings struct {
+	Network  string `json:"network"`
+	Address  string `json:"address"`
+	Facility string `json:"facility"`
+	AppName  string `json:"appName"`
+}
+
+// Codes of the syslog facilities.
+var syslogFacilities = map[string]int{
+	"kern": 0, "user": 1, "mail": 2, "daemon": 3, "auth": 4, "syslog": 5, "lpr": 6, "news": 7,
+	"uucp": 8, "cron": 9, "authpriv": 10, "ftp": 11,
+	"local0": 16, "local1": 17, "local2": 18, "local3": 19, "local4": 20, "local5": 21, "local6": 22, "local7": 23,
+}
+
+// Connection to syslog, opened with the first event.
+var syslogWriter struct {
+	mutex      sync.Mutex
+	connection net.Conn
+	failed     bool
+}
+
+/* Escape a value of the structured data of a message.
+* value - Value of a parameter
+ */
+func escapeSyslogValue(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, `]`, `\]`).Replace(value)
+}
+
+/* Format an RFC 5424 message.
+* settings - Syslog settings of the configuration
+* severity - Syslog severity of the event
+* msgId    - Type of the event
+* params   - Alternating names and values of the structured data, empty
+*            values being left out
+* text     - Text of the message
+ */
+func formatSyslogMessage(settings SyslogSettings, severity int, msgId string, params []string, text string) string {
+	facility, found := syslogFacilities[strings.ToLower(settings.Facility)]
+	if !found {
+		facility = syslogFacilities["user"]
+	}
+	appName := settings.AppName
+	if len(appName) == 0 {
+		appName = programName
+	}
+	host, err := os.Hostname()
+	if err != nil || len(host) == 0 {
+		host = "-"
+	}
+	data := "[" + syslogDataId
+	for index := 0; index+1 < len(params); index += 2 {
+		if len(params[index+1]) > 0 {
+			data += fmt.Sprintf(` %s="%s"`, params[index], escapeSyslogValue(params[index+1]))
+		}
+	}
+	data += "]"
+	return fmt.Sprintf("<%d>1 %s %s %s %d %s %s %s", facility*8+severity, time.Now().UTC().Format("2006-01-02T15:04:05.000Z"),
+		host, appName, os.Getpid(), msgId, data, text)
+}
+
+/* Open the connection to syslog.
+* settings - Syslog settings of the configuration
+ */
+func dialSyslog(settings SyslogSettings) (net.Conn, error) {
+	network, address := settings.Network, settings.Address
+	if len(network) == 0 && len(address) == 0 {
+		network, address = "unix", "/dev/log"
+	}
+	if network == "unix" {
+		// Local syslog daemons read datagrams, some older ones a stream
+		connection, err := net.DialTimeout("unixgram", address, 5*time.Second)
+		if err != nil {
+			connection, err = net.DialTimeout("unix", address, 5*time.Second)
+		}
+		return connection, err
+	}
+	return net.DialTimeout(network, address, 5*time.Second)
+}
+
+/* Send a transfer event to syslog, if it is configured.
+* severity - Syslog severity of the event
+* msgId    - Type of the event: submitted, submitFailed or completed
+* params   - Alternating names and values of the structured data
+* text     - Text of the message
+ */
+func syslogEvent(severity int, msgId string, params []string, text string) {
+	settings := config.Syslog
+	if len(settings.Network) == 0 && len(settings.Address) == 0 && len(settings.Facility) == 0 {
+		return
+	}
+	syslogWriter.mutex.Lock()
+	defer syslogWriter.mutex.Unlock()
+	if syslogWriter.failed {
+		return
+	}
+	var err error
+	if syslogWriter.connection == nil {
+		syslogWriter.connection, err = dialSyslog(settings)
+	}
+	if err == nil {
+		params = append([]string{"runId", runId}, params...)
+		line := redact(formatSyslogMessage(settings, severity, msgId, params, text))
+		if settings.Network == "tcp" {
+			line = fmt.Sprintf("%d %s", len(line), line)
+		}
+		_, err = syslogWriter.connection.Write([]byte(line))
+	}
+	if err != nil {
+		syslogWriter.failed = true
+		printMessage("syslogFailed", err)
+	}
+}
+
+/* Return the syslog severity of the final state of a transfer.
+* state - State of the transfer
+ */
+func syslogSeverity(state string) int {
+	switch strings.ToLower(state) {
+	case "successful":
+		return syslogInformational
+	case "partiallysuccessful":
+		return syslogWarning
+	}
+	return syslogError
+}
@@ -0,0 +1,402 @@
+/*
+© Copyright IBM Corporation 2022, 2022
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+/*
+* This file contains the "template" command, which manages reusable transfer
+* templates. The REST API of the MQ Web Server has no template resource, the
+* MFT templates created with fteCreateTemplate are stored on the coordination
+* queue manager and cannot be created or read over REST, so the templates of
+* this program are kept in the templates directory of the state directory.
+*
+* A template is a route, in the format of the "routes" of the configuration
+* file:
+*
+*   {
+*     "name": "orders",
+*     "description": "Orders from the shop to the warehouse",
+*     "createdAt": "2026-10-17T09:30:00Z",
+*     "route": {
+*       "sourceAgent": "SHOP", "sourceQM": "SHOPQM",
+*       "destinationAgent": "WAREHOUSE", "destinationQM": "WAREHOUSEQM",
+*       "sourceItem": "/shop/orders.csv", "sourceItemType": "file",
+*       "destinationItem": "/warehouse/in/orders.csv", "destinationItemType": "file"
+*     }
+*   }
+*
+* "template create -name NAME" creates a template from a route of the
+* configuration, from a JSON or YAML file holding a route definition with
+* -file, and
+* with the items of -item when given. The transfer of the template is checked
+* as for a submission before it is saved. An existing template is replaced
+* only with -replace.
+*
+* "submit -template NAME" submits the transfer of a template. -set KEY=VALUE
+* overrides a field of the template: src and dest the source and destination
+* of its single item, sourceAgent, sourceQM, destinationAgent and
+* destinationQM its agents. Other submit flags, such as -item, -metadata or
+* -job-name, apply as they do to a route.
+*
+* "template list" lists the templates with their agents and a summary, their
+* description or their items, optionally only those whose name matches the
+* -name wildcard pattern. "template delete NAME" deletes a template after
+* asking the user to confirm, unless -force is given.
+ */
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Subcommands of the "template" command.
+var templateCommands = map[string]func(args []string) int{
+	"create": templateCreateCommand,
+	"list":   templateListCommand,
+	"delete": templateDeleteCommand,
+}
+
+// Fields of a template overridden with -set KEY=VALUE when it is submitted.
+var templateOverrides = map[string]func(route *Route, value string) error{
+	"src":              setTemplateSource,
+	"dest":             setTemplateDestination,
+	"sourceAgent":      func(route *Route, value string) error { route.SourceAgent = value; return nil },
+	"sourceQM":         func(route *Route, value string) error { route.SourceQM = value; return nil },
+	"destinationAgent": func(route *Route, value string) error { route.DestinationAgent = value; return nil },
+	"destinationQM":    func(route *Route, value string) error { route.DestinationQM = value; return nil },
+}
+
+// Transfer template, a named route kept in the state directory.
+type TransferTemplate struct {
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	CreatedAt   time.Time `json:"createdAt"`
+	Route       Route     `json:"route"`
+}
+
+// Returns the directory in which templates are stored.
+func templateDirectory() string {
+	return filepath.Join(config.StateDirectory, "templates")
+}
+
+// Returns the file holding a template.
+func templateFile(name string) string {
+	return filepath.Join(templateDirectory(), name+".json")
+}
+
+// Check if a name is a template name of letters, digits, '.', '_' and '-',
+// which is used as a file name.
+func isTemplateName(name string) bool {
+	if len(name) == 0 || name[0] == '.' {
+		return false
+	}
+	for _, char := range name {
+		if !(char >= 'A' && char <= 'Z' || char >= 'a' && char <= 'z' || char >= '0' && char <= '9' ||
+			char == '.' || char == '_' || char == '-') {
+			return false
+		}
+	}
+	return true
+}
+
+// Read a template. An error satisfying os.IsNotExist is returned if there is
+// no template of that name.
+func readTemplate(name string) (TransferTemplate, error) {
+	var template TransferTemplate
+	content, err := os.ReadFile(templateFile(name))
+	if err != nil {
+		return template, err
+	}
+	if err := json.Unmarshal(content, &template); err != nil {
+		return template, fmt.Errorf("%s: %v", templateFile(name), err)
+	}
+	return template, nil
+}
+
+// Read all templates, sorted by name.
+func readTemplates() ([]TransferTemplate, error) {
+	files, err := filepath.Glob(filepath.Join(templateDirectory(), "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	templates := []TransferTemplate{}
+	for _, file := range files {
+		template, err := readTemplate(strings.TrimSuffix(filepath.Base(file), ".json"))
+		if err != nil {
+			return nil, err
+		}
+		templates = append(templates, template)
+	}
+	sort.Slice(templates, func(i, j int) bool {
+		return templates[i].Name < templates[j].Name
+	})
+	return templates, nil
+}
+
+// Return the summary of a template, its description or its items.
+func (template TransferTemplate) summary() string {
+	if len(template.Description) > 0 {
+		return template.Description
+	}
+	items := template.Route.transferItems()
+	if len(items) == 1 {
+		return items[0].Source + " -> " + items[0].Destination
+	}
+	return message("templateItems", len(items))
+}
+
+// Save a template, replacing the template of the same name.
+func saveTemplate(template TransferTemplate) error {
+	if err := os.MkdirAll(templateDirectory(), 0700); err != nil {
+		return err
+	}
+	content, err := json.MarshalIndent(template, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(templateFile(template.Name), content, 0600)
+}
+
+// Read a route definition from a JSON file, or a YAML file ending in .yaml
+// or .yml, see yamldefinition.go.
+// A route without a name is named after the file.
+func readRouteDefinition(fileName string) (Route, error) {
+	var route Route
+	var err error
+	extension := filepath.Ext(fileName)
+	if strings.EqualFold(extension, ".yaml") || strings.EqualFold(extension, ".yml") {
+		route, err = readYamlDefinition(fileName)
+	} else {
+		var content []byte
+		if content, err = os.ReadFile(fileName); err == nil {
+			if err = json.Unmarshal(content, &route); err != nil {
+				err = fmt.Errorf("%s: %v", fileName, err)
+			}
+		}
+	}
+	if len(route.Name) == 0 {
+		route.Name = strings.TrimSuffix(filepath.Base(fileName), extension)
+	}
+	return route, err
+}
+
+// Command "template" - manage transfer templates.
+func templateCommand(args []string) int {
+	if len(args) == 0 {
+		printMessage("templateUsage")
+		return 2
+	}
+	run, found := templateCommands[args[0]]
+	if !found {
+		printMessage("templateUsage")
+		return 2
+	}
+	return run(args[1:])
+}
+
+// Command "template create" - create a template from a route.
+func templateCreateCommand(args []string) int {
+	flags := newFlagSet("template create")
+	name := flags.String("name", "", "Name of the template")
+	routeName := flags.String("route", "", "Route of the configuration defining the transfer")
+	routeFile := flags.String("file", "", "JSON or YAML file with the route defining the transfer")
+	description := flags.String("description", "", "Description of the template")
+	replace := flags.Bool("replace", false, "Replace an existing template of the same name")
+	items := []TransferItem{}
+	flags.Func("item", "Transfer SOURCE=DESTINATION instead of the items of the route. May be repeated", func(value string) error {
+		item, err := parseTransferItem(value)
+		items = append(items, item)
+		return err
+	})
+	if !parseCommandLine(flags, args) || flags.NArg() != 0 || (len(*routeName) > 0 && len(*routeFile) > 0) {
+		printMessage("templateUsage")
+		return 2
+	}
+	if !isTemplateName(*name) {
+		printMessage("templateNameInvalid", *name)
+		return 2
+	}
+	if _, err := readTemplate(*name); err == nil && !*replace {
+		printMessage("templateExists", *name)
+		return 1
+	}
+
+	var route Route
+	var err error
+	if len(*routeFile) > 0 {
+		route, err = readRouteDefinition(*routeFile)
+	} else {
+		route, err = findRoute(*routeName)
+	}
+	if err != nil {
+		printError(err)
+		return 2
+	}
+	route.Name = *name
+	if len(items) > 0 {
+		route.Items = items
+	}
+	// The template is saved without the metadata of this run
+	submission, err := route.forSubmission(nil)
+	if err != nil {
+		printError(err)
+		return 2
+	}
+	template := TransferTemplate{Name: *name, Description: *description, CreatedAt: time.Now().UTC(), Route: route}
+	if err := saveTemplate(template); err != nil {
+		printMessage("templateSaveFailed", *name, err)
+		return 1
+	}
+	printMessage("templateCreated", *name, submission.SourceAgent, submission.DestinationAgent, len(submission.transferItems()))
+	return 0
+}
+
+// Command "template list" - list the templates.
+func templateListCommand(args []string) int {
+	flags := newFlagSet("template list")
+	name := flags.String("name", "*", "List the templates whose name matches a wildcard pattern")
+	if !parseCommandLine(flags, args) || flags.NArg() != 0 {
+		printMessage("templateUsage")
+		return 2
+	}
+	if _, err := path.Match(*name, ""); err != nil {
+		printMessage("templateUsage")
+		return 2
+	}
+	templates, err := readTemplates()
+	if err != nil {
+		printMessage("templateReadFailed", err)
+		return 1
+	}
+	matching := []TransferTemplate{}
+	for _, template := range templates {
+		if matched, _ := path.Match(*name, template.Name); matched {
+			matching = append(matching, template)
+		}
+	}
+	if len(matching) == 0 {
+		printMessage("templateNone")
+		return 0
+	}
+	fmt.Printf("%-20s %-40s %s\n", "NAME", "AGENTS", "SUMMARY")
+	for _, template := range matching {
+		agents := template.Route.SourceAgent + " -> " + template.Route.DestinationAgent
+		fmt.Printf("%-20s %-40s %s\n", template.Name, agents, template.summary())
+	}
+	return 0
+}
+
+// Command "template delete" - delete a template.
+func templateDeleteCommand(args []string) int {
+	flags := newFlagSet("template delete")
+	force := flags.Bool("force", false, "Delete the template without asking for confirmation")
+	if !parseCommandLine(flags, args) || flags.NArg() != 1 {
+		printMessage("templateUsage")
+		return 2
+	}
+	name := flags.Arg(0)
+	if !isTemplateName(name) {
+		printMessage("templateNameInvalid", name)
+		return 2
+	}
+	template, err := readTemplate(name)
+	if os.IsNotExist(err) {
+		printMessage("templateNotFound", name)
+		return 1
+	} else if err != nil {
+		printMessage("templateReadFailed", err)
+		return 1
+	}
+	if !*force && !askConfirmation(message("templateDeleteConfirm", name, template.summary())) {
+		printMessage("templateNotDeleted", name)
+		return 1
+	}
+	if err := os.Remove(templateFile(name)); err != nil {
+		printMessage("templateDeleteFailed", name, err)
+		return 1
+	}
+	printMessage("templateDeleted", name)
+	return 0
+}
+
+// Set the source of the single item of a template.
+func setTemplateSource(route *Route, value string) error {
+	switch len(route.Items) {
+	case 0:
+		route.SourceItem = value
+	case 1:
+		route.Items[0].Source = value
+	default:
+		return fmt.Errorf("the template has %d items, use -item to replace them", len(route.Items))
+	}
+	return nil
+}
+
+// Set the destination of the single item of a template.
+func setTemplateDestination(route *Route, value string) error {
+	switch len(route.Items) {
+	case 0:
+		route.DestinationItem = value
+	case 1:
+		route.Items[0].Destination = value
+	default:
+		return fmt.Errorf("the template has %d items, use -item to replace them", len(route.Items))
+	}
+	return nil
+}
+
+// Parse a -set flag, KEY=VALUE with a key of templateOverrides.
+func parseTemplateOverride(value string, overrides map[string]string) error {
+	key, value, found := strings.Cut(value, "=")
+	if !found {
+		return fmt.Errorf("%q must be KEY=VALUE", key)
+	}
+	if _, known := templateOverrides[key]; !known {
+		keys := make([]string, 0, len(templateOverrides))
+		for key := range templateOverrides {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		return fmt.Errorf("%q is not %s", key, strings.Join(keys, ", "))
+	}
+	overrides[key] = value
+	return nil
+}
+
+/* Find the route of a template to submit, with its fields overridden, and
+* prepare it for submission as findSubmissionRoute does for a route.
+* name      - Name of the template
+* overrides - Values of the fields to override, by key of templateOverrides
+* items     - Items replacing those of the template, may be empty
+ */
+func findTemplateRoute(name string, overrides map[string]string, items []TransferItem) (Route, error) {
+	template, err := readTemplate(name)
+	if os.IsNotExist(err) {
+		return Route{}, fmt.Errorf("%s", message("templateNotFound", name))
+	} else if err != nil {
+		return Route{}, err
+	}
+	route := template.Route
+	route.Name = template.Name
+	for key, value := range overrides {
+		if err := templateOverrides[key](&route, value); err != nil {
+			return route, fmt.Errorf("template %s: -set %s: %v", name, key, err)
+		}
+	}
+	return route.forSubmission(items)
+}
@@ -0,0 +1,120 @@
+/*
+© Copyright IBM Corporation 2022, 2022
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+/*
+* This file contains the HTTP wire trace of -trace-http, to diagnose the
+* requests that the MQ Web Server rejects, typically with 400 Bad Request.
+* Every request sent by the HTTP client, including retries, redirects and the
+* pings of gateways, is dumped with its request line, headers and body as
+* sent, followed by the status line, headers and body of its response or by
+* the error of the request:
+*
+*   >>> #3 2026-10-17T10:00:00.123Z
+*   POST /ibmmq/rest/v2/admin/mft/transfer HTTP/1.1
+*   Host: mqweb.example.com:9443
+*   Authorization: Basic ****
+*   ...
+*   <<< #3 400 Bad Request in 35ms
+*   HTTP/1.1 400 Bad Request
+*   ...
+*
+* The trace is written to the file given to -trace-http, appended to, or to
+* standard error with -trace-http -. Secrets are masked as in the log, see
+* redact.go.
+ */
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httputil"
+	"os"
+	"sync"
+	"time"
+)
+
+// Value of -trace-http: file of the trace, - for standard error.
+var traceHttpFlag string
+
+// Transport writing the requests and responses of another transport to a
+// trace.
+type tracingTransport struct {
+	next     http.RoundTripper
+	out      io.Writer
+	mutex    sync.Mutex
+	sequence int
+}
+
+/* Return the transport of the HTTP client, tracing the requests when
+* -trace-http is given.
+* transport - Transport sending the requests
+ */
+func traceTransport(transport http.RoundTripper) (http.RoundTripper, error) {
+	switch traceHttpFlag {
+	case "":
+		return transport, nil
+	case "-":
+		return &tracingTransport{next: transport, out: os.Stderr}, nil
+	}
+	file, err := os.OpenFile(traceHttpFlag, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("%s", message("traceOpenFailed", traceHttpFlag, err))
+	}
+	return &tracingTransport{next: transport, out: file}, nil
+}
+
+/* Send a request and write it and its response to the trace. The bodies are
+* read for the dump and replaced, so that the caller reads them as usual.
+* request - Request to send
+ */
+func (tracer *tracingTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	tracer.mutex.Lock()
+	tracer.sequence++
+	sequence := tracer.sequence
+	tracer.mutex.Unlock()
+
+	started := time.Now()
+	dump, err := httputil.DumpRequestOut(request, true)
+	if err != nil {
+		dump = []byte(fmt.Sprintf("%s %s: %v\n", request.Method, request.URL, err))
+	}
+	tracer.write(fmt.Sprintf(">>> #%d %s\n", sequence, started.UTC().Format(time.RFC3339Nano)), dump)
+
+	response, err := tracer.next.RoundTrip(request)
+	elapsed := time.Since(started).Round(time.Millisecond)
+	if err != nil {
+		tracer.write(fmt.Sprintf("<<< #%d failed in %s\n", sequence, elapsed), []byte(err.Error()))
+		return response, err
+	}
+	dump, dumpErr := httputil.DumpResponse(response, true)
+	if dumpErr != nil {
+		dump = []byte(dumpErr.Error())
+	}
+	tracer.write(fmt.Sprintf("<<< #%d %s in %s\n", sequence, response.Status, elapsed), dump)
+	return response, nil
+}
+
+/* Write an entry of the trace with its secrets masked.
+* heading - First line of the entry
+* dump    - Request, response or error
+ */
+func (tracer *tracingTransport) write(heading string, dump []byte) {
+	text := redact(string(dump))
+	if len(text) == 0 || text[len(text)-1] != '\n' {
+		text += "\n"
+	}
+	tracer.mutex.Lock()
+	defer tracer.mutex.Unlock()
+	fmt.Fprint(tracer.out, heading+text+"\n")
+}
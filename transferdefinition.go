@@ -0,0 +1,292 @@
+/*
+© Copyright IBM Corporation 2022, 2022
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+/*
+* This file contains the import of the XML transfer definitions of the
+* fteCreateTransfer command, given with the -td flag of "submit". Two forms
+* are accepted. A transfer specification lists items only, the agents being
+* taken from the route:
+*
+*   <transferSpecifications>
+*     <item mode="text" checksumMethod="MD5">
+*       <source disposition="delete"><file encoding="IBM-1047">/src/orders.txt</file></source>
+*       <destination type="file" exist="overwrite"><file EOL="LF">/dst/orders.txt</file></destination>
+*     </item>
+*   </transferSpecifications>
+*
+* A transfer request, as generated by fteCreateTransfer -gt, also gives the
+* agents, the priority, the user defined metadata and the job name, and
+* replaces the route:
+*
+*   <request version="4.00">
+*     <managedTransfer>
+*       <sourceAgent agent="SRC" QMgr="SRCQM"/>
+*       <destinationAgent agent="DEST" QMgr="DESTQM"/>
+*       <transferSet priority="5">
+*         <metaDataSet><metaData key="department">finance</metaData></metaDataSet>
+*         <item> ... </item>
+*       </transferSet>
+*       <job><name>PAYROLL</name></job>
+*     </managedTransfer>
+*   </request>
+*
+* The agent finds out whether a <file> source is a file or a directory, the
+* REST API needs its type: a source with a recursive attribute is taken as a
+* directory, a name starting with // as a dataset and any other as a file.
+* Elements without a counterpart in this program, such as schedules, triggers
+* and program calls, are reported rather than ignored.
+ */
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Root of a transfer definition, a request or transfer specifications.
+type tdDefinition struct {
+	XMLName         xml.Name
+	ManagedTransfer *tdManagedTransfer `xml:"managedTransfer"`
+	Items           []tdItem           `xml:"item"`
+	Unsupported     []tdElement        `xml:",any"`
+}
+
+// Managed transfer of a transfer request.
+type tdManagedTransfer struct {
+	SourceAgent      tdAgent       `xml:"sourceAgent"`
+	DestinationAgent tdAgent       `xml:"destinationAgent"`
+	TransferSet      tdTransferSet `xml:"transferSet"`
+	JobName          string        `xml:"job>name"`
+	Originator       tdElement     `xml:"originator"`
+	Unsupported      []tdElement   `xml:",any"`
+}
+
+// Agent of a transfer request.
+type tdAgent struct {
+	Agent string `xml:"agent,attr"`
+	QMgr  string `xml:"QMgr,attr"`
+}
+
+// Transfer set of a transfer request.
+type tdTransferSet struct {
+	Priority    *int         `xml:"priority,attr"`
+	MetaData    []tdMetaData `xml:"metaDataSet>metaData"`
+	Items       []tdItem     `xml:"item"`
+	Unsupported []tdElement  `xml:",any"`
+}
+
+// User defined metadata entry.
+type tdMetaData struct {
+	Key   string `xml:"key,attr"`
+	Value string `xml:",chardata"`
+}
+
+// Item of a transfer.
+type tdItem struct {
+	Mode           string      `xml:"mode,attr"`
+	ChecksumMethod string      `xml:"checksumMethod,attr"`
+	Source         tdEnd       `xml:"source"`
+	Destination    tdEnd       `xml:"destination"`
+	Unsupported    []tdElement `xml:",any"`
+}
+
+// Source or destination of an item.
+type tdEnd struct {
+	Type        string      `xml:"type,attr"`
+	Exist       string      `xml:"exist,attr"`
+	Disposition string      `xml:"disposition,attr"`
+	Recursive   *bool       `xml:"recursive,attr"`
+	File        *tdFile     `xml:"file"`
+	Queue       *tdQueue    `xml:"queue"`
+	Unsupported []tdElement `xml:",any"`
+}
+
+// File, directory or dataset of an item.
+type tdFile struct {
+	Encoding string `xml:"encoding,attr"`
+	EOL      string `xml:"EOL,attr"`
+	Name     string `xml:",chardata"`
+}
+
+// Queue of an item.
+type tdQueue struct {
+	Persistent string `xml:"persistent,attr"`
+	SetMqProps bool   `xml:"setMqProps,attr"`
+	UseGroups  bool   `xml:"useGroups,attr"`
+	Name       string `xml:",chardata"`
+}
+
+// Element of a transfer definition that is not converted.
+type tdElement struct {
+	XMLName xml.Name
+}
+
+// Transfer read from a transfer definition file. Route is set for a transfer
+// request, Items and ChecksumMethod for transfer specifications.
+type TransferDefinition struct {
+	Route          *Route
+	Items          []TransferItem
+	ChecksumMethod string
+}
+
+/* Read a transfer definition file.
+* fileName - Path of the XML file
+ */
+func readTransferDefinition(fileName string) (TransferDefinition, error) {
+	var transfer TransferDefinition
+	content, err := os.ReadFile(fileName)
+	if err != nil {
+		return transfer, err
+	}
+	var definition tdDefinition
+	if err := xml.Unmarshal(content, &definition); err != nil {
+		return transfer, fmt.Errorf("%s: %v", fileName, err)
+	}
+	switch definition.XMLName.Local {
+	case "request":
+		if definition.ManagedTransfer == nil {
+			err = fmt.Errorf("the request has no managedTransfer")
+			break
+		}
+		transfer.Route, err = definition.ManagedTransfer.route()
+	case "transferSpecifications":
+		transfer.Items, err = tdTransferItems(definition.Items)
+		transfer.ChecksumMethod = tdChecksumMethod(definition.Items)
+	default:
+		err = fmt.Errorf("<%s> is not a request or transferSpecifications", definition.XMLName.Local)
+	}
+	if err == nil {
+		err = tdUnsupported("", definition.Unsupported)
+	}
+	if err != nil {
+		return TransferDefinition{}, fmt.Errorf("%s: %v", fileName, err)
+	}
+	return transfer, nil
+}
+
+// Return the route of a managed transfer.
+func (transfer tdManagedTransfer) route() (*Route, error) {
+	if err := tdUnsupported("managedTransfer", transfer.Unsupported); err != nil {
+		return nil, err
+	}
+	if err := tdUnsupported("transferSet", transfer.TransferSet.Unsupported); err != nil {
+		return nil, err
+	}
+	items, err := tdTransferItems(transfer.TransferSet.Items)
+	if err != nil {
+		return nil, err
+	}
+	route := Route{
+		Name:             "td",
+		SourceAgent:      transfer.SourceAgent.Agent,
+		SourceQM:         transfer.SourceAgent.QMgr,
+		DestinationAgent: transfer.DestinationAgent.Agent,
+		DestinationQM:    transfer.DestinationAgent.QMgr,
+		Priority:         transfer.TransferSet.Priority,
+		JobName:          strings.TrimSpace(transfer.JobName),
+		Items:            items,
+	}
+	if len(transfer.TransferSet.MetaData) > 0 {
+		route.Metadata = map[string]string{}
+		for _, metaData := range transfer.TransferSet.MetaData {
+			route.Metadata[metaData.Key] = metaData.Value
+		}
+	}
+	route.ChecksumMethod = tdChecksumMethod(transfer.TransferSet.Items)
+	return &route, nil
+}
+
+// Return the checksum method of the items of a transfer definition. A
+// transfer has a single checksum method, that of the last item giving one.
+func tdChecksumMethod(tdItems []tdItem) string {
+	method := ""
+	for _, item := range tdItems {
+		if len(item.ChecksumMethod) > 0 {
+			method = item.ChecksumMethod
+		}
+	}
+	return method
+}
+
+// Return the transfer items of the items of a transfer definition.
+func tdTransferItems(tdItems []tdItem) ([]TransferItem, error) {
+	if len(tdItems) == 0 {
+		return nil, fmt.Errorf("the transfer definition has no items")
+	}
+	items := []TransferItem{}
+	for index, tdItem := range tdItems {
+		element := fmt.Sprintf("item %d", index+1)
+		for _, err := range []error{tdUnsupported(element, tdItem.Unsupported),
+			tdUnsupported(element+" source", tdItem.Source.Unsupported),
+			tdUnsupported(element+" destination", tdItem.Destination.Unsupported)} {
+			if err != nil {
+				return nil, err
+			}
+		}
+		item := TransferItem{Mode: tdItem.Mode, SourceDisposition: tdItem.Source.Disposition,
+			Recursive: tdItem.Source.Recursive, ActionIfExists: tdItem.Destination.Exist}
+		switch source := tdItem.Source; {
+		case source.Queue != nil:
+			item.Source, item.SourceType = strings.TrimSpace(source.Queue.Name), "queue"
+			item.GroupMessages = source.Queue.UseGroups
+		case source.File != nil:
+			item.Source, item.SourceEncoding = strings.TrimSpace(source.File.Name), source.File.Encoding
+			switch {
+			case isDatasetPath(item.Source):
+				item.SourceType = "dataset"
+			case source.Recursive != nil:
+				item.SourceType = "directory"
+			default:
+				item.SourceType = "file"
+			}
+		default:
+			return nil, fmt.Errorf("%s has no source file or queue", element)
+		}
+		switch destination := tdItem.Destination; {
+		case destination.Queue != nil:
+			item.Destination, item.DestinationType = strings.TrimSpace(destination.Queue.Name), "queue"
+			item.SetMqProps = destination.Queue.SetMqProps
+			switch strings.ToLower(destination.Queue.Persistent) {
+			case "true":
+				item.MessagePersistence = "persistent"
+			case "false":
+				item.MessagePersistence = "nonPersistent"
+			case "qdef":
+				item.MessagePersistence = "queueDefault"
+			}
+		case destination.File != nil:
+			item.Destination, item.DestinationType = strings.TrimSpace(destination.File.Name), destination.Type
+			item.DestinationEncoding, item.DestinationEOL = destination.File.Encoding, destination.File.EOL
+			if len(item.DestinationType) == 0 {
+				item.DestinationType = "file"
+			}
+		default:
+			return nil, fmt.Errorf("%s has no destination file or queue", element)
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+// Report the first element of a transfer definition that is not converted.
+func tdUnsupported(parent string, elements []tdElement) error {
+	if len(elements) == 0 {
+		return nil
+	}
+	if len(parent) == 0 {
+		return fmt.Errorf("<%s> is not supported", elements[0].XMLName.Local)
+	}
+	return fmt.Errorf("<%s> of %s is not supported", elements[0].XMLName.Local, parent)
+}
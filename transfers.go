@@ -0,0 +1,239 @@
+/*
+© Copyright IBM Corporation 2022, 2022
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+/*
+* This file contains the "list" command, which lists the transfers known to
+* the MQ Web Server, optionally only those of a -source-agent or
+* -destination-agent, in a -state or started in a time window given by
+* -since and -until. The filters are sent to the MQ Web Server and applied to
+* the response as well, for servers that ignore them.
+*
+* The transfers are printed as a table, or with -output json as the JSON
+* returned by the MQ Web Server, whose attributes are selected with
+* -attributes. With -watch the table of the transfers in progress is
+* refreshed, see watch.go.
+ */
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tidwall/gjson"
+)
+
+// Filters of the transfers listed.
+type TransferFilter struct {
+	SourceAgent      string
+	DestinationAgent string
+	States           []string
+	Since            time.Time
+	Until            time.Time
+	RunId            string
+}
+
+/* Parse the time of -since or -until: a duration before now such as 90m or
+* 2h, a local time yyyy-MM-ddThh:mm or an RFC 3339 time.
+* value - Value of the flag
+* now   - Current time
+ */
+func parseTimeFilter(value string, now time.Time) (time.Time, error) {
+	if duration, err := time.ParseDuration(value); err == nil {
+		return now.Add(-duration), nil
+	}
+	if parsed, err := time.ParseInLocation(scheduleTimeLayout, value, time.Local); err == nil {
+		return parsed, nil
+	}
+	if parsed, err := time.Parse(time.RFC3339, value); err == nil {
+		return parsed, nil
+	}
+	return time.Time{}, fmt.Errorf("%q is not a duration such as 2h, a time yyyy-MM-ddThh:mm or an RFC 3339 time", value)
+}
+
+// Return the query parameters of the filter.
+func (filter TransferFilter) query() url.Values {
+	query := url.Values{}
+	if len(filter.SourceAgent) > 0 {
+		query.Set("sourceAgentName", filter.SourceAgent)
+	}
+	if len(filter.DestinationAgent) > 0 {
+		query.Set("destinationAgentName", filter.DestinationAgent)
+	}
+	if len(filter.States) > 0 {
+		query.Set("state", strings.Join(filter.States, ","))
+	}
+	if !filter.Since.IsZero() {
+		query.Set("startTime", filter.Since.UTC().Format(time.RFC3339))
+	}
+	if !filter.Until.IsZero() {
+		query.Set("endTime", filter.Until.UTC().Format(time.RFC3339))
+	}
+	return query
+}
+
+// Check if a transfer passes the filter. Attributes not returned by the
+// MQ Web Server, because -attributes leaves them out or because the transfer
+// has not started, are not checked, except the run ID that transfers not
+// submitted by this program do not have.
+func (filter TransferFilter) matches(transfer gjson.Result) bool {
+	if len(filter.RunId) > 0 && transfer.Get("transferSet.userDefinedMetadata."+runIdMetadataKey).String() != filter.RunId {
+		return false
+	}
+	if agent := transfer.Get("sourceAgent.name"); agent.Exists() && len(filter.SourceAgent) > 0 &&
+		!strings.EqualFold(agent.String(), filter.SourceAgent) {
+		return false
+	}
+	if agent := transfer.Get("destinationAgent.name"); agent.Exists() && len(filter.DestinationAgent) > 0 &&
+		!strings.EqualFold(agent.String(), filter.DestinationAgent) {
+		return false
+	}
+	if state := transfer.Get("status.state"); state.Exists() && len(filter.States) > 0 {
+		matched := false
+		for _, filterState := range filter.States {
+			matched = matched || strings.EqualFold(state.String(), filterState)
+		}
+		if !matched {
+			return false
+		}
+	}
+	started, err := time.Parse(time.RFC3339, transfer.Get("statistics.startTime").String())
+	if err != nil {
+		return true
+	}
+	return (filter.Since.IsZero() || !started.Before(filter.Since)) && (filter.Until.IsZero() || started.Before(filter.Until))
+}
+
+/* Query the transfers passing a filter. Returns false if the query failed.
+* transfersUrl - URL of the transfer collection, with the query parameters
+* filter       - Filter of the transfers
+* limit        - Maximum number of transfers returned, 0 for no limit
+ */
+func queryTransfers(transfersUrl string, filter TransferFilter, limit int) ([]gjson.Result, bool) {
+	response, body, err := callMQWeb("GET", transfersUrl, "")
+	if err != nil {
+		logMessage("statusQueryFailed", []any{logUrl, transfersUrl}, transfersUrl, err)
+		return nil, false
+	}
+	if response.StatusCode != http.StatusOK {
+		logMessage("statusQueryFailed", []any{logUrl, transfersUrl}, transfersUrl, mqWebError(response, body))
+		return nil, false
+	}
+	transfers := []gjson.Result{}
+	for _, transfer := range gjson.Get(body, "transfer").Array() {
+		if (limit == 0 || len(transfers) < limit) && filter.matches(transfer) {
+			transfers = append(transfers, transfer)
+		}
+	}
+	return transfers, true
+}
+
+// Output formats of the list command.
+var transferListFormats = map[string]bool{"table": true, "json": true, "csv": true, "csv-items": true}
+
+// Command "list" - list transfers.
+func listCommand(args []string) int {
+	flags := newFlagSet("list")
+	routeName := flags.String("route", "", "Route whose MQ Web Server is queried")
+	sourceAgent := flags.String("source-agent", "", "List the transfers from an agent only")
+	destinationAgent := flags.String("destination-agent", "", "List the transfers to an agent only")
+	states := flags.String("state", "", "List the transfers in one of a comma separated list of states only")
+	since := flags.String("since", "", "List the transfers started after a time, or a duration before now such as 2h")
+	until := flags.String("until", "", "List the transfers started before a time, or a duration before now")
+	attributes := flags.String("attributes", "*", "Comma separated attributes of the transfers returned with -output json")
+	limit := flags.Int("limit", 0, "Maximum number of transfers listed, 0 for no limit")
+	output := flags.String("output", "table", "Output format, table, json, csv or csv-items")
+	watch := flags.Bool("watch", false, "Refresh the table of the transfers in progress until interrupted")
+	interval := flags.Duration("interval", 5*time.Second, "Interval between the refreshes of -watch")
+	if !parseCommandLine(flags, args) || flags.NArg() != 0 || !transferListFormats[*output] || *limit < 0 ||
+		(*watch && (*output != "table" || *interval <= 0)) {
+		printMessage("listUsage")
+		return 2
+	}
+	filter := TransferFilter{SourceAgent: *sourceAgent, DestinationAgent: *destinationAgent}
+	if len(*states) > 0 {
+		filter.States = strings.Split(*states, ",")
+	}
+	var err error
+	now := time.Now()
+	if len(*since) > 0 {
+		filter.Since, err = parseTimeFilter(*since, now)
+	}
+	if err == nil && len(*until) > 0 {
+		filter.Until, err = parseTimeFilter(*until, now)
+	}
+	if err != nil {
+		printError(err)
+		return 2
+	}
+	route, err := findRoute(*routeName)
+	if err != nil {
+		printError(err)
+		return 2
+	}
+
+	query := filter.query()
+	// The table and CSV need the state, statistics and items of the transfers
+	if *output != "json" {
+		query.Set("attributes", "*")
+	} else {
+		query.Set("attributes", *attributes)
+	}
+	if *limit > 0 {
+		query.Set("limit", strconv.Itoa(*limit))
+	}
+	transfersUrl := strings.TrimSuffix(route.transferUrl(), "/") + "?" + query.Encode()
+	if *watch {
+		watchTransfers(transfersUrl, filter, *limit, *interval)
+	}
+	transfers, found := queryTransfers(transfersUrl, filter, *limit)
+	if !found {
+		return 1
+	}
+
+	if *output == "json" {
+		raw := []json.RawMessage{}
+		for _, transfer := range transfers {
+			raw = append(raw, json.RawMessage(transfer.Raw))
+		}
+		content, _ := json.Marshal(map[string]interface{}{"transfer": raw})
+		fmt.Println(indentRequest(string(content)))
+		return 0
+	}
+	if *output == "csv" || *output == "csv-items" {
+		if err := writeTransfersCsv(os.Stdout, transfers, *output == "csv-items"); err != nil {
+			printError(err)
+			return 1
+		}
+		return 0
+	}
+	printMessage("transfersListed", len(transfers))
+	if len(transfers) == 0 {
+		return 0
+	}
+	fmt.Printf("%-48s %-20s %-16s %-16s %s\n", "ID", "STATE", "SOURCE", "DESTINATION", "STARTED")
+	for _, transfer := range transfers {
+		started := transfer.Get("statistics.startTime").String()
+		if startTime, err := time.Parse(time.RFC3339, started); err == nil {
+			started = startTime.Local().Format("2006-01-02 15:04:05")
+		}
+		fmt.Printf("%-48s %-20s %-16s %-16s %s\n", transfer.Get("id").String(), transfer.Get("status.state").String(),
+			transfer.Get("sourceAgent.name").String(), transfer.Get("destinationAgent.name").String(), started)
+	}
+	return 0
+}
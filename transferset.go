@@ -0,0 +1,88 @@
+/*
+© Copyright IBM Corporation 2022, 2022
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+/*
+* This file contains the options of the transfer set that control a stalled
+* transfer and where the agent reports its outcome.
+*
+* "recoveryTimeout" is the number of seconds the source agent keeps trying to
+* recover a stalled transfer before marking it failed: 0 fails it at once and
+* -1 keeps recovering until the transfer completes. The agent property
+* transferRecoveryTimeout applies when it is not set. The -recovery-timeout
+* flag of the submit command overrides the route.
+*
+* "reply" names a queue, and its queue manager, to which the source agent puts
+* a reply message when the transfer completes, for an application to wait on.
+* With detailed, the reply gives the result of each item:
+*
+*   "reply": { "queue": "APP.MFT.REPLY", "queueManager": "APPQM", "detailed": true }
+ */
+package main
+
+import (
+	"strconv"
+
+	j "github.com/ricardolonga/jsongo"
+)
+
+// Recovery timeout that keeps recovering a stalled transfer until it completes.
+const recoverIndefinitely = -1
+
+// Queue to which the source agent replies when a transfer completes.
+type TransferReply struct {
+	Queue        string `json:"queue"`
+	QueueManager string `json:"queueManager"`
+	Detailed     bool   `json:"detailed"`
+}
+
+// Return the reply section of a transfer request.
+func (reply TransferReply) jsonObject() j.O {
+	object := j.Object().Put("name", reply.Queue)
+	if len(reply.QueueManager) > 0 {
+		object.Put("qmgrName", reply.QueueManager)
+	}
+	if reply.Detailed {
+		object.Put("detailed", true)
+	}
+	return object
+}
+
+/* Parse the value of the -recovery-timeout flag, a number of seconds or -1.
+* value - Value of the flag
+ */
+func parseRecoveryTimeout(value string) (*int, error) {
+	seconds, err := strconv.Atoi(value)
+	if err != nil {
+		return nil, err
+	}
+	return &seconds, nil
+}
+
+/* Check the recovery timeout and reply queue of a route.
+* route - Route of the transfer
+ */
+func validateTransferSetOptions(route Route) error {
+	var problems requestProblems
+	if route.RecoveryTimeout != nil && *route.RecoveryTimeout < recoverIndefinitely {
+		problems.add(jsonPointer("transferSet", "recoveryTimeout"), *route.RecoveryTimeout, "must be a number of seconds, or -1 to recover until the transfer completes")
+	}
+	if reply := route.Reply; reply != nil {
+		if !isMQName(reply.Queue) {
+			problems.add(jsonPointer("transferSet", "reply", "name"), reply.Queue, "must be a queue name of 1 to %d characters A-Z, a-z, 0-9, '.', '_', '/' or '%%'", maxMQNameLength)
+		}
+		if len(reply.QueueManager) > 0 && !isMQName(reply.QueueManager) {
+			problems.add(jsonPointer("transferSet", "reply", "qmgrName"), reply.QueueManager, "must be a queue manager name of 1 to %d characters A-Z, a-z, 0-9, '.', '_', '/' or '%%'", maxMQNameLength)
+		}
+	}
+	return problems.err()
+}
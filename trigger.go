@@ -0,0 +1,202 @@
+/*
+© Copyright IBM Corporation 2022, 2022
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+/*
+* This file contains the trigger conditions of resource monitors, built from
+* the flags of "monitor create" so that a complex trigger does not require
+* hand-written JSON. The -trigger flag selects the condition:
+*   - matchAll, the default: files match -include and not -exclude
+*   - noneMatch: no file matches -include and not -exclude
+*   - fileSize: a matching file is at least -file-size, such as 10MB
+*   - noSizeChange: the size of a matching file has not changed for
+*     -no-change-polls polls, so that files still being written are skipped
+*   - queueNotEmpty, the default of a queue: the queue has messages
+*   - completeGroups: the queue has a complete group of messages
+*
+* The patterns are wildcards, * and ?, or regular expressions with -match
+* regex, and are checked before the monitor is created.
+ */
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	j "github.com/ricardolonga/jsongo"
+	"github.com/tidwall/gjson"
+)
+
+// Trigger conditions by the type of resource they apply to.
+var monitorTriggerTypes = map[string]string{
+	"matchAll":       "directory",
+	"noneMatch":      "directory",
+	"fileSize":       "directory",
+	"noSizeChange":   "directory",
+	"queueNotEmpty":  "queue",
+	"completeGroups": "queue",
+}
+
+// Units of the -file-size flag.
+var byteSizeUnits = map[string]int64{"": 1, "B": 1, "KB": 1 << 10, "MB": 1 << 20, "GB": 1 << 30, "TB": 1 << 40}
+
+// Condition triggering a monitor.
+type MonitorTrigger struct {
+	Type           string
+	IncludePattern string
+	ExcludePattern string
+	MatchPattern   string
+	FileSize       int64
+	NoChangePolls  int
+}
+
+/* Parse a size in bytes with an optional unit, B, KB, MB, GB or TB, in
+* powers of 1024.
+* value - Size such as 512, 64KB or 10MB
+ */
+func parseByteSize(value string) (int64, error) {
+	value = strings.ToUpper(strings.TrimSpace(value))
+	digits := strings.IndexFunc(value, func(r rune) bool { return r < '0' || r > '9' })
+	if digits < 0 {
+		digits = len(value)
+	}
+	size, err := strconv.ParseInt(value[:digits], 10, 64)
+	unit, found := byteSizeUnits[strings.TrimSpace(value[digits:])]
+	if err != nil || !found {
+		return 0, fmt.Errorf("size %q must be a number of bytes with an optional unit, KB, MB, GB or TB", value)
+	}
+	return size * unit, nil
+}
+
+/* Return the trigger condition with the type defaulted for a resource type.
+* resourceType - Type of the monitored resource
+ */
+func (trigger MonitorTrigger) withDefaults(resourceType string) MonitorTrigger {
+	if len(trigger.Type) == 0 {
+		trigger.Type = "matchAll"
+		if resourceType == "queue" {
+			trigger.Type = "queueNotEmpty"
+		}
+	}
+	return trigger
+}
+
+/* Check a trigger condition against the type of the monitored resource.
+* resourceType - Type of the monitored resource
+* problems     - Problems found
+ */
+func (trigger MonitorTrigger) validate(resourceType string, problems *requestProblems) {
+	pointer := func(name string) string {
+		return jsonPointer("triggerCondition", name)
+	}
+	appliesTo, found := monitorTriggerTypes[trigger.Type]
+	switch {
+	case !found:
+		problems.add(pointer("type"), trigger.Type, "must be matchAll, noneMatch, fileSize, noSizeChange, queueNotEmpty or completeGroups")
+		return
+	case appliesTo != resourceType:
+		problems.add(pointer("type"), trigger.Type, "only applies to a %s monitor", appliesTo)
+		return
+	}
+	if trigger.Type != "fileSize" && trigger.FileSize > 0 {
+		problems.add(pointer("fileSize"), trigger.FileSize, "only applies to a fileSize trigger")
+	}
+	if trigger.Type != "noSizeChange" && trigger.NoChangePolls > 0 {
+		problems.add(pointer("pollCount"), trigger.NoChangePolls, "only applies to a noSizeChange trigger")
+	}
+	if resourceType != "directory" {
+		return
+	}
+	if trigger.Type == "fileSize" && trigger.FileSize <= 0 {
+		problems.add(pointer("fileSize"), trigger.FileSize, "must be a size of at least 1 byte")
+	}
+	if trigger.Type == "noSizeChange" && trigger.NoChangePolls < 1 {
+		problems.add(pointer("pollCount"), trigger.NoChangePolls, "must be a number of polls of at least 1")
+	}
+	if !monitorMatchPatterns[trigger.MatchPattern] {
+		problems.add(pointer("matchPattern"), trigger.MatchPattern, "must be wildcard or regex")
+		return
+	}
+	if len(trigger.IncludePattern) == 0 {
+		problems.add(pointer("includeFilePattern"), nil, "the pattern of the files triggering the monitor is missing")
+	} else if err := checkTriggerPattern(trigger.IncludePattern, trigger.MatchPattern); err != nil {
+		problems.add(pointer("includeFilePattern"), trigger.IncludePattern, "%v", err)
+	}
+	if len(trigger.ExcludePattern) > 0 {
+		if err := checkTriggerPattern(trigger.ExcludePattern, trigger.MatchPattern); err != nil {
+			problems.add(pointer("excludeFilePattern"), trigger.ExcludePattern, "%v", err)
+		}
+	}
+}
+
+/* Check that a file pattern is valid in its syntax.
+* pattern - Pattern of file names
+* syntax  - wildcard or regex
+ */
+func checkTriggerPattern(pattern string, syntax string) error {
+	if syntax == "regex" {
+		if _, err := regexp.Compile(pattern); err != nil {
+			return fmt.Errorf("is not a valid regular expression")
+		}
+		return nil
+	}
+	if _, err := filepath.Match(pattern, ""); err != nil {
+		return fmt.Errorf("is not a valid wildcard pattern")
+	}
+	return nil
+}
+
+// Return the trigger condition section of a monitor request.
+func (trigger MonitorTrigger) jsonObject() j.O {
+	object := j.Object().Put("type", trigger.Type)
+	if monitorTriggerTypes[trigger.Type] != "directory" {
+		return object
+	}
+	object.Put("includeFilePattern", trigger.IncludePattern)
+	if len(trigger.ExcludePattern) > 0 {
+		object.Put("excludeFilePattern", trigger.ExcludePattern)
+	}
+	object.Put("matchPattern", trigger.MatchPattern)
+	if trigger.FileSize > 0 {
+		object.Put("fileSize", trigger.FileSize)
+	}
+	if trigger.NoChangePolls > 0 {
+		object.Put("pollCount", trigger.NoChangePolls)
+	}
+	return object
+}
+
+/* Describe the trigger condition of a monitor.
+* trigger - Trigger condition as returned by the monitor query
+ */
+func describeTrigger(trigger gjson.Result) string {
+	conditions := []string{trigger.Get("type").String()}
+	if include := trigger.Get("includeFilePattern").String(); len(include) > 0 {
+		conditions = append(conditions, "include "+include)
+	}
+	if exclude := trigger.Get("excludeFilePattern").String(); len(exclude) > 0 {
+		conditions = append(conditions, "exclude "+exclude)
+	}
+	if match := trigger.Get("matchPattern").String(); len(match) > 0 {
+		conditions = append(conditions, "("+match+")")
+	}
+	if fileSize := trigger.Get("fileSize").Int(); fileSize > 0 {
+		conditions = append(conditions, "size >= "+formatBytes(fileSize))
+	}
+	if polls := trigger.Get("pollCount").Int(); polls > 0 {
+		conditions = append(conditions, fmt.Sprintf("unchanged for %d polls", polls))
+	}
+	return strings.Join(conditions, " ")
+}
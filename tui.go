@@ -0,0 +1,313 @@
+/*
+© Copyright IBM Corporation 2022, 2022
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+/*
+* This file contains a terminal user interface to browse the item results of
+* a transfer, which may have many thousands of items. The following keys are
+* supported:
+*
+*   Up/Down, k/j        Move one item
+*   PgUp/PgDn, b/Space  Move one page
+*   Home/End, g/G       Move to first or last item
+*   f                   Cycle the state filter: all, failed, successful
+*   /                   Search source, destination and description
+*   Esc                 Clear the search
+*   q, Ctrl-C           Quit
+ */
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"golang.org/x/term"
+)
+
+// State filters of the item browser, selected in turn with f.
+var itemStateFilters = []string{"all", "failed", "successful"}
+
+// State of the item browser.
+type itemBrowser struct {
+	title    string
+	items    []ItemResult
+	visible  []ItemResult
+	filter   int
+	search   string
+	selected int
+	top      int
+	width    int
+	height   int
+}
+
+/* Browse the item results of a transfer until the user quits.
+* title - Title displayed at the top of the screen
+* items - Item results of the transfer
+ */
+func browseItemResults(title string, items []ItemResult) error {
+	keys, restore, err := openTerminal()
+	if err != nil {
+		return err
+	}
+	defer restore()
+	return browseItems(keys, title, items)
+}
+
+/* Switch the terminal to raw mode and the alternate screen. Returns the keys
+* pressed and the function restoring the terminal.
+ */
+func openTerminal() (<-chan string, func(), error) {
+	in, out := int(os.Stdin.Fd()), int(os.Stdout.Fd())
+	if !term.IsTerminal(in) || !term.IsTerminal(out) {
+		return nil, nil, fmt.Errorf("standard input and output must be a terminal")
+	}
+	state, err := term.MakeRaw(in)
+	if err != nil {
+		return nil, nil, err
+	}
+	// Use the alternate screen and hide the cursor
+	fmt.Print("\x1b[?1049h\x1b[?25l")
+	keys := make(chan string)
+	go func() {
+		reader := bufio.NewReader(os.Stdin)
+		for {
+			key, err := readKey(reader)
+			if err != nil {
+				close(keys)
+				return
+			}
+			keys <- key
+		}
+	}()
+	return keys, func() {
+		fmt.Print("\x1b[?25h\x1b[?1049l")
+		term.Restore(in, state)
+	}, nil
+}
+
+// Return the width and height of the terminal.
+func terminalSize() (int, int) {
+	width, height, err := term.GetSize(int(os.Stdout.Fd()))
+	if err != nil || height == 0 {
+		return 80, 24
+	}
+	return width, height
+}
+
+/* Browse item results on a terminal opened by openTerminal.
+* keys  - Keys pressed
+* title - Title displayed at the top of the screen
+* items - Item results of the transfer
+ */
+func browseItems(keys <-chan string, title string, items []ItemResult) error {
+	browser := &itemBrowser{title: title, items: items}
+	browser.applyFilter()
+	for {
+		browser.width, browser.height = terminalSize()
+		browser.render()
+		key, open := <-keys
+		if !open {
+			return io.EOF
+		}
+		switch key {
+		case "q", "\x03":
+			return nil
+		case "up", "k":
+			browser.move(-1)
+		case "down", "j":
+			browser.move(1)
+		case "pgup", "b":
+			browser.move(-browser.pageSize())
+		case "pgdn", " ":
+			browser.move(browser.pageSize())
+		case "home", "g":
+			browser.move(-len(browser.visible))
+		case "end", "G":
+			browser.move(len(browser.visible))
+		case "f":
+			browser.filter = (browser.filter + 1) % len(itemStateFilters)
+			browser.applyFilter()
+		case "/":
+			browser.search = promptLine(keys, browser.height, "Search: ")
+			browser.applyFilter()
+		case "esc":
+			browser.search = ""
+			browser.applyFilter()
+		}
+	}
+}
+
+// Read a key, decoding the escape sequences of cursor keys and the UTF-8
+// encoding of characters.
+func readKey(reader *bufio.Reader) (string, error) {
+	b, err := reader.ReadByte()
+	if err != nil {
+		return "", err
+	}
+	if b >= 0x80 {
+		reader.UnreadByte()
+		r, _, err := reader.ReadRune()
+		return string(r), err
+	}
+	if b != 0x1b {
+		return string(b), nil
+	}
+	if reader.Buffered() == 0 {
+		return "esc", nil
+	}
+	sequence := []byte{}
+	for reader.Buffered() > 0 {
+		next, _ := reader.ReadByte()
+		sequence = append(sequence, next)
+		if len(sequence) > 1 && (next == '~' || (next >= 'A' && next <= 'Z')) {
+			break
+		}
+	}
+	switch string(sequence) {
+	case "[A", "OA":
+		return "up", nil
+	case "[B", "OB":
+		return "down", nil
+	case "[5~":
+		return "pgup", nil
+	case "[6~":
+		return "pgdn", nil
+	case "[H", "OH", "[1~":
+		return "home", nil
+	case "[F", "OF", "[4~":
+		return "end", nil
+	}
+	return "", nil
+}
+
+// Number of item lines on the screen.
+func (browser *itemBrowser) pageSize() int {
+	// Title, header, description and status lines
+	if browser.height > 5 {
+		return browser.height - 4
+	}
+	return 1
+}
+
+// Select the items matching the state filter and search text.
+func (browser *itemBrowser) applyFilter() {
+	browser.visible = []ItemResult{}
+	search := strings.ToLower(browser.search)
+	for _, item := range browser.items {
+		successful := strings.EqualFold(item.State, "successful")
+		switch itemStateFilters[browser.filter] {
+		case "failed":
+			if successful {
+				continue
+			}
+		case "successful":
+			if !successful {
+				continue
+			}
+		}
+		if len(search) > 0 && !strings.Contains(strings.ToLower(item.Source+"\n"+item.Destination+"\n"+item.Description), search) {
+			continue
+		}
+		browser.visible = append(browser.visible, item)
+	}
+	browser.selected, browser.top = 0, 0
+}
+
+// Move the selection by a number of items.
+func (browser *itemBrowser) move(delta int) {
+	browser.selected += delta
+	if browser.selected >= len(browser.visible) {
+		browser.selected = len(browser.visible) - 1
+	}
+	if browser.selected < 0 {
+		browser.selected = 0
+	}
+	if browser.selected < browser.top {
+		browser.top = browser.selected
+	}
+	if browser.selected >= browser.top+browser.pageSize() {
+		browser.top = browser.selected - browser.pageSize() + 1
+	}
+}
+
+// Draw the screen.
+func (browser *itemBrowser) render() {
+	var screen strings.Builder
+	screen.WriteString("\x1b[H\x1b[2J")
+	screen.WriteString("\x1b[1m" + fitWidth(browser.title, browser.width) + "\x1b[0m\r\n")
+	column := (browser.width - 14) / 2
+	if column < 10 {
+		column = 10
+	}
+	screen.WriteString(fitWidth(fmt.Sprintf("%-12s %-*s %s", "STATE", column, "SOURCE", "DESTINATION"), browser.width) + "\r\n")
+	for row := 0; row < browser.pageSize(); row++ {
+		index := browser.top + row
+		if index < len(browser.visible) {
+			item := browser.visible[index]
+			line := fitWidth(fmt.Sprintf("%-12s %-*s %s", item.State, column, fitWidth(item.Source, column), item.Destination), browser.width)
+			if index == browser.selected {
+				line = "\x1b[7m" + line + "\x1b[0m"
+			}
+			screen.WriteString(line)
+		}
+		screen.WriteString("\r\n")
+	}
+	if browser.selected < len(browser.visible) {
+		screen.WriteString(fitWidth(browser.visible[browser.selected].Description, browser.width))
+	}
+	screen.WriteString("\r\n")
+	status := fmt.Sprintf("%d/%d items  filter:%s", len(browser.visible), len(browser.items), itemStateFilters[browser.filter])
+	if len(browser.search) > 0 {
+		status += "  search:" + browser.search
+	}
+	status += "  [f]ilter [/]search [q]uit"
+	screen.WriteString("\x1b[7m" + fitWidth(status, browser.width) + "\x1b[0m")
+	fmt.Print(screen.String())
+}
+
+/* Read a line of text on the last line of the screen. Returns an empty text
+* when Esc is pressed.
+* keys   - Keys pressed
+* line   - Line of the screen, the height of the terminal
+* label  - Label displayed before the text
+ */
+func promptLine(keys <-chan string, line int, label string) string {
+	text := []rune{}
+	for {
+		fmt.Printf("\x1b[%d;1H\x1b[2K%s%s", line, label, string(text))
+		key, open := <-keys
+		switch {
+		case !open || key == "esc" || key == "\x03":
+			return ""
+		case key == "\r" || key == "\n":
+			return string(text)
+		case key == "\x7f" || key == "\x08":
+			if len(text) > 0 {
+				text = text[:len(text)-1]
+			}
+		case len([]rune(key)) == 1 && []rune(key)[0] >= 0x20:
+			text = append(text, []rune(key)[0])
+		}
+	}
+}
+
+// Truncate a text to the width of the screen.
+func fitWidth(text string, width int) string {
+	runes := []rune(text)
+	if width > 0 && len(runes) > width {
+		return string(runes[:width])
+	}
+	return text
+}
@@ -0,0 +1,69 @@
+/*
+© Copyright IBM Corporation 2022, 2022
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+/*
+* This file contains the reporting of problems found when checking a transfer
+* request before it is submitted. Each problem names the attribute of the
+* request at fault by its JSON pointer (RFC 6901), with the offending value:
+*
+*   /transferSet/item/3/destination/type: "fil" must be file or directory
+*
+* Items are numbered from 0 in the order of the route or manifest, so item 3
+* is the fourth row of a manifest.
+ */
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Problems found in a transfer request, one per line.
+type requestProblems []string
+
+/* Add a problem with an attribute of the request.
+* pointer - JSON pointer of the attribute, see jsonPointer
+* value   - Offending value, nil if not relevant
+* format  - Description of the problem
+ */
+func (problems *requestProblems) add(pointer string, value interface{}, format string, args ...interface{}) {
+	description := fmt.Sprintf(format, args...)
+	switch value := value.(type) {
+	case nil:
+		*problems = append(*problems, fmt.Sprintf("%s: %s", pointer, description))
+	case string:
+		*problems = append(*problems, fmt.Sprintf("%s: %q %s", pointer, value, description))
+	default:
+		*problems = append(*problems, fmt.Sprintf("%s: %v %s", pointer, value, description))
+	}
+}
+
+// Return the problems as an error, nil if there are none.
+func (problems requestProblems) err() error {
+	if len(problems) == 0 {
+		return nil
+	}
+	return fmt.Errorf("%s", strings.Join(problems, "\n"))
+}
+
+// Return the JSON pointer of an attribute of a transfer request from its path
+// of attribute names and array indexes.
+func jsonPointer(tokens ...interface{}) string {
+	var pointer strings.Builder
+	escaper := strings.NewReplacer("~", "~0", "/", "~1")
+	for _, token := range tokens {
+		pointer.WriteString("/")
+		pointer.WriteString(escaper.Replace(fmt.Sprint(token)))
+	}
+	return pointer.String()
+}
@@ -0,0 +1,160 @@
+/*
+© Copyright IBM Corporation 2022, 2022
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+/*
+* This file contains the substitution variables of the transfer task of a
+* resource monitor. The agent replaces them when the monitor is triggered:
+*
+*   ${FilePath}                              /usr/inbound/orders.2026.csv
+*   ${FileName}                              orders.2026.csv
+*   ${FileName{token=1}{separator=.}}        orders
+*   ${FileName{token=-1}{separator=.}}       csv
+*
+* token selects a part of the value split at separator, from the start when
+* positive and from the end when negative. A directory monitor sets FilePath,
+* FileName, the LastModifiedDate and LastModifiedTime of the file and their
+* UTC variants. A queue monitor sets QueueName. Both set AgentName,
+* CurrentTimeStamp and CurrentTimeStampUTC. Custom variables, such as the MQ
+* message properties of a queue monitor, are declared with the -var flag of
+* "monitor create".
+*
+* The variables in the item names, job name and metadata of the transfer task
+* are checked before the monitor is created, as a malformed or unknown
+* variable is only reported by the agent when the monitor is triggered.
+ */
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Variables set by the monitors of each type of resource.
+var monitorVariables = map[string][]string{
+	"directory": {"FilePath", "FileName", "LastModifiedDate", "LastModifiedTime",
+		"LastModifiedDateUTC", "LastModifiedTimeUTC", "AgentName", "CurrentTimeStamp", "CurrentTimeStampUTC"},
+	"queue": {"QueueName", "AgentName", "CurrentTimeStamp", "CurrentTimeStampUTC"},
+}
+
+/* Return the names of the substitution variables of a text, or an error if a
+* variable is malformed.
+* text - Text with variables such as ${FileName{token=1}{separator=.}}
+ */
+func substitutionVariables(text string) ([]string, error) {
+	names := []string{}
+	for {
+		start := strings.Index(text, "${")
+		if start < 0 {
+			return names, nil
+		}
+		rest := text[start+2:]
+		end := strings.IndexAny(rest, "{}")
+		if end < 0 {
+			return nil, fmt.Errorf("variable %s is not closed by }", text[start:])
+		}
+		name := rest[:end]
+		if !isVariableName(name) {
+			return nil, fmt.Errorf("variable ${%s has no valid name", rest[:end+1])
+		}
+		rest = rest[end:]
+		// Modifiers such as {token=1}{separator=.}
+		for strings.HasPrefix(rest, "{") {
+			close := strings.Index(rest, "}")
+			if close < 0 {
+				return nil, fmt.Errorf("modifier of variable %s is not closed by }", name)
+			}
+			if err := checkVariableModifier(rest[1:close]); err != nil {
+				return nil, fmt.Errorf("variable %s: %v", name, err)
+			}
+			rest = rest[close+1:]
+		}
+		if !strings.HasPrefix(rest, "}") {
+			return nil, fmt.Errorf("variable %s is not closed by }", name)
+		}
+		names = append(names, name)
+		text = rest[1:]
+	}
+}
+
+// Check if a name is a variable name of letters, digits, '_' and '.'.
+func isVariableName(name string) bool {
+	if len(name) == 0 {
+		return false
+	}
+	for _, char := range name {
+		if !(char >= 'A' && char <= 'Z' || char >= 'a' && char <= 'z' || char >= '0' && char <= '9' || char == '_' || char == '.') {
+			return false
+		}
+	}
+	return true
+}
+
+// Check a modifier of a variable, token=N with N not 0 or separator=C with a
+// single character C.
+func checkVariableModifier(modifier string) error {
+	key, value, _ := strings.Cut(modifier, "=")
+	switch key {
+	case "token":
+		if token, err := strconv.Atoi(value); err != nil || token == 0 {
+			return fmt.Errorf("token %q must be a number other than 0", value)
+		}
+	case "separator":
+		if len([]rune(value)) != 1 {
+			return fmt.Errorf("separator %q must be a single character", value)
+		}
+	default:
+		return fmt.Errorf("modifier {%s} must be token=N or separator=C", modifier)
+	}
+	return nil
+}
+
+/* Check the substitution variables of the transfer task of a monitor.
+* route        - Route of the transfer task
+* resourceType - Type of the monitored resource
+* custom       - Names of the custom variables
+ */
+func validateMonitorVariables(route Route, resourceType string, custom []string) error {
+	known := map[string]bool{}
+	for _, name := range append(monitorVariables[resourceType], custom...) {
+		known[name] = true
+	}
+	var problems requestProblems
+	check := func(pointer string, text string) {
+		names, err := substitutionVariables(text)
+		if err != nil {
+			problems.add(pointer, text, "%v", err)
+			return
+		}
+		for _, name := range names {
+			if !known[name] {
+				problems.add(pointer, text, "uses ${%s}, not set by a %s monitor, declare custom variables with -var", name, resourceType)
+			}
+		}
+	}
+	for index, item := range route.transferItems() {
+		check(jsonPointer("transferDefinition", "transferSet", "item", index, "source", "name"), item.Source)
+		check(jsonPointer("transferDefinition", "transferSet", "item", index, "destination", "name"), item.Destination)
+	}
+	check(jsonPointer("transferDefinition", "job", "name"), route.JobName)
+	keys := make([]string, 0, len(route.Metadata))
+	for key := range route.Metadata {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		check(jsonPointer("transferDefinition", "transferSet", "userDefinedMetadata", key), route.Metadata[key])
+	}
+	return problems.err()
+}
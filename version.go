@@ -0,0 +1,175 @@
+/*
+© Copyright IBM Corporation 2022, 2022
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+/*
+* This file contains the "version" command and the discovery of the MQ
+* version of the MQ Web Server, from its installation resource:
+*
+*   GET /ibmmq/rest/v2/admin/installation
+*
+* "version" prints the version of this program, and with -remote the MQ
+* version and platform of the MQ Web Server and the features of the MFT REST
+* API it supports. A feature is supported from the MQ version given in
+* remoteFeatures. Commands using a feature that an older MQ Web Server does
+* not support fail before sending their request, rather than with an error
+* of the MQ Web Server that does not give the cause. When the version cannot
+* be discovered, for instance when the user is not allowed to query the
+* installation, the request is sent anyway.
+ */
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/tidwall/gjson"
+)
+
+// Feature of the MFT REST API and the MQ version from which it is supported.
+type RemoteFeature struct {
+	Name       string
+	MinVersion string
+}
+
+// Features of the MFT REST API used by this program.
+var remoteFeatures = []RemoteFeature{
+	{"transfer status", "9.0.5"},
+	{"agent status", "9.0.5"},
+	{"transfer submission", "9.1.0"},
+	{"resource monitors", "9.1.0"},
+}
+
+// Installation of MQ running the MQ Web Server.
+type RemoteInstallation struct {
+	Name     string
+	Version  string
+	Platform string
+}
+
+/* Return the URL of the installation resource of the MQ Web Server of a
+* transfer resource.
+* transferUrl - URL of the transfer resource
+ */
+func installationUrlOf(transferUrl string) string {
+	return strings.TrimSuffix(strings.TrimSuffix(transferUrl, "/"), "/mft/transfer") + "/installation"
+}
+
+/* Query the installation of MQ running the MQ Web Server of a route.
+* route - Route whose MQ Web Server is queried
+ */
+func queryInstallation(route Route) (RemoteInstallation, error) {
+	installationUrl := installationUrlOf(route.transferUrl())
+	response, body, err := callMQWeb("GET", installationUrl+"?attributes=*", "")
+	if err != nil {
+		return RemoteInstallation{}, err
+	}
+	if response.StatusCode != http.StatusOK {
+		return RemoteInstallation{}, mqWebError(response, body)
+	}
+	installation := gjson.Get(body, "installation.0")
+	if !installation.Exists() {
+		return RemoteInstallation{}, fmt.Errorf("%s", message("installationMissing", installationUrl))
+	}
+	platform := installation.Get("platform").String()
+	if len(platform) == 0 {
+		platform = installation.Get("extended.platform").String()
+	}
+	return RemoteInstallation{
+		Name:     installation.Get("name").String(),
+		Version:  installation.Get("version").String(),
+		Platform: platform,
+	}, nil
+}
+
+/* Compare two MQ versions such as 9.1.0.5. Returns a negative number, zero
+* or a positive number when the first is lower, equal or higher. Missing
+* fields are 0.
+* version - First version
+* other   - Second version
+ */
+func compareVersions(version string, other string) int {
+	fields, otherFields := strings.Split(version, "."), strings.Split(other, ".")
+	for index := 0; index < len(fields) || index < len(otherFields); index++ {
+		value, otherValue := 0, 0
+		if index < len(fields) {
+			value, _ = strconv.Atoi(fields[index])
+		}
+		if index < len(otherFields) {
+			otherValue, _ = strconv.Atoi(otherFields[index])
+		}
+		if value != otherValue {
+			return value - otherValue
+		}
+	}
+	return 0
+}
+
+// Check if an installation supports a feature of remoteFeatures.
+func (installation RemoteInstallation) supports(feature RemoteFeature) bool {
+	return compareVersions(installation.Version, feature.MinVersion) >= 0
+}
+
+/* Check that the MQ Web Server of a route supports a feature. A server whose
+* version cannot be discovered is assumed to support it.
+* route - Route whose MQ Web Server is used
+* name  - Name of the feature in remoteFeatures
+ */
+func requireRemoteFeature(route Route, name string) error {
+	installation, err := queryInstallation(route)
+	if err != nil {
+		return nil
+	}
+	for _, feature := range remoteFeatures {
+		if feature.Name == name && !installation.supports(feature) {
+			return fmt.Errorf("%s", message("featureUnsupported", name, feature.MinVersion, installation.Version))
+		}
+	}
+	return nil
+}
+
+// Command "version" - print the version of this program and of the MQ Web
+// Server.
+func versionCommand(args []string) int {
+	flags := newFlagSet("version")
+	remote := flags.Bool("remote", false, "Also print the MQ version of the MQ Web Server and the features it supports")
+	routeName := flags.String("route", "", "Route whose MQ Web Server is queried")
+	if !parseCommandLine(flags, args) || flags.NArg() != 0 {
+		printMessage("versionUsage")
+		return 2
+	}
+	printMessage("programVersion", programName, programVersion)
+	if !*remote {
+		return 0
+	}
+	route, err := findRoute(*routeName)
+	if err != nil {
+		printError(err)
+		return 2
+	}
+	installation, err := queryInstallation(route)
+	if err != nil {
+		printMessage("installationQueryFailed", installationUrlOf(route.transferUrl()), err)
+		return 1
+	}
+	printMessage("remoteVersion", installation.Version, installation.Platform, installation.Name)
+	for _, feature := range remoteFeatures {
+		supported := message("featureSupported")
+		if !installation.supports(feature) {
+			supported = message("featureNotSupported", feature.MinVersion)
+		}
+		fmt.Printf("  %-24s %s\n", feature.Name, supported)
+	}
+	return 0
+}
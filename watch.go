@@ -0,0 +1,74 @@
+/*
+© Copyright IBM Corporation 2022, 2022
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+/*
+* This file contains the watch mode of the "list" command. The transfers
+* that have not ended are printed as a table with their agents, state and
+* progress, then the transfers are queried again every -interval and a row is
+* printed for each transfer that started, changed state or made progress
+* since the last refresh, in the way of kubectl get --watch:
+*
+*   ID                                               STATE        SOURCE   DESTINATION  PROGRESS
+*   414D5120514D31202020202020202020A1B2C3D4E5F6070A started      SRC      DEST         0 B of 10.0 MiB (0%)
+*   414D5120514D31202020202020202020A1B2C3D4E5F6070A inProgress   SRC      DEST         4.0 MiB of 10.0 MiB (40%)
+*   414D5120514D31202020202020202020A1B2C3D4E5F6070A successful   SRC      DEST         10.0 MiB of 10.0 MiB (100%)
+*
+* The last row of a transfer gives the state in which it ended. The rows
+* are appended rather than redrawn, so the output can be piped or logged.
+* The watch goes on until the program is interrupted.
+ */
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// Format of the rows of the watch table.
+const watchRowFormat = "%-48s %-20s %-16s %-16s %s\n"
+
+/* Print the transfers in progress and their changes until interrupted.
+* transfersUrl - URL of the transfer collection, with the query parameters
+* filter       - Filter of the transfers
+* limit        - Maximum number of transfers queried, 0 for no limit
+* interval     - Interval between refreshes
+ */
+func watchTransfers(transfersUrl string, filter TransferFilter, limit int, interval time.Duration) {
+	fmt.Printf(watchRowFormat, "ID", "STATE", "SOURCE", "DESTINATION", "PROGRESS")
+	rows := map[string]string{}
+	for {
+		// A cached list would hide the changes
+		webResponses.invalidate(transfersUrl)
+		if transfers, found := queryTransfers(transfersUrl, filter, limit); found {
+			for _, transfer := range transfers {
+				id, state := transfer.Get("id").String(), transfer.Get("status.state").String()
+				_, watched := rows[id]
+				// Transfers that ended before they were watched are not shown
+				if isTerminalTransferState(state) && !watched {
+					continue
+				}
+				progress := transferProgress(transfer).String()
+				if len(progress) == 0 {
+					progress = "-"
+				}
+				row := fmt.Sprintf(watchRowFormat, id, state, transfer.Get("sourceAgent.name").String(),
+					transfer.Get("destinationAgent.name").String(), progress)
+				if row != rows[id] {
+					fmt.Print(row)
+					rows[id] = row
+				}
+			}
+		}
+		time.Sleep(interval)
+	}
+}
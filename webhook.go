@@ -0,0 +1,289 @@
+/*
+© Copyright IBM Corporation 2022, 2022
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+/*
+* This file contains the webhooks notified when a polled transfer ends, so
+* that downstream systems can react without polling the MQ Web Server:
+*
+*   "webhooks": [
+*     {
+*       "url": "https://hooks.example.com/mft",
+*       "secret": "keyring:mft-webhook",
+*       "states": [ "failed", "partiallySuccessful" ],
+*       "payload": { "text": "Transfer ${transferId} ended in state ${state}" },
+*       "retry": { "maxAttempts": 5 }
+*     }
+*   ]
+*
+* A webhook is notified of the transfers ending in one of its states, by
+* default successful, partiallySuccessful and failed. Without a payload the
+* JSON body gives the transfer ID, state, agents, job name, bytes transferred,
+* start and end times, run ID and URL of the transfer. A payload is a JSON
+* template whose strings may hold the variables ${transferId}, ${state},
+* ${sourceAgent}, ${destinationAgent}, ${jobName}, ${bytes}, ${startTime},
+* ${endTime}, ${runId} and ${url}.
+*
+* Each POST carries the headers:
+*   X-MFT-Event      - transfer.<state>
+*   X-MFT-Delivery   - ID of the notification, the same for all its attempts
+*   X-MFT-Timestamp  - Unix time of the attempt
+*   X-MFT-Signature  - with a secret, sha256=<HMAC-SHA256 in hexadecimal of
+*                      the timestamp, a dot and the body>
+*
+* The secret may be a reference to a credential provider, as the password of
+* the MQ Web Server. Failed notifications (errors, 429 and 5xx responses) are
+* retried as set by "retry", with the defaults of the retry of requests to
+* the MQ Web Server. A webhook that cannot be notified is reported as a
+* warning, the transfer being unaffected.
+ */
+package main
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tidwall/gjson"
+)
+
+// Timeout of a request to a webhook.
+const webhookTimeout = 30 * time.Second
+
+// Webhook of the configuration.
+type Webhook struct {
+	Url     string          `json:"url"`
+	Secret  string          `json:"secret"`
+	States  []string        `json:"states"`
+	Payload json.RawMessage `json:"payload"`
+	Retry   RetryPolicy     `json:"retry"`
+}
+
+// States notified to a webhook without states.
+var defaultWebhookStates = []string{"successful", "partiallySuccessful", "failed"}
+
+// Body of a notification without a payload.
+type WebhookNotification struct {
+	TransferId       string `json:"transferId"`
+	State            string `json:"state"`
+	SourceAgent      string `json:"sourceAgent"`
+	DestinationAgent string `json:"destinationAgent"`
+	JobName          string `json:"jobName,omitempty"`
+	Bytes            int64  `json:"bytes"`
+	StartTime        string `json:"startTime,omitempty"`
+	EndTime          string `json:"endTime,omitempty"`
+	RunId            string `json:"runId"`
+	Url              string `json:"url"`
+}
+
+/* Notify the webhooks of the configuration that a transfer has ended.
+* transferUrl    - URL of the transfer resource
+* transferStatus - Response of the transfer status query
+ */
+func notifyWebhooks(transferUrl string, transferStatus string) {
+	if len(config.Webhooks) == 0 {
+		return
+	}
+	transfer := gjson.Get(transferStatus, "transfer.0")
+	notification := WebhookNotification{
+		TransferId:       transfer.Get("id").String(),
+		State:            transfer.Get("status.state").String(),
+		SourceAgent:      transfer.Get("sourceAgent.name").String(),
+		DestinationAgent: transfer.Get("destinationAgent.name").String(),
+		JobName:          transfer.Get("job.name").String(),
+		Bytes:            transferProgress(transfer).Bytes,
+		StartTime:        transfer.Get("statistics.startTime").String(),
+		EndTime:          transfer.Get("statistics.endTime").String(),
+		RunId:            runId,
+		Url:              transferUrl,
+	}
+	for _, hook := range config.Webhooks {
+		if !webhookNotified(hook, notification.State) {
+			continue
+		}
+		if err := notifyWebhook(hook, notification); err != nil {
+			logMessage("webhookFailed", []any{logUrl, hook.Url, logTransferId, notification.TransferId},
+				redact(hook.Url), notification.TransferId, err)
+		}
+	}
+}
+
+/* Check whether a webhook is notified of the transfers ending in a state.
+* hook  - Webhook of the configuration
+* state - Final state of the transfer
+ */
+func webhookNotified(hook Webhook, state string) bool {
+	states := hook.States
+	if len(states) == 0 {
+		states = defaultWebhookStates
+	}
+	for _, notified := range states {
+		if strings.EqualFold(notified, state) {
+			return true
+		}
+	}
+	return false
+}
+
+/* Return the body of a notification, the payload of the webhook with its
+* variables replaced or the notification in JSON format.
+* hook         - Webhook of the configuration
+* notification - Transfer that has ended
+ */
+func webhookBody(hook Webhook, notification WebhookNotification) ([]byte, error) {
+	if len(hook.Payload) == 0 {
+		return json.Marshal(notification)
+	}
+	variables := map[string]string{
+		"transferId":       notification.TransferId,
+		"state":            notification.State,
+		"sourceAgent":      notification.SourceAgent,
+		"destinationAgent": notification.DestinationAgent,
+		"jobName":          notification.JobName,
+		"bytes":            strconv.FormatInt(notification.Bytes, 10),
+		"startTime":        notification.StartTime,
+		"endTime":          notification.EndTime,
+		"runId":            notification.RunId,
+		"url":              notification.Url,
+	}
+	payload := string(hook.Payload)
+	body := ""
+	for {
+		start := strings.Index(payload, "${")
+		if start < 0 {
+			return []byte(body + payload), nil
+		}
+		end := strings.Index(payload[start:], "}")
+		if end < 0 {
+			return nil, fmt.Errorf("%s", message("webhookVariableUnknown", payload[start:]))
+		}
+		name := payload[start+2 : start+end]
+		value, found := variables[name]
+		if !found {
+			return nil, fmt.Errorf("%s", message("webhookVariableUnknown", payload[start:start+end+1]))
+		}
+		// Variables are in strings of the payload, escape them as such
+		quoted, _ := json.Marshal(value)
+		body += payload[:start] + string(quoted[1:len(quoted)-1])
+		payload = payload[start+end+1:]
+	}
+}
+
+// Return the signature of a notification, the HMAC-SHA256 of the timestamp
+// and body.
+func webhookSignature(secret string, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+/* Resolve the secret of a webhook, which may be a reference to a credential
+* provider.
+* hook - Webhook of the configuration
+ */
+func webhookSecret(hook Webhook) (string, error) {
+	secret := hook.Secret
+	reference, provider, err := credentialProviderFor(secret)
+	if err != nil {
+		return "", err
+	}
+	if provider != nil {
+		secret, err = provider.Password(reference, config.UserId)
+		if err != nil {
+			return "", fmt.Errorf("unable to retrieve webhook secret from %s provider: %v", reference.Scheme, err)
+		}
+	}
+	registerSecret(secret)
+	return secret, nil
+}
+
+/* POST a notification to a webhook, retrying the attempts that fail with an
+* error, 429 or 5xx.
+* hook         - Webhook of the configuration
+* notification - Transfer that has ended
+ */
+func notifyWebhook(hook Webhook, notification WebhookNotification) error {
+	body, err := webhookBody(hook, notification)
+	if err != nil {
+		return err
+	}
+	secret, err := webhookSecret(hook)
+	if err != nil {
+		return err
+	}
+	policy := defaultRetryPolicy()
+	if hook.Retry.MaxAttempts > 0 {
+		policy.MaxAttempts = hook.Retry.MaxAttempts
+	}
+	if hook.Retry.InitialDelay > 0 {
+		policy.InitialDelay = hook.Retry.InitialDelay
+	}
+	if hook.Retry.MaxDelay > 0 {
+		policy.MaxDelay = hook.Retry.MaxDelay
+	}
+	if hook.Retry.MaxRetryAfter > 0 {
+		policy.MaxRetryAfter = hook.Retry.MaxRetryAfter
+	}
+	var delivery [16]byte
+	rand.Read(delivery[:])
+	client := &http.Client{Timeout: webhookTimeout}
+	for attempt := 1; ; attempt++ {
+		request, err := http.NewRequest("POST", hook.Url, bytes.NewReader(body))
+		if err != nil {
+			return err
+		}
+		timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+		request.Header.Set("Content-Type", "application/json")
+		request.Header.Set("User-Agent", programName)
+		request.Header.Set("X-MFT-Event", "transfer."+notification.State)
+		request.Header.Set("X-MFT-Delivery", hex.EncodeToString(delivery[:]))
+		request.Header.Set("X-MFT-Timestamp", timestamp)
+		if len(secret) > 0 {
+			request.Header.Set("X-MFT-Signature", webhookSignature(secret, timestamp, body))
+		}
+		response, err := client.Do(request)
+		if err == nil {
+			io.Copy(io.Discard, response.Body)
+			response.Body.Close()
+			if response.StatusCode < 300 {
+				logMessage("webhookNotified", []any{logUrl, hook.Url, logTransferId, notification.TransferId},
+					redact(hook.Url), notification.TransferId)
+				return nil
+			}
+			err = fmt.Errorf("%s", message("responseCodeReceived", response.Status))
+			if response.StatusCode != http.StatusTooManyRequests && response.StatusCode < 500 {
+				return err
+			}
+		} else {
+			response = nil
+		}
+		if attempt >= policy.MaxAttempts {
+			return err
+		}
+		delay, allowed := policy.nextDelay(attempt, response)
+		if !allowed {
+			return err
+		}
+		logMessage("webhookRetry", []any{logUrl, hook.Url}, redact(hook.Url), err, delay, attempt+1, policy.MaxAttempts)
+		time.Sleep(delay)
+	}
+}
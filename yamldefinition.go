@@ -0,0 +1,198 @@
+/*
+© Copyright IBM Corporation 2022, 2022
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+/*
+* This file contains the YAML format of transfer definitions, a shorter form
+* of a route for files written by hand. It is read by "submit -file" and
+* "template create -file" for files ending in .yaml or .yml:
+*
+*   name: payroll
+*   source: SRC@SRCQM
+*   destination: DEST@DESTQM
+*   mode: text
+*   checksum: MD5
+*   ifExists: overwrite
+*   priority: 5
+*   job: PAYROLL
+*   metadata:
+*     department: finance
+*   items:
+*     - from: /usr/src/payroll.csv
+*       to: /usr/dest/payroll.csv
+*     - from: /usr/src/archive
+*       to: /usr/dest/archive
+*       type: directory
+*       recursive: true
+*       exclude: ["*.tmp"]
+*   schedule:
+*     start: 2026-10-18T01:00
+*     every: 1d
+*     until: 2026-12-31T23:59
+*   exits:
+*     postDestination:
+*       program: /usr/local/bin/load-payroll.sh
+*       arguments: [--month, current]
+*
+* The type of an item applies to both its ends, fromType and toType set them
+* separately, and items are files when no type is given. The schedule takes
+* the values of the scheduling flags of "submit", and the exits are the
+* program calls preSource, postSource, preDestination and postDestination
+* with the attributes described in calls.go. Unknown keys are reported.
+ */
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Transfer definition in YAML.
+type yamlDefinition struct {
+	Name            string              `yaml:"name"`
+	Source          string              `yaml:"source"`
+	Destination     string              `yaml:"destination"`
+	Mode            string              `yaml:"mode"`
+	Checksum        string              `yaml:"checksum"`
+	IfExists        string              `yaml:"ifExists"`
+	Disposition     string              `yaml:"disposition"`
+	Priority        *int                `yaml:"priority"`
+	Job             string              `yaml:"job"`
+	RecoveryTimeout *int                `yaml:"recoveryTimeout"`
+	Metadata        map[string]string   `yaml:"metadata"`
+	Items           []yamlItem          `yaml:"items"`
+	Schedule        *yamlSchedule       `yaml:"schedule"`
+	Exits           map[string]yamlExit `yaml:"exits"`
+}
+
+// Item of a transfer definition in YAML.
+type yamlItem struct {
+	From        string   `yaml:"from"`
+	To          string   `yaml:"to"`
+	Type        string   `yaml:"type"`
+	FromType    string   `yaml:"fromType"`
+	ToType      string   `yaml:"toType"`
+	Mode        string   `yaml:"mode"`
+	Checksum    string   `yaml:"checksum"`
+	IfExists    string   `yaml:"ifExists"`
+	Disposition string   `yaml:"disposition"`
+	Recursive   *bool    `yaml:"recursive"`
+	Exclude     []string `yaml:"exclude"`
+}
+
+// Schedule of a transfer definition in YAML.
+type yamlSchedule struct {
+	Start    string `yaml:"start"`
+	TimeBase string `yaml:"timeBase"`
+	Timezone string `yaml:"timezone"`
+	Every    string `yaml:"every"`
+	Count    int    `yaml:"count"`
+	Until    string `yaml:"until"`
+}
+
+// Program call of a transfer definition in YAML.
+type yamlExit struct {
+	Program      string   `yaml:"program"`
+	Type         string   `yaml:"type"`
+	Arguments    []string `yaml:"arguments"`
+	Retries      int      `yaml:"retries"`
+	RetryWait    int      `yaml:"retryWait"`
+	SuccessCodes string   `yaml:"successCodes"`
+}
+
+// Read a transfer definition from a YAML file.
+func readYamlDefinition(fileName string) (Route, error) {
+	content, err := os.ReadFile(fileName)
+	if err != nil {
+		return Route{}, err
+	}
+	var definition yamlDefinition
+	decoder := yaml.NewDecoder(bytes.NewReader(content))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&definition); err != nil {
+		return Route{}, fmt.Errorf("%s: %v", fileName, err)
+	}
+	route, err := definition.route()
+	if err != nil {
+		return Route{}, fmt.Errorf("%s: %v", fileName, err)
+	}
+	return route, nil
+}
+
+// Return the route of a transfer definition in YAML.
+func (definition yamlDefinition) route() (Route, error) {
+	route := Route{
+		Name:                definition.Name,
+		SourceItemType:      "file",
+		DestinationItemType: "file",
+		Mode:                definition.Mode,
+		ChecksumMethod:      definition.Checksum,
+		ActionIfExists:      definition.IfExists,
+		SourceDisposition:   definition.Disposition,
+		Priority:            definition.Priority,
+		JobName:             definition.Job,
+		RecoveryTimeout:     definition.RecoveryTimeout,
+		Metadata:            definition.Metadata,
+	}
+	var err error
+	if route.SourceAgent, route.SourceQM, err = splitAgent(definition.Source); err != nil {
+		return route, fmt.Errorf("source: %v", err)
+	}
+	if route.DestinationAgent, route.DestinationQM, err = splitAgent(definition.Destination); err != nil {
+		return route, fmt.Errorf("destination: %v", err)
+	}
+	if len(definition.Items) == 0 {
+		return route, fmt.Errorf("the definition has no items")
+	}
+	for _, item := range definition.Items {
+		transferItem := TransferItem{Source: item.From, SourceType: item.Type, Destination: item.To, DestinationType: item.Type,
+			Mode: item.Mode, Checksum: item.Checksum, ActionIfExists: item.IfExists, SourceDisposition: item.Disposition,
+			Recursive: item.Recursive, Exclude: item.Exclude}
+		if len(item.FromType) > 0 {
+			transferItem.SourceType = item.FromType
+		}
+		if len(item.ToType) > 0 {
+			transferItem.DestinationType = item.ToType
+		}
+		route.Items = append(route.Items, transferItem)
+	}
+	if schedule := definition.Schedule; schedule != nil {
+		route.Schedule = &TransferSchedule{StartTime: schedule.Start, TimeBase: schedule.TimeBase, Timezone: schedule.Timezone}
+		if len(schedule.Every) > 0 || schedule.Count > 0 || len(schedule.Until) > 0 {
+			repeat := ScheduleRepeat{Count: schedule.Count, EndTime: schedule.Until}
+			if len(schedule.Every) > 0 {
+				if repeat.Frequency, repeat.Interval, err = parseRepeatEvery(schedule.Every); err != nil {
+					return route, fmt.Errorf("schedule: %v", err)
+				}
+			}
+			route.Schedule.Repeat = &repeat
+		}
+	}
+	calls := map[string]**ProgramCall{
+		"preSource":       &route.PreSourceCall,
+		"postSource":      &route.PostSourceCall,
+		"preDestination":  &route.PreDestinationCall,
+		"postDestination": &route.PostDestinationCall,
+	}
+	for name, exit := range definition.Exits {
+		call, found := calls[name]
+		if !found {
+			return route, fmt.Errorf("exit %s must be preSource, postSource, preDestination or postDestination", name)
+		}
+		*call = &ProgramCall{Name: exit.Program, Type: exit.Type, Arguments: exit.Arguments,
+			RetryCount: exit.Retries, RetryWait: exit.RetryWait, SuccessReturnCodes: exit.SuccessCodes}
+	}
+	return route, nil
+}